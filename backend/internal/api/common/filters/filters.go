@@ -47,6 +47,12 @@ type PlaylistFilters struct {
 	PageSize int
 }
 
+type SoundboardFilters struct {
+	Name     string
+	Page     int
+	PageSize int
+}
+
 type ImagesFilters struct {
 	Name     string
 	Type     string
@@ -54,6 +60,16 @@ type ImagesFilters struct {
 	PageSize int
 }
 
+type AnnotationFilters struct {
+	Visibility string
+}
+
+type SceneFilters struct {
+	Name     string
+	Page     int
+	PageSize int
+}
+
 type CharacterTemplateFilters struct {
 	Name          string
 	CharacterType string
@@ -1,10 +1,18 @@
 package common
 
 import (
+	annotationService "dmd/backend/internal/services/annotations"
+	audioService "dmd/backend/internal/services/audio"
+	displayService "dmd/backend/internal/services/display"
 	assetsService "dmd/backend/internal/services/images"
+	mapsService "dmd/backend/internal/services/maps"
 	pdfService "dmd/backend/internal/services/pdf"
+	playbackService "dmd/backend/internal/services/playback"
+	sceneService "dmd/backend/internal/services/scenes"
+	soundboardService "dmd/backend/internal/services/soundboard"
 	spotifyService "dmd/backend/internal/services/spotify"
 	wsService "dmd/backend/internal/services/websocket"
+	youtubeService "dmd/backend/internal/services/youtube"
 	"log/slog"
 	"net/http"
 
@@ -22,10 +30,18 @@ type IHandler interface {
 type HandlerCreator func(rs *RoutingServices, path string) IHandler
 
 type RoutingServices struct {
-	Log            *slog.Logger
-	DbConnection   *gorm.DB
-	WsManager      *wsService.Manager
-	ImageService   *assetsService.Service
-	PdfService     *pdfService.Service
-	SpotifyService *spotifyService.Service
+	Log               *slog.Logger
+	DbConnection      *gorm.DB
+	WsManager         *wsService.Manager
+	ImageService      *assetsService.Service
+	PdfService        *pdfService.Service
+	AudioService      *audioService.Service
+	PlaybackService   *playbackService.Service
+	SoundboardService *soundboardService.Service
+	SpotifyService    *spotifyService.Service
+	YouTubeService    *youtubeService.Service
+	DisplayService    *displayService.Service
+	SceneService      *sceneService.Service
+	MapsService       *mapsService.Service
+	AnnotationService *annotationService.Service
 }
@@ -17,7 +17,7 @@ func SetupTestEnvironment(t *testing.T, models ...any) (*common.RoutingServices,
 
 	// Use the test name in the DSN to ensure a unique, isolated in-memory DB for each test function.
 	dsn := "file:" + t.Name() + "?mode=memory&cache=shared"
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		t.Fatalf("failed to connect to in-memory database: %v", err)
 	}
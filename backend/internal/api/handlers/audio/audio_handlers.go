@@ -2,18 +2,50 @@ package audio
 
 import (
 	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
 	"dmd/backend/internal/api/handlers"
+	playbackSvc "dmd/backend/internal/services/playback"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"net/http"
 )
 
 type AudioHandler struct {
 	handlers.BaseHandler
-	log *slog.Logger
+	playbackService *playbackSvc.Service
+	log             *slog.Logger
 }
 
 func NewAudioHandler(rs *common.RoutingServices, path string) common.IHandler {
 	return &AudioHandler{
-		BaseHandler: handlers.NewBaseHandler(path),
-		log:         rs.Log,
+		BaseHandler:     handlers.NewBaseHandler(path),
+		playbackService: rs.PlaybackService,
+		log:             rs.Log,
 	}
 }
+
+// GET /audio - returns the playback state of every channel.
+func (h *AudioHandler) Get(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, h.playbackService.GetState())
+}
+
+// POST /audio - applies a playback command to a channel and broadcasts the new state.
+func (h *AudioHandler) Post(w http.ResponseWriter, r *http.Request) {
+	var cmd playbackSvc.Command
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	state, err := h.playbackService.Execute(cmd)
+	if err != nil {
+		if errors.Is(err, playbackSvc.ErrUnknownChannel) || errors.Is(err, playbackSvc.ErrInvalidCommand) {
+			utils.RespondWithError(w, errors2.NewBadRequestError("Invalid playback command", err))
+			return
+		}
+		utils.RespondWithError(w, newAudioError("Failed to apply playback command", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, state)
+}
@@ -0,0 +1,422 @@
+package audio
+
+import (
+	"dmd/backend/internal/api/common"
+	"dmd/backend/internal/api/common/utils"
+	wsHandlers "dmd/backend/internal/api/handlers/websocket"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/platform/storage/repos/playlist_repo"
+	"dmd/backend/internal/platform/storage/repos/track_repo"
+	playbackSvc "dmd/backend/internal/services/playback"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+func TestPlaybackCommands(t *testing.T) {
+	rs, db := setupPlaybackTest(t)
+	handler := NewAudioHandler(rs, "/audio")
+
+	var tracks []audio.Track
+	for _, title := range []string{"Tavern", "Market", "Inn"} {
+		track := audio.Track{Title: title, Source: audio.SourceLocal, SourceID: title + ".mp3", Duration: 120}
+		db.Create(&track)
+		tracks = append(tracks, track)
+	}
+	playlist, _ := playlist_repo.NewPlaylistRepository(db).CreatePlaylist(&audio.Playlist{Name: "Town"},
+		[]uint{tracks[0].ID, tracks[1].ID, tracks[2].ID})
+	rain := audio.Track{Title: "Rain", Source: audio.SourceLocal, SourceID: "rain.mp3"}
+	db.Create(&rain)
+
+	send := func(body string) (*httptest.ResponseRecorder, audio.PlaybackState) {
+		req := httptest.NewRequest(http.MethodPost, "/audio", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.Post(rr, req)
+		var state audio.PlaybackState
+		if rr.Code == http.StatusOK {
+			json.NewDecoder(rr.Body).Decode(&state)
+		}
+		return rr, state
+	}
+	channel := func(state audio.PlaybackState, name audio.ChannelName) audio.ChannelState {
+		for _, ch := range state.Channels {
+			if ch.Name == name {
+				return ch
+			}
+		}
+		t.Fatalf("channel %q missing from state", name)
+		return audio.ChannelState{}
+	}
+	mustSend := func(body string) audio.ChannelState {
+		t.Helper()
+		rr, state := send(body)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var cmd playbackSvc.Command
+		json.Unmarshal([]byte(body), &cmd)
+		return channel(state, cmd.Channel)
+	}
+
+	t.Run("Initial_State", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.Get(rr, httptest.NewRequest(http.MethodGet, "/audio", nil))
+		var state audio.PlaybackState
+		json.NewDecoder(rr.Body).Decode(&state)
+		if len(state.Channels) != 3 {
+			t.Fatalf("expected 3 channels, got %d", len(state.Channels))
+		}
+		if ch := channel(state, audio.ChannelAmbience); ch.Status != audio.StatusStopped || ch.Volume != 1 || !ch.Loop {
+			t.Errorf("expected stopped, looping ambience at full volume, got %+v", ch)
+		}
+	})
+
+	t.Run("Play_Playlist", func(t *testing.T) {
+		ch := mustSend(fmt.Sprintf(`{"channel": "music", "action": "play", "playlist_id": %d, "queue_index": 1}`, playlist.ID))
+		if ch.Status != audio.StatusPlaying || ch.Track == nil || ch.Track.Title != "Market" || len(ch.Queue) != 3 {
+			t.Errorf("expected the playlist playing from its second track, got %+v", ch)
+		}
+	})
+
+	t.Run("Pause_Keeps_Position", func(t *testing.T) {
+		time.Sleep(20 * time.Millisecond)
+		ch := mustSend(`{"channel": "music", "action": "pause"}`)
+		if ch.Status != audio.StatusPaused || ch.Position <= 0 {
+			t.Errorf("expected paused with the elapsed position, got %+v", ch)
+		}
+		resumed := mustSend(`{"channel": "music", "action": "play"}`)
+		if resumed.Status != audio.StatusPlaying || resumed.Position != ch.Position {
+			t.Errorf("expected resume from %v, got %+v", ch.Position, resumed)
+		}
+	})
+
+	t.Run("Seek_And_Previous_Restarts", func(t *testing.T) {
+		ch := mustSend(`{"channel": "music", "action": "seek", "position": 42}`)
+		if ch.Position < 42 {
+			t.Errorf("expected position 42, got %v", ch.Position)
+		}
+		ch = mustSend(`{"channel": "music", "action": "previous"}`)
+		if ch.Track.Title != "Market" || ch.Position != 0 {
+			t.Errorf("expected previous to restart the current track, got %+v", ch)
+		}
+	})
+
+	t.Run("Ended_Advances_Once", func(t *testing.T) {
+		ch := mustSend(`{"channel": "music", "action": "ended", "queue_index": 1}`)
+		if ch.Track.Title != "Inn" || ch.QueueIndex != 2 {
+			t.Fatalf("expected the next track, got %+v", ch)
+		}
+		// A second display reporting the same track must not skip another one.
+		ch = mustSend(`{"channel": "music", "action": "ended", "queue_index": 1}`)
+		if ch.Track.Title != "Inn" {
+			t.Errorf("expected a stale ended report to be ignored, got %+v", ch)
+		}
+		ch = mustSend(`{"channel": "music", "action": "ended", "queue_index": 2}`)
+		if ch.Status != audio.StatusStopped {
+			t.Errorf("expected the playlist to stop after its last track, got %+v", ch)
+		}
+	})
+
+	t.Run("Loop_Wraps_Playlist", func(t *testing.T) {
+		mustSend(`{"channel": "music", "action": "set", "loop": true}`)
+		mustSend(`{"channel": "music", "action": "play"}`)
+		ch := mustSend(`{"channel": "music", "action": "next"}`)
+		if ch.Track.Title != "Tavern" || ch.QueueIndex != 0 {
+			t.Errorf("expected the playlist to wrap to its first track, got %+v", ch)
+		}
+	})
+
+	t.Run("Shuffle_Keeps_Current_Track", func(t *testing.T) {
+		mustSend(`{"channel": "music", "action": "next"}`)
+		ch := mustSend(`{"channel": "music", "action": "set", "shuffle": true}`)
+		if ch.QueueIndex != 0 || ch.Queue[0] != tracks[1].ID || len(ch.Queue) != 3 {
+			t.Errorf("expected the current track to lead the shuffled queue, got %+v", ch)
+		}
+		ch = mustSend(`{"channel": "music", "action": "set", "shuffle": false}`)
+		if ch.QueueIndex != 1 || ch.Queue[0] != tracks[0].ID || ch.Queue[2] != tracks[2].ID {
+			t.Errorf("expected playlist order restored around the current track, got %+v", ch)
+		}
+	})
+
+	t.Run("Channels_Are_Independent", func(t *testing.T) {
+		ch := mustSend(fmt.Sprintf(`{"channel": "ambience", "action": "play", "track_id": %d}`, rain.ID))
+		if ch.Track.Title != "Rain" || ch.PlaylistID != nil {
+			t.Errorf("expected rain on the ambience channel, got %+v", ch)
+		}
+		ch = mustSend(`{"channel": "ambience", "action": "set", "volume": 0.4}`)
+		if ch.Volume != 0.4 {
+			t.Errorf("expected volume 0.4, got %v", ch.Volume)
+		}
+		_, state := send(`{"channel": "music", "action": "pause"}`)
+		if channel(state, audio.ChannelAmbience).Status != audio.StatusPlaying {
+			t.Error("expected pausing music to leave ambience playing")
+		}
+	})
+
+	t.Run("Invalid_Commands", func(t *testing.T) {
+		cases := map[string]struct {
+			body string
+			want int
+		}{
+			"unknown channel": {`{"channel": "voice", "action": "stop"}`, http.StatusBadRequest},
+			"unknown action":  {`{"channel": "music", "action": "rewind"}`, http.StatusBadRequest},
+			"volume too high": {`{"channel": "music", "action": "set", "volume": 1.5}`, http.StatusBadRequest},
+			"nothing loaded":  {`{"channel": "sfx", "action": "play"}`, http.StatusBadRequest},
+			"seek no target":  {`{"channel": "music", "action": "seek"}`, http.StatusBadRequest},
+			"unknown track":   {`{"channel": "sfx", "action": "play", "track_id": 9999}`, http.StatusNotFound},
+			"bad queue index": {fmt.Sprintf(`{"channel": "music", "action": "play", "playlist_id": %d, "queue_index": 5}`, playlist.ID), http.StatusBadRequest},
+		}
+		for name, c := range cases {
+			if rr, _ := send(c.body); rr.Code != c.want {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", name, rr.Code, c.want)
+			}
+		}
+	})
+}
+
+func TestPlaybackTransitions(t *testing.T) {
+	rs, db := setupSoundboardTest(t)
+	handler := NewAudioHandler(rs, "/audio")
+	playlistRepo := playlist_repo.NewPlaylistRepository(db)
+
+	newPlaylist := func(name string, titles ...string) *audio.Playlist {
+		var trackIDs []uint
+		for _, title := range titles {
+			track := audio.Track{Title: title, Source: audio.SourceLocal, SourceID: title + ".mp3"}
+			db.Create(&track)
+			trackIDs = append(trackIDs, track.ID)
+		}
+		playlist, _ := playlistRepo.CreatePlaylist(&audio.Playlist{Name: name}, trackIDs)
+		return playlist
+	}
+	tavern := newPlaylist("Tavern", "Lute", "Fiddle")
+	combat := newPlaylist("Combat", "Drums", "Horns")
+
+	send := func(body string) (*httptest.ResponseRecorder, audio.ChannelState) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		handler.Post(rr, httptest.NewRequest(http.MethodPost, "/audio", strings.NewReader(body)))
+		var state audio.PlaybackState
+		json.NewDecoder(rr.Body).Decode(&state)
+		if len(state.Channels) == 0 {
+			return rr, audio.ChannelState{}
+		}
+		return rr, state.Channels[0] // Music
+	}
+	mustSend := func(body string) audio.ChannelState {
+		t.Helper()
+		rr, ch := send(body)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		return ch
+	}
+
+	t.Run("Crossfade_Between_Playlists", func(t *testing.T) {
+		mustSend(fmt.Sprintf(`{"channel": "music", "action": "play", "playlist_id": %d, "queue_index": 1}`, tavern.ID))
+		ch := mustSend(fmt.Sprintf(`{"channel": "music", "action": "crossfade", "playlist_id": %d, "duration": 4}`, combat.ID))
+		if ch.Track.Title != "Drums" || *ch.PlaylistID != combat.ID || ch.Status != audio.StatusPlaying {
+			t.Fatalf("expected the combat playlist to take over the channel, got %+v", ch)
+		}
+		fade := ch.Crossfade
+		if fade == nil || fade.From == nil || fade.From.Track.Title != "Fiddle" || *fade.From.PlaylistID != tavern.ID {
+			t.Fatalf("expected a crossfade out of the tavern playlist, got %+v", fade)
+		}
+		if fade.EndsAt.Sub(fade.StartsAt) != 4*time.Second || !ch.UpdatedAt.Equal(fade.StartsAt) {
+			t.Errorf("expected a 4s crossfade starting when the new track does, got %+v", fade)
+		}
+
+		// Displays still report the end of the track fading out; it must not skip the new one.
+		if ch = mustSend(`{"channel": "music", "action": "ended", "queue_index": 0}`); ch.Track.Title != "Drums" {
+			t.Errorf("expected ended to be ignored during a crossfade, got %+v", ch)
+		}
+	})
+
+	t.Run("Scheduled_Crossfade", func(t *testing.T) {
+		ch := mustSend(fmt.Sprintf(`{"channel": "music", "action": "crossfade", "playlist_id": %d, "duration": 2, "delay": 60}`, tavern.ID))
+		startsAt := ch.Crossfade.StartsAt
+		if time.Until(startsAt) < 59*time.Second || !ch.UpdatedAt.Equal(startsAt) || ch.Crossfade.From.Track.Title != "Drums" {
+			t.Fatalf("expected the crossfade to start in a minute, got %+v", ch.Crossfade)
+		}
+		if ch = mustSend(`{"channel": "music", "action": "set", "volume": 0.7}`); ch.Crossfade == nil || !ch.UpdatedAt.Equal(startsAt) {
+			t.Errorf("expected a volume change to keep the scheduled crossfade, got %+v", ch)
+		}
+		ch = mustSend(`{"channel": "music", "action": "pause"}`)
+		if ch.Crossfade != nil || ch.UpdatedAt.After(time.Now()) || ch.Position != 0 {
+			t.Errorf("expected pausing to cancel the crossfade, got %+v", ch)
+		}
+	})
+
+	t.Run("Duck_Expires", func(t *testing.T) {
+		ch := mustSend(`{"channel": "music", "action": "duck", "volume": 0.2, "duration": 0.05, "fade": 0}`)
+		if ch.Duck == nil || ch.Duck.Level != 0.2 || ch.Status != audio.StatusPaused {
+			t.Fatalf("expected the music ducked without changing what plays, got %+v", ch)
+		}
+		deadline := time.Now().Add(2 * time.Second)
+		for rs.PlaybackService.GetState().Channels[0].Duck != nil {
+			if time.Now().After(deadline) {
+				t.Fatal("expected the duck to be cleared once it ends")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+
+	t.Run("Pad_Ducks_Music", func(t *testing.T) {
+		roar := audio.Track{Title: "Roar", Source: audio.SourceLocal, SourceID: "roar.mp3", Duration: 2}
+		db.Create(&roar)
+		board := &audio.Soundboard{Name: "Monsters", Pads: []audio.SoundboardPad{
+			{Column: 0, TrackID: roar.ID, DuckMusic: true},
+			{Column: 1, TrackID: roar.ID},
+		}}
+		if err := rs.SoundboardService.CreateSoundboard(board); err != nil {
+			t.Fatalf("CreateSoundboard failed unexpectedly: %v", err)
+		}
+
+		rs.SoundboardService.Trigger(board.Pads[1].ID)
+		if duck := rs.PlaybackService.GetState().Channels[0].Duck; duck != nil {
+			t.Errorf("expected a plain pad not to duck the music, got %+v", duck)
+		}
+		rs.SoundboardService.Trigger(board.Pads[0].ID)
+		duck := rs.PlaybackService.GetState().Channels[0].Duck
+		if duck == nil || duck.EndsAt.Sub(duck.StartsAt) != 2*time.Second {
+			t.Errorf("expected the music ducked for the roar's 2 seconds, got %+v", duck)
+		}
+	})
+
+	t.Run("Invalid_Transitions", func(t *testing.T) {
+		cases := map[string]string{
+			"no source":        `{"channel": "music", "action": "crossfade", "duration": 3}`,
+			"no duration":      fmt.Sprintf(`{"channel": "music", "action": "crossfade", "playlist_id": %d}`, combat.ID),
+			"long crossfade":   fmt.Sprintf(`{"channel": "music", "action": "crossfade", "playlist_id": %d, "duration": 600}`, combat.ID),
+			"negative delay":   fmt.Sprintf(`{"channel": "music", "action": "crossfade", "playlist_id": %d, "duration": 3, "delay": -1}`, combat.ID),
+			"duck no volume":   `{"channel": "music", "action": "duck", "duration": 3}`,
+			"duck no duration": `{"channel": "music", "action": "duck", "volume": 0.3}`,
+			"duck long fade":   `{"channel": "music", "action": "duck", "volume": 0.3, "duration": 3, "fade": 30}`,
+		}
+		for name, body := range cases {
+			if rr, _ := send(body); rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", name, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+func TestPlaybackOverWebSocket(t *testing.T) {
+	rs, db := setupPlaybackTest(t)
+	track := audio.Track{Title: "Dragon Roar", Source: audio.SourceLocal, SourceID: "roar.mp3"}
+	db.Create(&track)
+
+	router := mux.NewRouter()
+	wsHandlers.RegisterWebsocketRoutes(router, rs.Log, rs.WsManager)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	readEvent := func(conn *websocket.Conn) (string, json.RawMessage) {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		return event.Type, event.Payload
+	}
+	// dial connects and waits for the first reply, so the client is registered before anything is broadcast.
+	dial := func(role string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?role="+role, nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		conn.WriteJSON(map[string]any{"type": playbackSvc.MessagePlaybackStateRequest})
+		readEvent(conn)
+		return conn
+	}
+
+	dm, display := dial("dm"), dial("display")
+	defer dm.Close()
+	defer display.Close()
+
+	t.Run("Command_Is_Broadcast", func(t *testing.T) {
+		dm.WriteJSON(map[string]any{
+			"type":    playbackSvc.MessagePlaybackCommand,
+			"payload": map[string]any{"channel": "sfx", "action": "play", "track_id": track.ID},
+		})
+		eventType, payload := readEvent(display)
+		if eventType != playbackSvc.EventPlaybackUpdated {
+			t.Fatalf("expected %s, got %s", playbackSvc.EventPlaybackUpdated, eventType)
+		}
+		var state audio.PlaybackState
+		json.Unmarshal(payload, &state)
+		if sfx := state.Channels[2]; sfx.Name != audio.ChannelSFX || sfx.Status != audio.StatusPlaying || *sfx.TrackID != track.ID {
+			t.Errorf("expected sfx playing the roar, got %+v", sfx)
+		}
+		readEvent(dm) // The sender receives the broadcast too
+	})
+
+	t.Run("Rejected_Command_Goes_To_Sender", func(t *testing.T) {
+		dm.WriteJSON(map[string]any{
+			"type":    playbackSvc.MessagePlaybackCommand,
+			"payload": map[string]any{"channel": "voice", "action": "stop"},
+		})
+		if eventType, _ := readEvent(dm); eventType != playbackSvc.EventPlaybackError {
+			t.Errorf("expected %s, got %s", playbackSvc.EventPlaybackError, eventType)
+		}
+	})
+
+	t.Run("Display_Command_Is_Rejected", func(t *testing.T) {
+		display.WriteJSON(map[string]any{
+			"type":    playbackSvc.MessagePlaybackCommand,
+			"payload": map[string]any{"channel": "sfx", "action": "stop"},
+		})
+		eventType, payload := readEvent(display)
+		if eventType != playbackSvc.EventPlaybackError || !strings.Contains(string(payload), "not permitted") {
+			t.Errorf("expected %s for a display client, got %s: %s", playbackSvc.EventPlaybackError, eventType, payload)
+		}
+		if sfx := rs.PlaybackService.GetState().Channels[2]; sfx.Status != audio.StatusPlaying {
+			t.Errorf("expected sfx to keep playing, got %s", sfx.Status)
+		}
+	})
+
+	t.Run("Transition_Is_Broadcast", func(t *testing.T) {
+		dm.WriteJSON(map[string]any{
+			"type":    playbackSvc.MessagePlaybackCommand,
+			"payload": map[string]any{"channel": "music", "action": "crossfade", "track_id": track.ID, "duration": 3, "delay": 1},
+		})
+		eventType, payload := readEvent(display)
+		if eventType != playbackSvc.EventPlaybackTransition {
+			t.Fatalf("expected %s, got %s", playbackSvc.EventPlaybackTransition, eventType)
+		}
+		var event playbackSvc.TransitionEvent
+		json.Unmarshal(payload, &event)
+		music := event.State.Channels[0]
+		if event.Channel != audio.ChannelMusic || event.Kind != playbackSvc.ActionCrossfade || music.Crossfade == nil || music.Crossfade.StartsAt.IsZero() {
+			t.Errorf("expected a timed crossfade on the music channel, got %+v", event)
+		}
+		readEvent(dm)
+	})
+
+	t.Run("State_Request", func(t *testing.T) {
+		display.WriteJSON(map[string]any{"type": playbackSvc.MessagePlaybackStateRequest})
+		if eventType, _ := readEvent(display); eventType != playbackSvc.EventPlaybackUpdated {
+			t.Errorf("expected %s, got %s", playbackSvc.EventPlaybackUpdated, eventType)
+		}
+	})
+}
+
+func setupPlaybackTest(t *testing.T) (*common.RoutingServices, *gorm.DB) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.Track{}, &audio.Playlist{}, &audio.PlaylistTrack{})
+	rs.WsManager = wsService.NewManager(rs.Log)
+	rs.PlaybackService = playbackSvc.NewService(rs.Log, track_repo.NewTrackRepository(db), playlist_repo.NewPlaylistRepository(db), rs.WsManager)
+	go rs.WsManager.Run()
+	return rs, db
+}
@@ -0,0 +1,467 @@
+package audio
+
+import (
+	"bytes"
+	"dmd/backend/internal/api/common"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/platform/storage/repos/track_repo"
+	audioSvc "dmd/backend/internal/services/audio"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/tcolgate/mp3"
+	"gorm.io/gorm"
+)
+
+func TestLocalTrackSync(t *testing.T) {
+	rs, db := setupAudioTest(t, map[string][]byte{
+		"tavern.mp3":     testMp3(3, "Tavern Ambience", "DMD Soundscapes"),
+		"rain.wav":       testWav(2),
+		"cover.jpg":      []byte("not audio"),
+		"untagged.mp3":   testMp3(1, "", ""),
+		"unreadable.ogg": []byte("not really ogg"),
+	})
+	audioDir := rs.AudioService.GetAudioPath()
+
+	getTrack := func(sourceID string) *audio.Track {
+		var track audio.Track
+		if err := db.Unscoped().Where("source = ? AND source_id = ?", audio.SourceLocal, sourceID).First(&track).Error; err != nil {
+			return nil
+		}
+		return &track
+	}
+
+	t.Run("Scan_Reads_Tags", func(t *testing.T) {
+		var count int64
+		db.Model(&audio.Track{}).Count(&count)
+		if count != 4 {
+			t.Errorf("expected 4 tracks for the audio files, got %d", count)
+		}
+
+		tavern := getTrack("tavern.mp3")
+		if tavern == nil || tavern.Title != "Tavern Ambience" || tavern.Artist != "DMD Soundscapes" || tavern.Duration != 3 {
+			t.Errorf("expected tagged mp3 metadata, got %+v", tavern)
+		}
+		if rain := getTrack("rain.wav"); rain == nil || rain.Title != "rain" || rain.Duration != 2 {
+			t.Errorf("expected wav titled from its file name with a 2s duration, got %+v", rain)
+		}
+		if untagged := getTrack("untagged.mp3"); untagged == nil || untagged.Title != "untagged" || untagged.Duration != 1 {
+			t.Errorf("expected untagged mp3 titled from its file name, got %+v", untagged)
+		}
+		if unreadable := getTrack("unreadable.ogg"); unreadable == nil || unreadable.Title != "unreadable" {
+			t.Errorf("expected unreadable file to still be listed, got %+v", unreadable)
+		}
+	})
+
+	t.Run("Removed_File_Is_Soft_Deleted", func(t *testing.T) {
+		os.Remove(filepath.Join(audioDir, "rain.wav"))
+		rs.AudioService.SyncTracksWithDatabase()
+
+		rain := getTrack("rain.wav")
+		if rain == nil || !rain.DeletedAt.Valid {
+			t.Fatalf("expected rain.wav track to be soft-deleted, got %+v", rain)
+		}
+	})
+
+	t.Run("Returning_File_Is_Restored", func(t *testing.T) {
+		deleted := getTrack("rain.wav")
+		os.WriteFile(filepath.Join(audioDir, "rain.wav"), testWav(5), 0644)
+		rs.AudioService.SyncTracksWithDatabase()
+
+		rain := getTrack("rain.wav")
+		if rain == nil || rain.DeletedAt.Valid || rain.ID != deleted.ID {
+			t.Fatalf("expected the same track to be restored, got %+v", rain)
+		}
+		if rain.Duration != 5 {
+			t.Errorf("expected restored track metadata to be re-read, got duration %d", rain.Duration)
+		}
+	})
+
+	t.Run("Corrupt_Wav_Is_Listed", func(t *testing.T) {
+		// A fmt chunk claiming 0xFFFFFFFF bytes, which wraps to 0 if padded in uint32.
+		corrupt := []byte("RIFF\x00\x00\x00\x00WAVEfmt \xFF\xFF\xFF\xFF")
+		os.WriteFile(filepath.Join(audioDir, "corrupt.wav"), corrupt, 0644)
+		rs.AudioService.SyncTracksWithDatabase()
+
+		if track := getTrack("corrupt.wav"); track == nil || track.Title != "corrupt" || track.Duration != 0 {
+			t.Errorf("expected corrupt wav to be listed without a duration, got %+v", track)
+		}
+	})
+}
+
+// setupAudioTest writes the given files into a temporary audio directory and scans it.
+func setupAudioTest(t *testing.T, files map[string][]byte) (*common.RoutingServices, *gorm.DB) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.Track{})
+	audioDir := filepath.Join(t.TempDir(), "audio")
+	if err := os.MkdirAll(audioDir, 0755); err != nil {
+		t.Fatalf("failed to create audio dir: %v", err)
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(audioDir, name), data, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	wsManager := wsService.NewManager(rs.Log)
+	go wsManager.Run()
+	rs.AudioService = audioSvc.NewService(rs.Log, track_repo.NewTrackRepository(db), wsManager, audioDir)
+	return rs, db
+}
+
+// testMp3 returns about the given number of seconds of silent MP3 frames, behind an ID3v2.3 tag when title or artist is set.
+func testMp3(seconds int, title, artist string) []byte {
+	var buf bytes.Buffer
+	if title != "" || artist != "" {
+		var frames bytes.Buffer
+		for id, text := range map[string]string{"TIT2": title, "TPE1": artist} {
+			frames.WriteString(id)
+			binary.Write(&frames, binary.BigEndian, uint32(len(text)+1))
+			frames.Write([]byte{0, 0, 0}) // Flags, then ISO-8859-1 encoding
+			frames.WriteString(text)
+		}
+		size := frames.Len()
+		buf.WriteString("ID3")
+		buf.Write([]byte{3, 0, 0, byte(size >> 21 & 0x7F), byte(size >> 14 & 0x7F), byte(size >> 7 & 0x7F), byte(size & 0x7F)})
+		buf.Write(frames.Bytes())
+	}
+	frameCount := int(float64(seconds) / mp3.SilentFrame.Duration().Seconds())
+	for i := 0; i < frameCount; i++ {
+		buf.Write(mp3.SilentBytes)
+	}
+	return buf.Bytes()
+}
+
+// testWav returns the given number of seconds of 8 kHz, 16-bit mono silence.
+func testWav(seconds int) []byte {
+	const sampleRate, blockAlign = 8000, 2
+	dataSize := uint32(seconds * sampleRate * blockAlign)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+dataSize)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size                 uint32
+		Format, Channels     uint16
+		SampleRate, ByteRate uint32
+		BlockAlign, Bits     uint16
+	}{16, 1, 1, sampleRate, sampleRate * blockAlign, blockAlign, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, dataSize)
+	buf.Write(make([]byte, dataSize))
+	return buf.Bytes()
+}
+
+// testSineWav returns 48 kHz, 16-bit stereo audio: silence, then a 997 Hz sine at the given amplitude.
+func testSineWav(silentSeconds, sineSeconds int, amplitude float64) []byte {
+	const sampleRate, channels = 48000, 2
+	var samples bytes.Buffer
+	for i := 0; i < (silentSeconds+sineSeconds)*sampleRate; i++ {
+		var sample int16
+		if i >= silentSeconds*sampleRate {
+			sample = int16(math.Round(amplitude * 32767 * math.Sin(2*math.Pi*997*float64(i)/sampleRate)))
+		}
+		for ch := 0; ch < channels; ch++ {
+			binary.Write(&samples, binary.LittleEndian, sample)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+samples.Len()))
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Size                 uint32
+		Format, Channels     uint16
+		SampleRate, ByteRate uint32
+		BlockAlign, Bits     uint16
+	}{16, 1, channels, sampleRate, sampleRate * channels * 2, channels * 2, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(samples.Len()))
+	buf.Write(samples.Bytes())
+	return buf.Bytes()
+}
+
+func TestTrackWaveformHandler(t *testing.T) {
+	rs, db := setupAudioTest(t, map[string][]byte{
+		"sine.wav":     testSineWav(2, 2, 0.5),
+		"rain.wav":     testWav(1),
+		"tavern.mp3":   testMp3(1, "Tavern", ""),
+		"ambience.ogg": []byte("OggS not really"),
+		"corrupt.wav":  []byte("RIFF\x00\x00\x00\x00WAVEfmt \xFF\xFF\xFF\xFF"),
+	})
+	handler := NewTrackWaveformHandler(rs, "/audio/tracks/{id}/waveform")
+
+	getTrack := func(sourceID string) audio.Track {
+		var track audio.Track
+		db.Where("source_id = ?", sourceID).First(&track)
+		return track
+	}
+	waveform := func(id uint, query string) (int, audioSvc.Waveform) {
+		idStr := strconv.Itoa(int(id))
+		req := httptest.NewRequest(http.MethodGet, "/audio/tracks/"+idStr+"/waveform"+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": idStr})
+		rr := httptest.NewRecorder()
+		handler.Get(rr, req)
+		var result audioSvc.Waveform
+		json.NewDecoder(rr.Body).Decode(&result)
+		return rr.Code, result
+	}
+
+	t.Run("Not_Analysed_Yet", func(t *testing.T) {
+		if code, _ := waveform(getTrack("sine.wav").ID, ""); code != http.StatusAccepted {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusAccepted)
+		}
+		rs.AudioService.RunTrackAnalyzer()
+		waitForAnalysis(t, db, "sine.wav", "rain.wav", "tavern.mp3", "corrupt.wav")
+	})
+
+	t.Run("Loudness_And_Peaks", func(t *testing.T) {
+		code, result := waveform(getTrack("sine.wav").ID, "")
+		if code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		// A 997 Hz sine at -6.02 dBFS in both channels measures -6.02 LUFS. The silent half is gated out,
+		// but the three blocks that overlap the start of the sine pass the gates and pull it down to -6.36.
+		if result.Loudness == nil || math.Abs(*result.Loudness+6.36) > 0.05 {
+			t.Errorf("expected a loudness of about -6.36 LUFS, got %v", derefOr(result.Loudness))
+		}
+		if result.Gain == nil || math.Abs(*result.Gain-0.415) > 0.005 {
+			t.Errorf("expected a gain of about 0.415 to reach -14 LUFS, got %v", derefOr(result.Gain))
+		}
+		if result.Duration != 4 || len(result.Peaks) != 1000 {
+			t.Fatalf("expected 4 seconds and 1000 peaks, got %d seconds and %d peaks", result.Duration, len(result.Peaks))
+		}
+		if first, last := result.Peaks[0], result.Peaks[999]; first != 0 || math.Abs(last-0.5) > 0.01 {
+			t.Errorf("expected silence then peaks of 0.5, got %v and %v", first, last)
+		}
+
+		stored := getTrack("sine.wav")
+		if stored.AnalyzedAt == nil || len(stored.Waveform) != 1000 || stored.Loudness == nil {
+			t.Errorf("expected the analysis to be stored with the track, got %+v", stored)
+		}
+		data, _ := json.Marshal(stored)
+		if bytes.Contains(data, []byte("waveform")) || !bytes.Contains(data, []byte(`"gain":`)) {
+			t.Errorf("expected tracks to carry the gain but not the waveform, got %s", data)
+		}
+	})
+
+	t.Run("Fewer_Points", func(t *testing.T) {
+		code, result := waveform(getTrack("sine.wav").ID, "?points=4")
+		if code != http.StatusOK || len(result.Peaks) != 4 {
+			t.Fatalf("expected 4 peaks, got %v %v", code, result.Peaks)
+		}
+		if result.Peaks[0] != 0 || result.Peaks[2] < 0.49 {
+			t.Errorf("expected each point to keep the highest peak of its slice, got %v", result.Peaks)
+		}
+		if code, _ := waveform(getTrack("sine.wav").ID, "?points=abc"); code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Silent_Files", func(t *testing.T) {
+		for _, name := range []string{"rain.wav", "tavern.mp3"} {
+			code, result := waveform(getTrack(name).ID, "")
+			if code != http.StatusOK {
+				t.Fatalf("%s: handler returned wrong status code: got %v want %v", name, code, http.StatusOK)
+			}
+			if result.Loudness != nil || result.Gain != nil || len(result.Peaks) == 0 || result.Peaks[0] != 0 {
+				t.Errorf("%s: expected a flat waveform without loudness, got %+v", name, result)
+			}
+		}
+	})
+
+	t.Run("No_Waveform", func(t *testing.T) {
+		spotify := audio.Track{Title: "Spotify", Source: audio.SourceSpotify, SourceID: "spotify:track:1"}
+		db.Create(&spotify)
+		cases := []struct {
+			id   uint
+			want int
+		}{
+			{getTrack("ambience.ogg").ID, http.StatusNotFound},
+			{getTrack("corrupt.wav").ID, http.StatusNotFound},
+			{spotify.ID, http.StatusBadRequest},
+			{9999, http.StatusNotFound},
+		}
+		for _, c := range cases {
+			if code, _ := waveform(c.id, ""); code != c.want {
+				t.Errorf("track %d: handler returned wrong status code: got %v want %v", c.id, code, c.want)
+			}
+		}
+	})
+}
+
+func TestTrackAnalyzer(t *testing.T) {
+	rs, db := setupAudioTest(t, map[string][]byte{"sine.wav": testSineWav(0, 1, 0.25)})
+	rs.AudioService.RunTrackAnalyzer()
+
+	track := waitForAnalysis(t, db, "sine.wav")[0]
+	if track.Loudness == nil || math.Abs(*track.Loudness+12.04) > 0.05 {
+		t.Errorf("expected the background analyser to measure about -12.04 LUFS, got %+v", track)
+	}
+}
+
+// waitForAnalysis waits for the background analyser to get through the given files and returns their tracks.
+func waitForAnalysis(t *testing.T, db *gorm.DB, sourceIDs ...string) []audio.Track {
+	t.Helper()
+	tracks := make([]audio.Track, len(sourceIDs))
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		done := true
+		for i, sourceID := range sourceIDs {
+			db.Where("source_id = ?", sourceID).First(&tracks[i])
+			done = done && tracks[i].AnalyzedAt != nil
+		}
+		if done {
+			return tracks
+		}
+	}
+	t.Fatalf("timed out waiting for %v to be analysed", sourceIDs)
+	return nil
+}
+
+func TestTrackStreamHandler(t *testing.T) {
+	rs, db := setupAudioTest(t, map[string][]byte{"tavern.mp3": testMp3(1, "Tavern", "")})
+	audioDir := rs.AudioService.GetAudioPath()
+	handler := NewTrackStreamHandler(rs, "/audio/tracks/{id}/stream")
+
+	var tavern audio.Track
+	db.Where("source_id = ?", "tavern.mp3").First(&tavern)
+	data, _ := os.ReadFile(filepath.Join(audioDir, "tavern.mp3"))
+
+	stream := func(id uint, headers map[string]string) *httptest.ResponseRecorder {
+		idStr := strconv.Itoa(int(id))
+		req := httptest.NewRequest(http.MethodGet, "/audio/tracks/"+idStr+"/stream", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": idStr})
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rr := httptest.NewRecorder()
+		handler.Get(rr, req)
+		return rr
+	}
+
+	t.Run("Full_File", func(t *testing.T) {
+		rr := stream(tavern.ID, nil)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "audio/mpeg" {
+			t.Errorf("expected Content-Type audio/mpeg, got %q", ct)
+		}
+		if rr.Header().Get("ETag") == "" || rr.Header().Get("Accept-Ranges") != "bytes" {
+			t.Errorf("expected ETag and Accept-Ranges headers, got %v", rr.Header())
+		}
+		if !bytes.Equal(rr.Body.Bytes(), data) {
+			t.Errorf("expected the whole file, got %d of %d bytes", rr.Body.Len(), len(data))
+		}
+	})
+
+	t.Run("Range_Request", func(t *testing.T) {
+		rr := stream(tavern.ID, map[string]string{"Range": "bytes=100-199"})
+		if rr.Code != http.StatusPartialContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusPartialContent)
+		}
+		want := fmt.Sprintf("bytes 100-199/%d", len(data))
+		if cr := rr.Header().Get("Content-Range"); cr != want {
+			t.Errorf("expected Content-Range %q, got %q", want, cr)
+		}
+		if !bytes.Equal(rr.Body.Bytes(), data[100:200]) {
+			t.Error("expected the requested byte range")
+		}
+	})
+
+	t.Run("ETag_Revalidation", func(t *testing.T) {
+		etag := stream(tavern.ID, nil).Header().Get("ETag")
+		if rr := stream(tavern.ID, map[string]string{"If-None-Match": etag}); rr.Code != http.StatusNotModified {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotModified)
+		}
+		// A stale If-Range validator falls back to the full file.
+		rr := stream(tavern.ID, map[string]string{"Range": "bytes=0-9", "If-Range": `"stale"`})
+		if rr.Code != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+	})
+
+	t.Run("Slow_Reader", func(t *testing.T) {
+		// Large enough not to fit in the socket buffers, so the server is still writing when the timeout passes.
+		loop := testWav(1000)
+		os.WriteFile(filepath.Join(audioDir, "loop.wav"), loop, 0644)
+		rs.AudioService.SyncTracksWithDatabase()
+		var track audio.Track
+		db.Where("source_id = ?", "loop.wav").First(&track)
+
+		router := mux.NewRouter()
+		router.HandleFunc("/audio/tracks/{id}/stream", handler.Get)
+		server := httptest.NewUnstartedServer(router)
+		server.Config.WriteTimeout = 100 * time.Millisecond
+		server.Start()
+		defer server.Close()
+
+		resp, err := http.Get(fmt.Sprintf("%s/audio/tracks/%d/stream", server.URL, track.ID))
+		if err != nil {
+			t.Fatalf("failed to request the stream: %v", err)
+		}
+		defer resp.Body.Close()
+		time.Sleep(300 * time.Millisecond)
+		body, err := io.ReadAll(resp.Body)
+		if err != nil || len(body) != len(loop) {
+			t.Errorf("expected the whole file past the write timeout, got %d of %d bytes: %v", len(body), len(loop), err)
+		}
+	})
+
+	t.Run("Rejected_Tracks", func(t *testing.T) {
+		outside := filepath.Join(filepath.Dir(audioDir), "secret.mp3")
+		os.WriteFile(outside, []byte("secret"), 0644)
+		os.Symlink(outside, filepath.Join(audioDir, "link.mp3"))
+
+		cases := []struct {
+			track audio.Track
+			want  int
+		}{
+			{audio.Track{Title: "Spotify", Source: audio.SourceSpotify, SourceID: "spotify:track:1"}, http.StatusBadRequest},
+			{audio.Track{Title: "Traversal", Source: audio.SourceLocal, SourceID: "../secret.mp3"}, http.StatusForbidden},
+			{audio.Track{Title: "Absolute", Source: audio.SourceLocal, SourceID: outside}, http.StatusForbidden},
+			{audio.Track{Title: "Missing", Source: audio.SourceLocal, SourceID: "missing.mp3"}, http.StatusNotFound},
+		}
+		for _, c := range cases {
+			db.Create(&c.track)
+			if rr := stream(c.track.ID, nil); rr.Code != c.want {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", c.track.Title, rr.Code, c.want)
+			}
+		}
+
+		var link audio.Track
+		db.Where("source_id = ?", "link.mp3").First(&link)
+		if link.ID == 0 {
+			link = audio.Track{Title: "Link", Source: audio.SourceLocal, SourceID: "link.mp3"}
+			db.Create(&link)
+		}
+		if rr := stream(link.ID, nil); rr.Code != http.StatusForbidden {
+			t.Errorf("symlink: handler returned wrong status code: got %v want %v", rr.Code, http.StatusForbidden)
+		}
+		if rr := stream(9999, nil); rr.Code != http.StatusNotFound {
+			t.Errorf("unknown track: handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+}
+
+// derefOr prints optional floats by value.
+func derefOr(f *float64) any {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
@@ -4,6 +4,7 @@ package audio
 import (
 	"dmd/backend/internal/api/common"
 	"dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/filters"
 	"dmd/backend/internal/api/common/utils"
 	"dmd/backend/internal/api/handlers"
 	"dmd/backend/internal/model/audio"
@@ -12,6 +13,10 @@ import (
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
 )
 
 type PlaylistsHandler struct {
@@ -35,6 +40,25 @@ type createPlaylistRequest struct {
 	TrackIDs    []uint `json:"track_ids"`
 }
 
+// Request struct for updating a playlist's details; tracks are changed through /audio/playlists/{id}/tracks.
+type updatePlaylistRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Request struct for adding or reordering tracks
+type playlistTracksRequest struct {
+	TrackIDs []uint `json:"track_ids"`
+}
+
+func (h *PlaylistsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if _, ok := mux.Vars(r)["id"]; ok {
+		h.getPlaylistByID(w, r)
+	} else {
+		h.getAllPlaylists(w, r)
+	}
+}
+
 func (h *PlaylistsHandler) Post(w http.ResponseWriter, r *http.Request) {
 	var req createPlaylistRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -54,3 +78,164 @@ func (h *PlaylistsHandler) Post(w http.ResponseWriter, r *http.Request) {
 	}
 	utils.RespondWithJSON(w, http.StatusCreated, createdPlaylist)
 }
+
+func (h *PlaylistsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var req updatePlaylistRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		utils.RespondWithError(w, errors.NewBadRequestError("Playlist name is required"))
+		return
+	}
+
+	playlist := &audio.Playlist{Name: req.Name, Description: req.Description}
+	playlist.ID = id
+	updatedPlaylist, err := h.repo.UpdatePlaylist(playlist)
+	if err != nil {
+		utils.RespondWithError(w, newAudioError("Failed to update playlist", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, updatedPlaylist)
+}
+
+func (h *PlaylistsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.repo.DeletePlaylist(id); err != nil {
+		utils.RespondWithError(w, newAudioError("Failed to delete playlist", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// --- Private Helpers ---
+func (h *PlaylistsHandler) getAllPlaylists(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	page, _ := strconv.Atoi(queryParams.Get("page"))
+	pageSize, _ := strconv.Atoi(queryParams.Get("pageSize"))
+
+	playlists, err := h.repo.GetAllPlaylists(filters.PlaylistFilters{
+		Name:     queryParams.Get("name"),
+		Page:     page,
+		PageSize: pageSize,
+	})
+	if err != nil {
+		utils.RespondWithError(w, errors.NewInternalError("Failed to fetch playlists from db", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, playlists)
+}
+
+func (h *PlaylistsHandler) getPlaylistByID(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	playlist, err := h.repo.GetPlaylistByID(id)
+	if err != nil {
+		utils.RespondWithError(w, newAudioError("Failed to get playlist by id", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, playlist)
+}
+
+// PlaylistTracksHandler adds tracks to a playlist and reorders them.
+type PlaylistTracksHandler struct {
+	handlers.BaseHandler
+	repo repos.PlaylistRepository
+	log  *slog.Logger
+}
+
+func NewPlaylistTracksHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PlaylistTracksHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		repo:        playlist_repo.NewPlaylistRepository(rs.DbConnection),
+		log:         rs.Log,
+	}
+}
+
+// POST /audio/playlists/{id}/tracks appends the tracks to the end of the playlist.
+func (h *PlaylistTracksHandler) Post(w http.ResponseWriter, r *http.Request) {
+	h.changeTracks(w, r, "Failed to add tracks to playlist", h.repo.AddPlaylistTracks)
+}
+
+// PUT /audio/playlists/{id}/tracks moves the listed tracks to the front, in the given order.
+func (h *PlaylistTracksHandler) Put(w http.ResponseWriter, r *http.Request) {
+	h.changeTracks(w, r, "Failed to reorder playlist", h.repo.ReorderPlaylistTracks)
+}
+
+func (h *PlaylistTracksHandler) changeTracks(w http.ResponseWriter, r *http.Request, failure string, change func(uint, []uint) error) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var req playlistTracksRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if len(req.TrackIDs) == 0 {
+		utils.RespondWithError(w, errors.NewBadRequestError("track_ids must not be empty"))
+		return
+	}
+	if err = change(id, req.TrackIDs); err != nil {
+		utils.RespondWithError(w, newAudioError(failure, err))
+		return
+	}
+	respondWithPlaylist(w, h.repo, id)
+}
+
+// PlaylistTrackHandler removes a single track from a playlist.
+type PlaylistTrackHandler struct {
+	handlers.BaseHandler
+	repo repos.PlaylistRepository
+	log  *slog.Logger
+}
+
+func NewPlaylistTrackHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PlaylistTrackHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		repo:        playlist_repo.NewPlaylistRepository(rs.DbConnection),
+		log:         rs.Log,
+	}
+}
+
+// DELETE /audio/playlists/{id}/tracks/{trackId}
+func (h *PlaylistTrackHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	trackID, err := strconv.ParseUint(mux.Vars(r)["trackId"], 10, 32)
+	if err != nil {
+		utils.RespondWithError(w, errors.NewBadRequestError("Invalid track ID", err))
+		return
+	}
+	if err = h.repo.RemovePlaylistTrack(id, uint(trackID)); err != nil {
+		utils.RespondWithError(w, newAudioError("Failed to remove track from playlist", err))
+		return
+	}
+	respondWithPlaylist(w, h.repo, id)
+}
+
+func respondWithPlaylist(w http.ResponseWriter, repo repos.PlaylistRepository, id uint) {
+	playlist, err := repo.GetPlaylistByID(id)
+	if err != nil {
+		utils.RespondWithError(w, newAudioError("Failed to reload playlist", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, playlist)
+}
@@ -9,6 +9,8 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/gorilla/mux"
 )
 
 func TestCreatePlaylistHandler(t *testing.T) {
@@ -50,3 +52,108 @@ func TestCreatePlaylistHandler(t *testing.T) {
 		}
 	})
 }
+
+func TestPlaylistManagementHandlers(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.Playlist{}, &audio.Track{}, &audio.PlaylistTrack{})
+	playlistsHandler := NewPlaylistsHandler(rs, "/audio/playlists/{id}")
+	tracksHandler := NewPlaylistTracksHandler(rs, "/audio/playlists/{id}/tracks")
+	trackHandler := NewPlaylistTrackHandler(rs, "/audio/playlists/{id}/tracks/{trackId}")
+
+	var trackIDs []string
+	for _, title := range []string{"Tavern", "Forest", "Battle"} {
+		track := audio.Track{Title: title, Source: audio.SourceLocal, SourceID: title + ".mp3"}
+		db.Create(&track)
+		trackIDs = append(trackIDs, strconv.Itoa(int(track.ID)))
+	}
+	playlist := audio.Playlist{Name: "Session 1"}
+	db.Create(&playlist)
+	id := strconv.Itoa(int(playlist.ID))
+
+	call := func(method string, handler func(http.ResponseWriter, *http.Request), vars map[string]string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/audio/playlists/"+id, strings.NewReader(body))
+		req = mux.SetURLVars(req, vars)
+		rr := httptest.NewRecorder()
+		handler(rr, req)
+		return rr
+	}
+	titles := func(rr *httptest.ResponseRecorder) string {
+		var result audio.Playlist
+		json.NewDecoder(rr.Body).Decode(&result)
+		var names []string
+		for _, track := range result.Tracks {
+			names = append(names, track.Title)
+		}
+		return strings.Join(names, ",")
+	}
+	vars := map[string]string{"id": id}
+
+	t.Run("Add_Tracks", func(t *testing.T) {
+		rr := call(http.MethodPost, tracksHandler.Post, vars, `{"track_ids": [`+strings.Join(trackIDs, ",")+`]}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if got := titles(rr); got != "Tavern,Forest,Battle" {
+			t.Errorf("expected tracks in insertion order, got %s", got)
+		}
+	})
+
+	t.Run("Reorder_Tracks", func(t *testing.T) {
+		rr := call(http.MethodPut, tracksHandler.Put, vars, `{"track_ids": [`+trackIDs[2]+`,`+trackIDs[0]+`,`+trackIDs[1]+`]}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if got := titles(rr); got != "Battle,Tavern,Forest" {
+			t.Errorf("expected reordered tracks, got %s", got)
+		}
+		if got := titles(call(http.MethodGet, playlistsHandler.Get, vars, "")); got != "Battle,Tavern,Forest" {
+			t.Errorf("expected GET by id to return tracks in order, got %s", got)
+		}
+	})
+
+	t.Run("Remove_Track", func(t *testing.T) {
+		rr := call(http.MethodDelete, trackHandler.Delete, map[string]string{"id": id, "trackId": trackIDs[0]}, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if got := titles(rr); got != "Battle,Forest" {
+			t.Errorf("expected track removed, got %s", got)
+		}
+		rr = call(http.MethodDelete, trackHandler.Delete, map[string]string{"id": id, "trackId": trackIDs[0]}, "")
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Update_Playlist", func(t *testing.T) {
+		rr := call(http.MethodPut, playlistsHandler.Put, vars, `{"name": "Session 2", "description": "The crypt"}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var updated audio.Playlist
+		json.NewDecoder(rr.Body).Decode(&updated)
+		if updated.Name != "Session 2" || updated.Description != "The crypt" || len(updated.Tracks) != 2 {
+			t.Errorf("expected updated details with tracks kept, got %+v", updated)
+		}
+		if rr := call(http.MethodPut, playlistsHandler.Put, vars, `{"name": " "}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Invalid_Track_Changes", func(t *testing.T) {
+		if rr := call(http.MethodPost, tracksHandler.Post, vars, `{"track_ids": [9999]}`); rr.Code != http.StatusNotFound {
+			t.Errorf("unknown track: handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+		if rr := call(http.MethodPut, tracksHandler.Put, vars, `{"track_ids": []}`); rr.Code != http.StatusBadRequest {
+			t.Errorf("empty list: handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Delete_Playlist", func(t *testing.T) {
+		if rr := call(http.MethodDelete, playlistsHandler.Delete, vars, ""); rr.Code != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+		if rr := call(http.MethodGet, playlistsHandler.Get, vars, ""); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package audio
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/audio"
+	soundboardSvc "dmd/backend/internal/services/soundboard"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type SoundboardsHandler struct {
+	handlers.BaseHandler
+	soundboardService *soundboardSvc.Service
+	log               *slog.Logger
+}
+
+func NewSoundboardsHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &SoundboardsHandler{
+		BaseHandler:       handlers.NewBaseHandler(path),
+		soundboardService: rs.SoundboardService,
+		log:               rs.Log,
+	}
+}
+
+func (h *SoundboardsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if _, ok := mux.Vars(r)["id"]; ok {
+		h.getSoundboardByID(w, r)
+	} else {
+		h.getAllSoundboards(w, r)
+	}
+}
+
+func (h *SoundboardsHandler) Post(w http.ResponseWriter, r *http.Request) {
+	var board audio.Soundboard
+	if err := json.NewDecoder(r.Body).Decode(&board); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if err := h.soundboardService.CreateSoundboard(&board); err != nil {
+		utils.RespondWithError(w, newSoundboardError("Failed to create soundboard", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, board)
+}
+
+// PUT /audio/soundboards/{id} - replaces the soundboard's name, grid size and pads.
+func (h *SoundboardsHandler) Put(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var board audio.Soundboard
+	if err = json.NewDecoder(r.Body).Decode(&board); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	board.ID = id
+	if err = h.soundboardService.UpdateSoundboard(&board); err != nil {
+		utils.RespondWithError(w, newSoundboardError("Failed to update soundboard", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, board)
+}
+
+func (h *SoundboardsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.soundboardService.DeleteSoundboard(id); err != nil {
+		utils.RespondWithError(w, newSoundboardError("Failed to delete soundboard", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Helper Methods
+func (h *SoundboardsHandler) getAllSoundboards(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	page, _ := strconv.Atoi(queryParams.Get("page"))
+	pageSize, _ := strconv.Atoi(queryParams.Get("pageSize"))
+	filters := filters.SoundboardFilters{
+		Name:     queryParams.Get("name"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	boards, err := h.soundboardService.GetSoundboards(filters)
+	if err != nil {
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to get soundboards", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, boards)
+}
+
+func (h *SoundboardsHandler) getSoundboardByID(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	board, err := h.soundboardService.GetSoundboard(id)
+	if err != nil {
+		utils.RespondWithError(w, newSoundboardError("Failed to get soundboard by id", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, board)
+}
+
+// SoundboardTriggerHandler fires a single pad.
+type SoundboardTriggerHandler struct {
+	handlers.BaseHandler
+	soundboardService *soundboardSvc.Service
+	log               *slog.Logger
+}
+
+func NewSoundboardTriggerHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &SoundboardTriggerHandler{
+		BaseHandler:       handlers.NewBaseHandler(path),
+		soundboardService: rs.SoundboardService,
+		log:               rs.Log,
+	}
+}
+
+// POST /audio/soundboards/pads/{id}/trigger - broadcasts a one-shot play event for the pad.
+func (h *SoundboardTriggerHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	event, err := h.soundboardService.Trigger(id)
+	if err != nil {
+		utils.RespondWithError(w, newSoundboardError("Failed to trigger pad", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, event)
+}
+
+func newSoundboardError(message string, err error) errors2.AppError {
+	if errors.Is(err, soundboardSvc.ErrInvalidSoundboard) {
+		return errors2.NewBadRequestError(message, err)
+	}
+	return newAudioError(message, err)
+}
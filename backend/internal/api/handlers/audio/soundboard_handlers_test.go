@@ -0,0 +1,261 @@
+package audio
+
+import (
+	"dmd/backend/internal/api/common"
+	wsHandlers "dmd/backend/internal/api/handlers/websocket"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/platform/storage/repos/soundboard_repo"
+	"dmd/backend/internal/platform/storage/repos/track_repo"
+	soundboardSvc "dmd/backend/internal/services/soundboard"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+)
+
+func TestSoundboardHandlers(t *testing.T) {
+	rs, db := setupSoundboardTest(t)
+	handler := NewSoundboardsHandler(rs, "/audio/soundboards")
+
+	roar := audio.Track{Title: "Dragon Roar", Source: audio.SourceLocal, SourceID: "roar.mp3"}
+	door := audio.Track{Title: "Creaking Door", Source: audio.SourceLocal, SourceID: "door.mp3"}
+	db.Create(&roar)
+	db.Create(&door)
+
+	send := func(method, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/audio/soundboards/"+id, strings.NewReader(body))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		rr := httptest.NewRecorder()
+		switch method {
+		case http.MethodGet:
+			handler.Get(rr, req)
+		case http.MethodPost:
+			handler.Post(rr, req)
+		case http.MethodPut:
+			handler.Put(rr, req)
+		case http.MethodDelete:
+			handler.Delete(rr, req)
+		}
+		return rr
+	}
+
+	var board audio.Soundboard
+	t.Run("Create", func(t *testing.T) {
+		body := fmt.Sprintf(`{"name": "Dungeon", "rows": 2, "columns": 3, "pads": [
+			{"row": 1, "column": 0, "track_id": %d, "key_binding": " Q ", "mode": "overlap", "volume": 0.5},
+			{"row": 0, "column": 2, "track_id": %d, "label": "Door"}
+		]}`, roar.ID, door.ID)
+		rr := send(http.MethodPost, "", body)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+		json.NewDecoder(rr.Body).Decode(&board)
+		if len(board.Pads) != 2 {
+			t.Fatalf("expected 2 pads, got %d", len(board.Pads))
+		}
+		first, second := board.Pads[0], board.Pads[1]
+		if first.Label != "Door" || first.Track.Title != "Creaking Door" || first.Volume == nil || *first.Volume != 1 || first.Mode != audio.PadModeRestart {
+			t.Errorf("expected door pad first with defaults applied, got %+v", first)
+		}
+		if second.KeyBinding != "q" || second.Mode != audio.PadModeOverlap || second.Volume == nil || *second.Volume != 0.5 {
+			t.Errorf("expected normalised key binding and given mode and volume, got %+v", second)
+		}
+	})
+
+	t.Run("Invalid_Boards", func(t *testing.T) {
+		cases := map[string]string{
+			"missing name":    `{"pads": []}`,
+			"outside grid":    fmt.Sprintf(`{"name": "A", "rows": 1, "columns": 1, "pads": [{"row": 1, "column": 0, "track_id": %d}]}`, roar.ID),
+			"shared cell":     fmt.Sprintf(`{"name": "B", "pads": [{"track_id": %d}, {"track_id": %d}]}`, roar.ID, door.ID),
+			"duplicate key":   fmt.Sprintf(`{"name": "C", "pads": [{"column": 0, "track_id": %d, "key_binding": "a"}, {"column": 1, "track_id": %d, "key_binding": "A"}]}`, roar.ID, door.ID),
+			"unknown mode":    fmt.Sprintf(`{"name": "D", "pads": [{"track_id": %d, "mode": "loop"}]}`, roar.ID),
+			"loud volume":     fmt.Sprintf(`{"name": "E", "pads": [{"track_id": %d, "volume": 1.5}]}`, roar.ID),
+			"unknown track":   `{"name": "F", "pads": [{"track_id": 9999}]}`,
+			"oversized board": `{"name": "G", "rows": 40}`,
+		}
+		for name, body := range cases {
+			if rr := send(http.MethodPost, "", body); rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", name, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+
+	t.Run("Update_Keeps_Pad_IDs", func(t *testing.T) {
+		door, roarPad := board.Pads[0], board.Pads[1]
+		// The door pad moves and is matched by its ID, the roar pad is sent without one and matched by its cell.
+		body := fmt.Sprintf(`{"name": "Dungeon", "rows": 2, "columns": 3, "pads": [
+			{"ID": %d, "row": 0, "column": 1, "track_id": %d, "label": "Old Door"},
+			{"row": 1, "column": 0, "track_id": %d, "volume": 0.25},
+			{"row": 0, "column": 0, "track_id": %d, "label": "New", "volume": 0}
+		]}`, door.ID, door.TrackID, roar.ID, roar.ID)
+		rr := send(http.MethodPut, strconv.Itoa(int(board.ID)), body)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var updated audio.Soundboard
+		json.NewDecoder(rr.Body).Decode(&updated)
+		if len(updated.Pads) != 3 {
+			t.Fatalf("expected 3 pads, got %+v", updated.Pads)
+		}
+		added, moved, kept := updated.Pads[0], updated.Pads[1], updated.Pads[2]
+		if moved.ID != door.ID || moved.Label != "Old Door" || moved.Column != 1 {
+			t.Errorf("expected the door pad to keep its ID %d when moved, got %+v", door.ID, moved)
+		}
+		if kept.ID != roarPad.ID || kept.Volume == nil || *kept.Volume != 0.25 || kept.KeyBinding != "" || kept.Mode != audio.PadModeRestart {
+			t.Errorf("expected the roar pad to keep its ID %d with the new values, got %+v", roarPad.ID, kept)
+		}
+		if added.ID == 0 || added.ID == door.ID || added.ID == roarPad.ID || added.Label != "New" {
+			t.Errorf("expected a new pad with its own ID, got %+v", added)
+		}
+		if added.Volume == nil || *added.Volume != 0 {
+			t.Errorf("expected the new pad to keep volume 0, got %v", added.Volume)
+		}
+		board = updated
+	})
+
+	t.Run("Update_Replaces_Pads", func(t *testing.T) {
+		body := fmt.Sprintf(`{"name": "Crypt", "rows": 1, "columns": 1, "pads": [{"track_id": %d}]}`, roar.ID)
+		rr := send(http.MethodPut, strconv.Itoa(int(board.ID)), body)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var updated audio.Soundboard
+		json.NewDecoder(rr.Body).Decode(&updated)
+		if updated.Name != "Crypt" || updated.Rows != 1 || len(updated.Pads) != 1 || updated.Pads[0].TrackID != roar.ID {
+			t.Errorf("expected renamed board with a single roar pad, got %+v", updated)
+		}
+		board = updated
+
+		if rr := send(http.MethodPut, "9999", `{"name": "Nowhere"}`); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Trigger_Leaves_Playback_Alone", func(t *testing.T) {
+		trigger := NewSoundboardTriggerHandler(rs, "/audio/soundboards/pads/{id}/trigger")
+		before := rs.PlaybackService.GetState()
+
+		padID := strconv.Itoa(int(board.Pads[0].ID))
+		req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/audio/soundboards/pads/"+padID+"/trigger", nil), map[string]string{"id": padID})
+		rr := httptest.NewRecorder()
+		trigger.Post(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var event soundboardSvc.TriggerEvent
+		json.NewDecoder(rr.Body).Decode(&event)
+		if event.SoundboardID != board.ID || event.TrackID != roar.ID || event.Track.Title != "Dragon Roar" || event.TriggeredAt.IsZero() {
+			t.Errorf("expected a roar trigger event, got %+v", event)
+		}
+		if after := rs.PlaybackService.GetState(); after.Channels[0].UpdatedAt != before.Channels[0].UpdatedAt {
+			t.Error("expected triggering a pad not to change the playback state")
+		}
+
+		req = mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/audio/soundboards/pads/9999/trigger", nil), map[string]string{"id": "9999"})
+		rr = httptest.NewRecorder()
+		trigger.Post(rr, req)
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if rr := send(http.MethodDelete, strconv.Itoa(int(board.ID)), ""); rr.Code != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+		var pads int64
+		db.Model(&audio.SoundboardPad{}).Unscoped().Count(&pads)
+		if pads != 0 {
+			t.Errorf("expected the board's pads to be deleted, %d left", pads)
+		}
+		if rr := send(http.MethodGet, strconv.Itoa(int(board.ID)), ""); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestSoundboardTriggerOverWebSocket(t *testing.T) {
+	rs, db := setupSoundboardTest(t)
+	track := audio.Track{Title: "Thunder", Source: audio.SourceLocal, SourceID: "thunder.mp3"}
+	db.Create(&track)
+	board := &audio.Soundboard{Name: "Storm", Pads: []audio.SoundboardPad{{TrackID: track.ID, KeyBinding: "t"}}}
+	if err := rs.SoundboardService.CreateSoundboard(board); err != nil {
+		t.Fatalf("CreateSoundboard failed unexpectedly: %v", err)
+	}
+
+	router := mux.NewRouter()
+	wsHandlers.RegisterWebsocketRoutes(router, rs.Log, rs.WsManager)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	readEvent := func(conn *websocket.Conn) (string, json.RawMessage) {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		return event.Type, event.Payload
+	}
+	// dial connects and waits for an error reply, so the client is registered before anything is broadcast.
+	dial := func(role string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?role="+role, nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		conn.WriteJSON(map[string]any{"type": soundboardSvc.MessageSoundboardTrigger, "payload": map[string]any{"pad_id": 9999}})
+		if eventType, _ := readEvent(conn); eventType != soundboardSvc.EventSoundboardError {
+			t.Fatalf("expected %s for an unknown pad, got %s", soundboardSvc.EventSoundboardError, eventType)
+		}
+		return conn
+	}
+
+	dm, display := dial("dm"), dial("display")
+	defer dm.Close()
+	defer display.Close()
+
+	dm.WriteJSON(map[string]any{
+		"type":    soundboardSvc.MessageSoundboardTrigger,
+		"payload": map[string]any{"pad_id": board.Pads[0].ID},
+	})
+	eventType, payload := readEvent(display)
+	if eventType != soundboardSvc.EventSoundboardTrigger {
+		t.Fatalf("expected %s, got %s", soundboardSvc.EventSoundboardTrigger, eventType)
+	}
+	var event soundboardSvc.TriggerEvent
+	json.Unmarshal(payload, &event)
+	if event.PadID != board.Pads[0].ID || event.Track.Title != "Thunder" || event.Mode != audio.PadModeRestart {
+		t.Errorf("expected the thunder pad to fire, got %+v", event)
+	}
+
+	// Only the DM's screen may fire pads.
+	display.WriteJSON(map[string]any{
+		"type":    soundboardSvc.MessageSoundboardTrigger,
+		"payload": map[string]any{"pad_id": board.Pads[0].ID},
+	})
+	if eventType, payload := readEvent(display); eventType != soundboardSvc.EventSoundboardError || !strings.Contains(string(payload), "not permitted") {
+		t.Errorf("expected %s for a display client, got %s: %s", soundboardSvc.EventSoundboardError, eventType, payload)
+	}
+}
+
+func setupSoundboardTest(t *testing.T) (*common.RoutingServices, *gorm.DB) {
+	rs, db := setupPlaybackTest(t)
+	if err := db.AutoMigrate(&audio.Soundboard{}, &audio.SoundboardPad{}); err != nil {
+		t.Fatalf("failed to migrate soundboards: %v", err)
+	}
+	t.Cleanup(func() { db.Migrator().DropTable(&audio.Soundboard{}, &audio.SoundboardPad{}) })
+	rs.SoundboardService = soundboardSvc.NewService(rs.Log, soundboard_repo.NewSoundboardRepository(db), track_repo.NewTrackRepository(db), rs.PlaybackService, rs.WsManager)
+	return rs, db
+}
@@ -9,11 +9,14 @@ import (
 	"dmd/backend/internal/model/audio"
 	"dmd/backend/internal/platform/storage/repos"
 	"dmd/backend/internal/platform/storage/repos/track_repo"
+	audioSvc "dmd/backend/internal/services/audio"
 	"encoding/json"
 	"errors"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
@@ -93,3 +96,102 @@ func (h *TracksHandler) getTrackByID(w http.ResponseWriter, r *http.Request) {
 	}
 	utils.RespondWithJSON(w, http.StatusOK, track)
 }
+
+// TrackStreamHandler serves the audio file behind a local track.
+type TrackStreamHandler struct {
+	handlers.BaseHandler
+	audioService *audioSvc.Service
+	log          *slog.Logger
+}
+
+func NewTrackStreamHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &TrackStreamHandler{
+		BaseHandler:  handlers.NewBaseHandler(path),
+		audioService: rs.AudioService,
+		log:          rs.Log,
+	}
+}
+
+// GET /audio/tracks/{id}/stream
+// ServeContent answers Range requests, so the browser can seek without downloading the whole file.
+func (h *TrackStreamHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	file, err := h.audioService.OpenTrackFile(id)
+	if err != nil {
+		utils.RespondWithError(w, newAudioError("Failed to open track", err))
+		return
+	}
+	defer file.Close()
+
+	// A browser reads a long loop as it plays, far past the server's write timeout, so the stream has none.
+	if err = http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.log.Debug("Failed to clear the write deadline", "error", err)
+	}
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("ETag", file.ETag())
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, file.Info.Name(), file.Info.ModTime(), file)
+}
+
+// TrackWaveformHandler serves the waveform and loudness of a local track.
+type TrackWaveformHandler struct {
+	handlers.BaseHandler
+	audioService *audioSvc.Service
+	log          *slog.Logger
+}
+
+func NewTrackWaveformHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &TrackWaveformHandler{
+		BaseHandler:  handlers.NewBaseHandler(path),
+		audioService: rs.AudioService,
+		log:          rs.Log,
+	}
+}
+
+// GET /audio/tracks/{id}/waveform?points=200
+// points limits the number of peaks returned, for drawing the waveform at a smaller width.
+func (h *TrackWaveformHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	points := 0
+	if raw := r.URL.Query().Get("points"); raw != "" {
+		if points, err = strconv.Atoi(raw); err != nil || points <= 0 {
+			utils.RespondWithError(w, errors2.NewBadRequestError("Invalid points, must be a positive number", err))
+			return
+		}
+	}
+
+	waveform, err := h.audioService.GetWaveform(id, points)
+	if errors.Is(err, audioSvc.ErrNotAnalyzed) {
+		utils.RespondWithJSON(w, http.StatusAccepted, map[string]string{"status": "analysing"})
+		return
+	}
+	if err != nil {
+		utils.RespondWithError(w, newAudioError("Failed to get track waveform", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, waveform)
+}
+
+// --- Helpers ---
+
+func newAudioError(message string, err error) errors2.AppError {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, fs.ErrNotExist), errors.Is(err, audioSvc.ErrNoWaveform):
+		return errors2.NewNotFoundError(message, err)
+	case errors.Is(err, audioSvc.ErrNotLocalTrack):
+		return errors2.NewBadRequestError(message, err)
+	case errors.Is(err, audioSvc.ErrUnsafeSourceID):
+		return errors2.NewAppError(http.StatusForbidden, message, err)
+	default:
+		return errors2.NewInternalError(message, err)
+	}
+}
@@ -0,0 +1,77 @@
+package audio
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	youtubeSvc "dmd/backend/internal/services/youtube"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// maxImportSize limits pasted text and uploaded export files.
+const maxImportSize = 10 << 20
+
+// YouTubeImportHandler creates youtube tracks from pasted links or playlist exports.
+type YouTubeImportHandler struct {
+	handlers.BaseHandler
+	youtubeService *youtubeSvc.Service
+	log            *slog.Logger
+}
+
+func NewYouTubeImportHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &YouTubeImportHandler{
+		BaseHandler:    handlers.NewBaseHandler(path),
+		youtubeService: rs.YouTubeService,
+		log:            rs.Log,
+	}
+}
+
+// POST /audio/youtube/import
+// The body is the pasted text or export itself, or a multipart form with a "file" upload or a "text" field.
+func (h *YouTubeImportHandler) Post(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	data, err := readImport(r)
+	if err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Failed to read import", err))
+		return
+	}
+
+	result, err := h.youtubeService.Import(r.Context(), data)
+	if err != nil {
+		utils.RespondWithError(w, newYouTubeError("Failed to import YouTube videos", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// --- Helpers ---
+
+func readImport(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return io.ReadAll(r.Body)
+	}
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		return nil, err
+	}
+	if text := r.FormValue("text"); text != "" {
+		return []byte(text), nil
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+func newYouTubeError(message string, err error) errors2.AppError {
+	if errors.Is(err, youtubeSvc.ErrInvalidImport) {
+		return errors2.NewBadRequestError(message, err)
+	}
+	return errors2.NewInternalError(message, err)
+}
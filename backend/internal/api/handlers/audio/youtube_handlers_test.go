@@ -0,0 +1,269 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/platform/storage/repos/track_repo"
+	wsService "dmd/backend/internal/services/websocket"
+	youtubeSvc "dmd/backend/internal/services/youtube"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeResolver knows the titles it was given and fails for the video "netFailure1".
+type fakeResolver struct {
+	mu     sync.Mutex
+	titles map[string]string
+	asked  []string
+}
+
+func (f *fakeResolver) Resolve(_ context.Context, videoID string) (youtubeSvc.Metadata, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.asked = append(f.asked, videoID)
+	if videoID == "netFailure1" {
+		return youtubeSvc.Metadata{}, errors.New("no network")
+	}
+	title, ok := f.titles[videoID]
+	if !ok {
+		return youtubeSvc.Metadata{}, youtubeSvc.ErrNoMetadata
+	}
+	return youtubeSvc.Metadata{Title: title, Artist: "Resolved Channel", Duration: 90}, nil
+}
+
+// lookups waits until the background resolver has asked about n videos, then returns and forgets them.
+func (f *fakeResolver) lookups(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		f.mu.Lock()
+		if len(f.asked) >= n {
+			asked := f.asked
+			f.asked = nil
+			f.mu.Unlock()
+			return asked
+		}
+		f.mu.Unlock()
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %d lookups", n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitForTitle waits until the background resolver has given the video's track its title.
+func waitForTitle(t *testing.T, db *gorm.DB, videoID, title string) *audio.Track {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var track audio.Track
+		db.Where("source = ? AND source_id = ?", audio.SourceYouTube, videoID).First(&track)
+		if track.Title == title {
+			return &track
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s to be titled %q, got %q", videoID, title, track.Title)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestYouTubeImport(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.Track{})
+	rs.WsManager = wsService.NewManager(rs.Log)
+	go rs.WsManager.Run()
+	resolver := &fakeResolver{titles: map[string]string{"dQw4w9WgXcQ": "Tavern Song", "kJQP7kiw5Fk": "Battle Drums"}}
+	rs.YouTubeService = youtubeSvc.NewService(rs.Log, track_repo.NewTrackRepository(db), resolver, rs.WsManager)
+	rs.YouTubeService.RunMetadataResolver()
+	handler := NewYouTubeImportHandler(rs, "/audio/youtube/import")
+
+	post := func(req *http.Request) (int, youtubeSvc.ImportResult) {
+		rr := httptest.NewRecorder()
+		handler.Post(rr, req)
+		var result youtubeSvc.ImportResult
+		json.NewDecoder(rr.Body).Decode(&result)
+		return rr.Code, result
+	}
+	postText := func(text string) (int, youtubeSvc.ImportResult) {
+		return post(httptest.NewRequest(http.MethodPost, "/audio/youtube/import", strings.NewReader(text)))
+	}
+	postFile := func(name, content string) (int, youtubeSvc.ImportResult) {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, _ := form.CreateFormFile("file", name)
+		part.Write([]byte(content))
+		form.Close()
+		req := httptest.NewRequest(http.MethodPost, "/audio/youtube/import", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		return post(req)
+	}
+	ids := func(tracks []*audio.Track) string {
+		var ids []string
+		for _, track := range tracks {
+			ids = append(ids, track.SourceID)
+		}
+		return strings.Join(ids, ",")
+	}
+
+	t.Run("Pasted_Links", func(t *testing.T) {
+		code, result := postText(`Session playlist:
+			https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s
+			youtu.be/kJQP7kiw5Fk?si=share
+			https://youtube.com/shorts/9bZkp7q19f0, https://music.youtube.com/watch?v=dQw4w9WgXcQ
+			https://www.youtube.com/embed/OPf0YbXqDm0
+			https://www.youtube.com/playlist?list=PLx0sYbCqOb8TBPRdmBHs5Iftvv9TPboYG`)
+		if code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		if got := ids(result.Tracks); got != "dQw4w9WgXcQ,kJQP7kiw5Fk,9bZkp7q19f0,OPf0YbXqDm0" {
+			t.Errorf("expected each video once in order, got %s", got)
+		}
+		if result.Created != 4 || result.Resolving != 4 || len(result.Ignored) != 1 || !strings.Contains(result.Ignored[0], "playlist?list=") {
+			t.Errorf("expected 4 created and queued for lookup and the playlist link ignored, got %+v", result)
+		}
+		placeholder := result.Tracks[0]
+		if placeholder.ID == 0 || placeholder.Source != audio.SourceYouTube || placeholder.Title != "YouTube video dQw4w9WgXcQ" ||
+			placeholder.ThumbnailURL != "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg" {
+			t.Errorf("expected a saved placeholder track before any lookup, got %+v", placeholder)
+		}
+
+		if asked := resolver.lookups(t, 4); strings.Join(asked, ",") != "dQw4w9WgXcQ,kJQP7kiw5Fk,9bZkp7q19f0,OPf0YbXqDm0" {
+			t.Errorf("expected the videos to be looked up in import order, asked %v", asked)
+		}
+		if tavern := waitForTitle(t, db, "dQw4w9WgXcQ", "Tavern Song"); tavern.Artist != "Resolved Channel" || tavern.Duration != 90 {
+			t.Errorf("expected a resolved youtube track, got %+v", tavern)
+		}
+		waitForTitle(t, db, "kJQP7kiw5Fk", "Battle Drums")
+		waitForTitle(t, db, "9bZkp7q19f0", "YouTube video 9bZkp7q19f0")
+	})
+
+	t.Run("Reimport_Reuses_Tracks", func(t *testing.T) {
+		resolver.mu.Lock()
+		resolver.titles["9bZkp7q19f0"] = "Gangnam Style"
+		resolver.mu.Unlock()
+
+		code, result := postText("https://youtu.be/dQw4w9WgXcQ\nhttps://youtu.be/9bZkp7q19f0")
+		if code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		if result.Created != 0 || result.Existing != 2 || result.Resolving != 1 || result.Tracks[0].Title != "Tavern Song" {
+			t.Errorf("expected both tracks to be reused and the placeholder looked up again, got %+v", result)
+		}
+		if asked := resolver.lookups(t, 1); len(asked) != 1 || asked[0] != "9bZkp7q19f0" {
+			t.Errorf("expected only the placeholder to be resolved again, asked %v", asked)
+		}
+		waitForTitle(t, db, "9bZkp7q19f0", "Gangnam Style")
+		var count int64
+		db.Model(&audio.Track{}).Count(&count)
+		if count != 4 {
+			t.Errorf("expected 4 tracks, got %d", count)
+		}
+	})
+
+	t.Run("JSON_Export_File", func(t *testing.T) {
+		export := `{"kind": "youtube#playlistItemListResponse", "items": [
+			{"snippet": {"title": "Forest Ambience", "videoOwnerChannelTitle": "Nature Sounds", "resourceId": {"videoId": "aaaaaaaaaaa"}},
+			 "contentDetails": {"videoId": "aaaaaaaaaaa"}},
+			{"snippet": {"title": "Dragon Fight", "resourceId": {"videoId": "bbbbbbbbbbb"}}}
+		]}`
+		code, result := postFile("playlist.json", export)
+		if code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		if got := ids(result.Tracks); got != "aaaaaaaaaaa,bbbbbbbbbbb" {
+			t.Fatalf("expected both videos, got %s", got)
+		}
+		if forest := result.Tracks[0]; forest.Title != "Forest Ambience" || forest.Artist != "Nature Sounds" {
+			t.Errorf("expected metadata from the export, got %+v", forest)
+		}
+		// The dragon video has a title but no channel, so only it is looked up.
+		if result.Resolving != 1 {
+			t.Errorf("expected 1 video queued for lookup, got %+v", result)
+		}
+		if asked := resolver.lookups(t, 1); len(asked) != 1 || asked[0] != "bbbbbbbbbbb" {
+			t.Errorf("expected only the video missing a channel to be resolved, asked %v", asked)
+		}
+	})
+
+	t.Run("Takeout_CSV_And_ID_List", func(t *testing.T) {
+		code, result := postFile("playlist.csv", "Video ID,Playlist Video Creation Timestamp\nccccccccccc,2024-01-01T00:00:00+00:00\n")
+		if code != http.StatusOK || ids(result.Tracks) != "ccccccccccc" {
+			t.Errorf("expected the video from the CSV, got %v %s", code, ids(result.Tracks))
+		}
+		resolver.lookups(t, 1)
+		code, result = postText(`["ddddddddddd", "https://youtu.be/ccccccccccc"]`)
+		if code != http.StatusOK || ids(result.Tracks) != "ddddddddddd,ccccccccccc" {
+			t.Errorf("expected the videos from the JSON list, got %v %s", code, ids(result.Tracks))
+		}
+		resolver.lookups(t, 2)
+	})
+
+	t.Run("Lookup_Failure_Skips_Queue", func(t *testing.T) {
+		resolver.mu.Lock()
+		resolver.titles["eeeeeeeeeee"] = "Never Asked"
+		resolver.titles["fffffffffff"] = "Next Import"
+		resolver.mu.Unlock()
+
+		code, result := postText("https://youtu.be/netFailure1 https://youtu.be/eeeeeeeeeee")
+		if code != http.StatusOK || result.Created != 2 {
+			t.Fatalf("expected both videos to be created, got %v %+v", code, result)
+		}
+		if asked := resolver.lookups(t, 1); len(asked) != 1 || asked[0] != "netFailure1" {
+			t.Errorf("expected only the failing lookup, asked %v", asked)
+		}
+
+		// The next import tries again; by the time it is resolved the failed batch is long done.
+		postText("https://youtu.be/fffffffffff")
+		waitForTitle(t, db, "fffffffffff", "Next Import")
+		if asked := resolver.lookups(t, 1); len(asked) != 1 || asked[0] != "fffffffffff" {
+			t.Errorf("expected the queue after the failure to be skipped, asked %v", asked)
+		}
+		waitForTitle(t, db, "eeeeeeeeeee", "YouTube video eeeeeeeeeee")
+	})
+
+	t.Run("Offline_Queues_Nothing", func(t *testing.T) {
+		offline := youtubeSvc.NewService(rs.Log, track_repo.NewTrackRepository(db), nil, rs.WsManager)
+		offline.RunMetadataResolver()
+		result, err := offline.Import(context.Background(), []byte("https://youtu.be/ggggggggggg"))
+		if err != nil || result.Created != 1 || result.Resolving != 0 || result.Tracks[0].Title != "YouTube video ggggggggggg" {
+			t.Errorf("expected a placeholder track and no lookups, got %+v %v", result, err)
+		}
+	})
+
+	t.Run("No_Videos", func(t *testing.T) {
+		if code, _ := postText("just some notes about the session"); code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestOEmbedResolver(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("url") != youtubeSvc.WatchURL("dQw4w9WgXcQ") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"title": "Tavern Song", "author_name": "Bard"}`))
+	}))
+	defer server.Close()
+	resolver := &youtubeSvc.OEmbedResolver{Client: server.Client(), Endpoint: server.URL}
+
+	meta, err := resolver.Resolve(context.Background(), "dQw4w9WgXcQ")
+	if err != nil || meta.Title != "Tavern Song" || meta.Artist != "Bard" {
+		t.Errorf("expected the oEmbed title and author, got %+v, err %v", meta, err)
+	}
+	if _, err := resolver.Resolve(context.Background(), "aaaaaaaaaaa"); !errors.Is(err, youtubeSvc.ErrNoMetadata) {
+		t.Errorf("expected ErrNoMetadata for an unknown video, got %v", err)
+	}
+}
@@ -2,18 +2,58 @@ package display
 
 import (
 	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
 	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/display"
+	displaySvc "dmd/backend/internal/services/display"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"net/http"
 )
 
 type DisplayHandler struct {
 	handlers.BaseHandler
-	log *slog.Logger
+	displayService *displaySvc.Service
+	log            *slog.Logger
 }
 
 func NewDisplayHandler(rs *common.RoutingServices, path string) common.IHandler {
 	return &DisplayHandler{
-		BaseHandler: handlers.NewBaseHandler(path),
-		log:         rs.Log,
+		BaseHandler:    handlers.NewBaseHandler(path),
+		displayService: rs.DisplayService,
+		log:            rs.Log,
 	}
 }
+
+// GET /display - returns the current player display state.
+func (h *DisplayHandler) Get(w http.ResponseWriter, r *http.Request) {
+	utils.RespondWithJSON(w, http.StatusOK, h.displayService.GetState())
+}
+
+// PUT /display - replaces the player display state and broadcasts it.
+func (h *DisplayHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var newState display.State
+	if err := json.NewDecoder(r.Body).Decode(&newState); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	state, err := h.displayService.SetState(newState)
+	if err != nil {
+		if errors.Is(err, displaySvc.ErrInvalidLayout) || errors.Is(err, displaySvc.ErrInvalidSlot) || errors.Is(err, displaySvc.ErrImageNotFound) {
+			utils.RespondWithError(w, errors2.NewBadRequestError("Invalid display state", err))
+			return
+		}
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to update display state", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, state)
+}
+
+// DELETE /display - clears the player display.
+func (h *DisplayHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	h.displayService.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
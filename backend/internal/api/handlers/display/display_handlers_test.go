@@ -0,0 +1,132 @@
+package display
+
+import (
+	"dmd/backend/internal/api/common/utils"
+	wsHandlers "dmd/backend/internal/api/handlers/websocket"
+	"dmd/backend/internal/model/display"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/images_repo"
+	displaySvc "dmd/backend/internal/services/display"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+func TestDisplayStateHandler(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &images.ImageEntry{})
+	wsManager := wsService.NewManager(rs.Log)
+	go wsManager.Run()
+	rs.DisplayService = displaySvc.NewService(rs.Log, images_repo.NewImagesRepository(db), wsManager)
+	handler := NewDisplayHandler(rs, "/display")
+
+	img := images.ImageEntry{Name: "Dungeon Map", Type: images.ImageTypeMap, FilePath: "images/dungeon.png"}
+	db.Create(&img)
+
+	t.Run("PUT_Set_State", func(t *testing.T) {
+		stateJSON := fmt.Sprintf(`{"layout_type":"dual","slots":[{"slot_id":1,"image_id":%d,"zoom":1.5,"page":1,"position_y":-10},{"slot_id":0,"image_id":0}]}`, img.ID)
+		req := httptest.NewRequest(http.MethodPut, handler.GetPath(), strings.NewReader(stateJSON))
+		rr := httptest.NewRecorder()
+		handler.Put(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", status, http.StatusOK, rr.Body.String())
+		}
+		var state display.State
+		json.NewDecoder(rr.Body).Decode(&state)
+		if len(state.Slots) != 2 || state.Slots[0].SlotID != 0 {
+			t.Fatalf("expected 2 slots sorted by slot_id, got %+v", state.Slots)
+		}
+		if state.Slots[1].Image == nil || state.Slots[1].Image.Name != "Dungeon Map" {
+			t.Errorf("expected slot 1 to resolve its image, got %+v", state.Slots[1].Image)
+		}
+	})
+
+	t.Run("GET_Current_State", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, handler.GetPath(), nil)
+		rr := httptest.NewRecorder()
+		handler.Get(rr, req)
+
+		var state display.State
+		json.NewDecoder(rr.Body).Decode(&state)
+		if state.LayoutType != images.Dual {
+			t.Errorf("expected layout %q, got %q", images.Dual, state.LayoutType)
+		}
+	})
+
+	t.Run("PUT_Invalid_Slot", func(t *testing.T) {
+		stateJSON := `{"layout_type":"single","slots":[{"slot_id":2,"image_id":0}]}`
+		req := httptest.NewRequest(http.MethodPut, handler.GetPath(), strings.NewReader(stateJSON))
+		rr := httptest.NewRecorder()
+		handler.Put(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Concurrent_PUTs_Broadcast_In_Order", func(t *testing.T) {
+		router := mux.NewRouter()
+		wsHandlers.RegisterWebsocketRoutes(router, rs.Log, wsManager)
+		server := httptest.NewServer(router)
+		defer server.Close()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		defer conn.Close()
+		// Wait for the current state, so the client is registered before anything is broadcast.
+		conn.WriteJSON(map[string]any{"type": displaySvc.MessageDisplayStateRequest})
+		readState := func() display.State {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			var event struct {
+				Payload display.State `json:"payload"`
+			}
+			if err := conn.ReadJSON(&event); err != nil {
+				t.Fatalf("failed to read event: %v", err)
+			}
+			return event.Payload
+		}
+		readState()
+
+		const puts = 20
+		var wg sync.WaitGroup
+		for i := range puts {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rs.DisplayService.SetState(display.State{LayoutType: images.Single, Slots: []display.Slot{{SlotID: 0, Zoom: float64(i + 1)}}})
+			}()
+		}
+		wg.Wait()
+
+		var last display.State
+		for range puts {
+			last = readState()
+		}
+		if held := rs.DisplayService.GetState(); last.Slots[0].Zoom != held.Slots[0].Zoom {
+			t.Errorf("expected the last broadcast to match the stored state, got zoom %v want %v", last.Slots[0].Zoom, held.Slots[0].Zoom)
+		}
+	})
+
+	t.Run("DELETE_Clear", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, handler.GetPath(), nil)
+		rr := httptest.NewRecorder()
+		handler.Delete(rr, req)
+
+		if status := rr.Code; status != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusNoContent)
+		}
+		if state := rs.DisplayService.GetState(); state.LayoutType != "" || len(state.Slots) != 0 {
+			t.Errorf("expected cleared state, got %+v", state)
+		}
+	})
+}
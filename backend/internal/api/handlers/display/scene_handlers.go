@@ -0,0 +1,185 @@
+package display
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/display"
+	"dmd/backend/internal/platform/storage/repos"
+	"dmd/backend/internal/platform/storage/repos/scene_repo"
+	displaySvc "dmd/backend/internal/services/display"
+	sceneSvc "dmd/backend/internal/services/scenes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type ScenesHandler struct {
+	handlers.BaseHandler
+	repo repos.SceneRepository
+	log  *slog.Logger
+}
+
+func NewScenesHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &ScenesHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		repo:        scene_repo.NewSceneRepository(rs.DbConnection),
+		log:         rs.Log,
+	}
+}
+
+func (h *ScenesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if _, ok := mux.Vars(r)["id"]; ok {
+		h.getSceneByID(w, r)
+	} else {
+		h.getAllScenes(w, r)
+	}
+}
+
+func (h *ScenesHandler) Post(w http.ResponseWriter, r *http.Request) {
+	var newScene display.Scene
+	if err := json.NewDecoder(r.Body).Decode(&newScene); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if err := h.repo.CreateScene(&newScene); err != nil {
+		appErr := errors2.NewInternalError("Failed to create scene", err)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			appErr.StatusCode = http.StatusConflict
+		}
+		utils.RespondWithError(w, appErr)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, newScene)
+}
+
+// PUT /display/scenes/{id} - replaces the scene's details and cue list, rewinding it to the start.
+func (h *ScenesHandler) Put(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var updatedScene display.Scene
+	if err = json.NewDecoder(r.Body).Decode(&updatedScene); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	updatedScene.ID = id
+	if err = h.repo.UpdateScene(&updatedScene); err != nil {
+		appErr := errors2.NewInternalError("Failed to update scene", err)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			appErr.StatusCode = http.StatusNotFound
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			appErr.StatusCode = http.StatusConflict
+		}
+		utils.RespondWithError(w, appErr)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, updatedScene)
+}
+
+func (h *ScenesHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.repo.DeleteScene(id); err != nil {
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to delete scene", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Helper Methods
+func (h *ScenesHandler) getAllScenes(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	page, _ := strconv.Atoi(queryParams.Get("page"))
+	pageSize, _ := strconv.Atoi(queryParams.Get("pageSize"))
+	filters := filters.SceneFilters{
+		Name:     queryParams.Get("name"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	scenes, err := h.repo.GetAllScenes(filters)
+	if err != nil {
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to get scenes", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, scenes)
+}
+
+func (h *ScenesHandler) getSceneByID(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	scene, err := h.repo.GetSceneByID(id)
+	if err != nil {
+		appErr := errors2.NewInternalError("Failed to get scene by id", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr.StatusCode = http.StatusNotFound
+		}
+		utils.RespondWithError(w, appErr)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, scene)
+}
+
+// SceneStepHandler moves a scene one cue forward or backward and applies it to the display.
+type SceneStepHandler struct {
+	handlers.BaseHandler
+	step func(sceneID uint) (*sceneSvc.CueEvent, error)
+	log  *slog.Logger
+}
+
+// NewSceneNextHandler handles POST /display/scenes/{id}/next.
+func NewSceneNextHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &SceneStepHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		step:        rs.SceneService.Next,
+		log:         rs.Log,
+	}
+}
+
+// NewScenePreviousHandler handles POST /display/scenes/{id}/previous.
+func NewScenePreviousHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &SceneStepHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		step:        rs.SceneService.Previous,
+		log:         rs.Log,
+	}
+}
+
+func (h *SceneStepHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	event, err := h.step(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.RespondWithError(w, errors2.NewNotFoundError("Scene not found", err))
+		case errors.Is(err, sceneSvc.ErrEndOfScene), errors.Is(err, sceneSvc.ErrStartOfScene):
+			utils.RespondWithError(w, errors2.NewAppError(http.StatusConflict, "Cannot step scene", err))
+		case errors.Is(err, sceneSvc.ErrPresetMissing), errors.Is(err, displaySvc.ErrImageNotFound):
+			utils.RespondWithError(w, errors2.NewAppError(http.StatusUnprocessableEntity, "Cannot apply scene cue", err))
+		default:
+			utils.RespondWithError(w, errors2.NewInternalError("Failed to step scene", err))
+		}
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, event)
+}
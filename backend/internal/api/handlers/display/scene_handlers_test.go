@@ -0,0 +1,128 @@
+package display
+
+import (
+	"dmd/backend/internal/api/common"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/display"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/images_repo"
+	"dmd/backend/internal/platform/storage/repos/scene_repo"
+	displaySvc "dmd/backend/internal/services/display"
+	sceneSvc "dmd/backend/internal/services/scenes"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestSceneStepping(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &images.ImageEntry{}, &images.PresetLayout{}, &images.PresetLayoutSlot{},
+		&display.Scene{}, &display.SceneCue{}, &audio.Track{}, &audio.Playlist{})
+	wsManager := wsService.NewManager(rs.Log)
+	go wsManager.Run()
+	rs.DisplayService = displaySvc.NewService(rs.Log, images_repo.NewImagesRepository(db), wsManager)
+	rs.SceneService = sceneSvc.NewService(rs.Log, scene_repo.NewSceneRepository(db), rs.DisplayService, wsManager)
+
+	town := images.ImageEntry{Name: "Town", Type: images.ImageTypeMap, FilePath: "images/town.png"}
+	cave := images.ImageEntry{Name: "Cave", Type: images.ImageTypeMap, FilePath: "images/cave.png"}
+	db.Create(&town)
+	db.Create(&cave)
+	townPreset := images.PresetLayout{LayoutType: images.Single, Slots: []images.PresetLayoutSlot{{ImageID: town.ID, SlotID: 0, Zoom: 1}}}
+	cavePreset := images.PresetLayout{LayoutType: images.Single, Slots: []images.PresetLayoutSlot{{ImageID: cave.ID, SlotID: 0, Zoom: 2}}}
+	db.Create(&townPreset)
+	db.Create(&cavePreset)
+
+	scene := &display.Scene{Name: "Session 1", Cues: []display.SceneCue{{PresetLayoutID: townPreset.ID}, {PresetLayoutID: cavePreset.ID}}}
+	if err := scene_repo.NewSceneRepository(db).CreateScene(scene); err != nil {
+		t.Fatalf("failed to seed scene: %v", err)
+	}
+	sceneID := strconv.Itoa(int(scene.ID))
+
+	next := NewSceneNextHandler(rs, "/display/scenes/{id}/next")
+	previous := NewScenePreviousHandler(rs, "/display/scenes/{id}/previous")
+	step := func(h common.IHandler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/display/scenes/"+sceneID, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": sceneID})
+		rr := httptest.NewRecorder()
+		h.Post(rr, req)
+		return rr
+	}
+
+	testCases := []struct {
+		name          string
+		handler       common.IHandler
+		expectedCode  int
+		expectedImage uint
+	}{
+		{name: "Previous_Before_Start", handler: previous, expectedCode: http.StatusConflict},
+		{name: "Next_Starts_Scene", handler: next, expectedCode: http.StatusOK, expectedImage: town.ID},
+		{name: "Next_Second_Cue", handler: next, expectedCode: http.StatusOK, expectedImage: cave.ID},
+		{name: "Next_Past_End", handler: next, expectedCode: http.StatusConflict},
+		{name: "Previous_Back_To_First", handler: previous, expectedCode: http.StatusOK, expectedImage: town.ID},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := step(tc.handler)
+			if rr.Code != tc.expectedCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, tc.expectedCode, rr.Body.String())
+			}
+			if tc.expectedImage == 0 {
+				return
+			}
+			var event sceneSvc.CueEvent
+			json.NewDecoder(rr.Body).Decode(&event)
+			if len(event.Display.Slots) != 1 || event.Display.Slots[0].ImageID != tc.expectedImage {
+				t.Errorf("expected display to show image %d, got %+v", tc.expectedImage, event.Display.Slots)
+			}
+			if state := rs.DisplayService.GetState(); state.Slots[0].ImageID != tc.expectedImage {
+				t.Errorf("expected server display state to show image %d, got %d", tc.expectedImage, state.Slots[0].ImageID)
+			}
+		})
+	}
+
+	t.Run("Concurrent_Steps_Move_One_Cue_Each", func(t *testing.T) {
+		// Replacing the cue list rewinds the scene to the start.
+		repo := scene_repo.NewSceneRepository(db)
+		scene.Cues = []display.SceneCue{{PresetLayoutID: townPreset.ID}, {PresetLayoutID: cavePreset.ID}, {PresetLayoutID: townPreset.ID}}
+		if err := repo.UpdateScene(scene); err != nil {
+			t.Fatalf("failed to rewind scene: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		codes := make(chan int, 2)
+		for range cap(codes) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes <- step(next).Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+		for code := range codes {
+			if code != http.StatusOK {
+				t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+			}
+		}
+		if stepped, _ := repo.GetSceneByID(scene.ID); stepped.ActiveCue != 2 {
+			t.Errorf("expected two steps to reach cue 2, got %d", stepped.ActiveCue)
+		}
+	})
+
+	t.Run("Duplicate_Name_Conflicts", func(t *testing.T) {
+		scenes := NewScenesHandler(rs, "/display/scenes")
+		rr := httptest.NewRecorder()
+		scenes.Post(rr, httptest.NewRequest(http.MethodPost, "/display/scenes", strings.NewReader(`{"name": "Session 1"}`)))
+		if rr.Code != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusConflict, rr.Body.String())
+		}
+	})
+}
@@ -12,7 +12,7 @@ import (
 
 func TestCreateCombatHandler(t *testing.T) {
 	// 1. Setup a clean test environment.
-	rs, _ := utils.SetupTestEnvironment(t, &combat.Combat{}, &combat.Combatant{})
+	rs, _ := utils.SetupTestEnvironment(t, &combat.Combat{}, &combat.Combatant{}, &combat.HistoryEntry{})
 	handler := NewCombatHandler(rs, "/gameplay/combat")
 
 	// 2. Define the test case.
@@ -50,7 +50,7 @@ func TestCreateCombatHandler(t *testing.T) {
 
 func TestGetActiveCombatHandler(t *testing.T) {
 	// 1. Setup a clean test environment.
-	rs, db := utils.SetupTestEnvironment(t, &combat.Combat{}, &combat.Combatant{})
+	rs, db := utils.SetupTestEnvironment(t, &combat.Combat{}, &combat.Combatant{}, &combat.HistoryEntry{})
 	handler := NewCombatHandler(rs, "/gameplay/combat")
 
 	// 2. Seed the database with the specific data needed for this test.
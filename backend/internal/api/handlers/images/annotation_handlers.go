@@ -0,0 +1,184 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/images"
+	annotationSvc "dmd/backend/internal/services/annotations"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// ImageAnnotationsHandler lists, adds and clears the annotations on an image.
+type ImageAnnotationsHandler struct {
+	handlers.BaseHandler
+	annotationService *annotationSvc.Service
+	log               *slog.Logger
+}
+
+func NewImageAnnotationsHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &ImageAnnotationsHandler{
+		BaseHandler:       handlers.NewBaseHandler(path),
+		annotationService: rs.AnnotationService,
+		log:               rs.Log,
+	}
+}
+
+// GET /images/images/{id}/annotations?role={dm|display|player}&visibility={dm|shared}
+// The role works as it does for /ws: without role=dm, only shared annotations are listed.
+func (h *ImageAnnotationsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	query := r.URL.Query()
+	role, err := wsService.ParseRole(query.Get("role"))
+	if err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid role", err))
+		return
+	}
+	annotations, err := h.annotationService.GetAnnotations(id, query.Get("visibility"), role)
+	if err != nil {
+		utils.RespondWithError(w, newAnnotationError("Failed to get annotations", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, annotations)
+}
+
+func (h *ImageAnnotationsHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var annotation images.Annotation
+	if err = json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	annotation.ImageID = id
+	if err = h.annotationService.Add(&annotation); err != nil {
+		utils.RespondWithError(w, newAnnotationError("Failed to add annotation", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, annotation)
+}
+
+// DELETE /images/images/{id}/annotations - clears the whole layer.
+func (h *ImageAnnotationsHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.annotationService.Clear(id); err != nil {
+		utils.RespondWithError(w, newAnnotationError("Failed to clear annotations", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AnnotationHandler updates and deletes a single annotation.
+type AnnotationHandler struct {
+	handlers.BaseHandler
+	annotationService *annotationSvc.Service
+	log               *slog.Logger
+}
+
+func NewAnnotationHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &AnnotationHandler{
+		BaseHandler:       handlers.NewBaseHandler(path),
+		annotationService: rs.AnnotationService,
+		log:               rs.Log,
+	}
+}
+
+func (h *AnnotationHandler) Put(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var annotation images.Annotation
+	if err = json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	annotation.ID = id
+	if err = h.annotationService.Update(&annotation); err != nil {
+		utils.RespondWithError(w, newAnnotationError("Failed to update annotation", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, annotation)
+}
+
+func (h *AnnotationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.annotationService.Delete(id); err != nil {
+		utils.RespondWithError(w, newAnnotationError("Failed to delete annotation", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AnnotationPointsHandler extends a freehand stroke.
+type AnnotationPointsHandler struct {
+	handlers.BaseHandler
+	annotationService *annotationSvc.Service
+	log               *slog.Logger
+}
+
+func NewAnnotationPointsHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &AnnotationPointsHandler{
+		BaseHandler:       handlers.NewBaseHandler(path),
+		annotationService: rs.AnnotationService,
+		log:               rs.Log,
+	}
+}
+
+// POST /images/annotations/{id}/points
+func (h *AnnotationPointsHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var req struct {
+		Points []images.Point `json:"points"`
+	}
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	annotation, err := h.annotationService.AppendPoints(id, req.Points)
+	if err != nil {
+		utils.RespondWithError(w, newAnnotationError("Failed to append points", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, annotation)
+}
+
+// Helpers
+
+func newAnnotationError(message string, err error) errors2.AppError {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errors2.NewNotFoundError(message, err)
+	case errors.Is(err, annotationSvc.ErrInvalidAnnotation):
+		return errors2.NewBadRequestError(message, err)
+	default:
+		return errors2.NewInternalError(message, err)
+	}
+}
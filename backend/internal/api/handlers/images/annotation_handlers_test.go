@@ -0,0 +1,254 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common/utils"
+	wsHandlers "dmd/backend/internal/api/handlers/websocket"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/annotation_repo"
+	"dmd/backend/internal/platform/storage/repos/images_repo"
+	annotationSvc "dmd/backend/internal/services/annotations"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+func TestAnnotationHandlers(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &images.ImageEntry{}, &images.Annotation{})
+	wsManager := wsService.NewManager(rs.Log)
+	rs.AnnotationService = annotationSvc.NewService(rs.Log, images_repo.NewImagesRepository(db), annotation_repo.NewAnnotationRepository(db), wsManager)
+	go wsManager.Run()
+
+	dungeon := images.ImageEntry{Name: "Dungeon", Type: images.ImageTypeMap, FilePath: "images/dungeon.png"}
+	db.Create(&dungeon)
+
+	layer := NewImageAnnotationsHandler(rs, "/images/images/{id}/annotations")
+	points := NewAnnotationPointsHandler(rs, "/images/annotations/{id}/points")
+
+	newRequest := func(method string, id uint, target, body string) *http.Request {
+		idStr := strconv.Itoa(int(id))
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		return mux.SetURLVars(req, map[string]string{"id": idStr})
+	}
+	add := func(body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		layer.Post(rr, newRequest(http.MethodPost, dungeon.ID, "/images/images/1/annotations", body))
+		return rr
+	}
+
+	var stroke images.Annotation
+
+	t.Run("Add_Stroke_Defaults_To_DM", func(t *testing.T) {
+		rr := add(`{"kind":"stroke","points":[{"x":1,"y":1}],"color":"#ff0000"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+		json.NewDecoder(rr.Body).Decode(&stroke)
+		if stroke.Visibility != images.VisibilityDM {
+			t.Errorf("expected visibility %q, got %q", images.VisibilityDM, stroke.Visibility)
+		}
+	})
+
+	t.Run("Add_Invalid", func(t *testing.T) {
+		testCases := []struct {
+			name string
+			body string
+		}{
+			{"Unknown_Kind", `{"kind":"star","points":[{"x":1,"y":1}]}`},
+			{"Sphere_Without_Size", `{"kind":"sphere","points":[{"x":1,"y":1}]}`},
+			{"Cone_One_Point", `{"kind":"cone","size_feet":15,"points":[{"x":1,"y":1}]}`},
+			{"Empty_Text", `{"kind":"text","points":[{"x":1,"y":1}]}`},
+			{"Bad_Visibility", `{"kind":"rect","visibility":"everyone","points":[{"x":1,"y":1},{"x":2,"y":2}]}`},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if rr := add(tc.body); rr.Code != http.StatusBadRequest {
+					t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+				}
+			})
+		}
+	})
+
+	t.Run("Append_Points", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		points.Post(rr, newRequest(http.MethodPost, stroke.ID, "/images/annotations/1/points", `{"points":[{"x":2,"y":2},{"x":3,"y":3}]}`))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var updated images.Annotation
+		json.NewDecoder(rr.Body).Decode(&updated)
+		if len(updated.Points) != 3 {
+			t.Errorf("expected 3 points, got %d", len(updated.Points))
+		}
+	})
+
+	t.Run("Shared_Filter", func(t *testing.T) {
+		if rr := add(`{"kind":"sphere","visibility":"shared","size_feet":20,"points":[{"x":100,"y":100}]}`); rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+
+		rr := httptest.NewRecorder()
+		layer.Get(rr, newRequest(http.MethodGet, dungeon.ID, "/images/images/1/annotations?role=dm&visibility=shared", ""))
+		var shared []images.Annotation
+		json.NewDecoder(rr.Body).Decode(&shared)
+		if len(shared) != 1 || shared[0].Kind != images.AnnotationSphere {
+			t.Errorf("expected only the shared sphere, got %+v", shared)
+		}
+	})
+
+	t.Run("DM_Only_Hidden_From_Other_Roles", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			query string
+			want  int
+		}{
+			{"DM", "?role=dm", 2},
+			{"Display", "?role=display", 1},
+			{"No_Role", "", 1},
+			{"Player_Asks_For_DM", "?role=player&visibility=dm", 0},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				layer.Get(rr, newRequest(http.MethodGet, dungeon.ID, "/images/images/1/annotations"+tc.query, ""))
+				if rr.Code != http.StatusOK {
+					t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+				}
+				var listed []images.Annotation
+				json.NewDecoder(rr.Body).Decode(&listed)
+				if len(listed) != tc.want {
+					t.Errorf("expected %d annotations, got %d", tc.want, len(listed))
+				}
+				for _, a := range listed {
+					if tc.name != "DM" && a.Visibility != images.VisibilityShared {
+						t.Errorf("expected only shared annotations, got %q", a.Visibility)
+					}
+				}
+			})
+		}
+
+		rr := httptest.NewRecorder()
+		layer.Get(rr, newRequest(http.MethodGet, dungeon.ID, "/images/images/1/annotations?role=admin", ""))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		layer.Delete(rr, newRequest(http.MethodDelete, dungeon.ID, "/images/images/1/annotations", ""))
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+
+		rr = httptest.NewRecorder()
+		layer.Get(rr, newRequest(http.MethodGet, dungeon.ID, "/images/images/1/annotations?role=dm", ""))
+		var remaining []images.Annotation
+		json.NewDecoder(rr.Body).Decode(&remaining)
+		if len(remaining) != 0 {
+			t.Errorf("expected no annotations, got %d", len(remaining))
+		}
+	})
+}
+
+func TestAnnotationOpsByRole(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &images.ImageEntry{}, &images.Annotation{})
+	wsManager := wsService.NewManager(rs.Log)
+	rs.AnnotationService = annotationSvc.NewService(rs.Log, images_repo.NewImagesRepository(db), annotation_repo.NewAnnotationRepository(db), wsManager)
+	go wsManager.Run()
+
+	dungeon := images.ImageEntry{Name: "Dungeon", Type: images.ImageTypeMap, FilePath: "images/dungeon.png"}
+	db.Create(&dungeon)
+
+	router := mux.NewRouter()
+	wsHandlers.RegisterWebsocketRoutes(router, rs.Log, wsManager)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	readOp := func(conn *websocket.Conn) (string, annotationSvc.Op) {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event struct {
+			Type    string           `json:"type"`
+			Payload annotationSvc.Op `json:"payload"`
+		}
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		return event.Type, event.Payload
+	}
+	// dial connects and waits for client_info, so the client is registered before anything is broadcast.
+	dial := func(role string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?role="+role, nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		conn.WriteJSON(map[string]any{"type": wsService.MessageClientInfoRequest})
+		readOp(conn)
+		return conn
+	}
+	dm, display, player := dial("dm"), dial("display"), dial("player")
+	defer dm.Close()
+	defer display.Close()
+	defer player.Close()
+
+	stroke := &images.Annotation{ImageID: dungeon.ID, Kind: images.AnnotationStroke, Points: []images.Point{{X: 1, Y: 1}}}
+	if err := rs.AnnotationService.Add(stroke); err != nil {
+		t.Fatalf("failed to add annotation: %v", err)
+	}
+	if _, op := readOp(dm); op.Op != annotationSvc.OpAdd || op.AnnotationID != stroke.ID {
+		t.Errorf("expected the dm to get the DM-only add, got %+v", op)
+	}
+
+	stroke.Visibility = images.VisibilityShared
+	rs.AnnotationService.Update(stroke)
+	for _, conn := range []*websocket.Conn{dm, display} {
+		// The display's first op is this update, so it never saw the DM-only add.
+		if _, op := readOp(conn); op.Op != annotationSvc.OpUpdate || op.Annotation == nil {
+			t.Errorf("expected everyone to get the shared update, got %+v", op)
+		}
+	}
+
+	stroke.Visibility = images.VisibilityDM
+	rs.AnnotationService.Update(stroke)
+	if _, op := readOp(dm); op.Op != annotationSvc.OpUpdate {
+		t.Errorf("expected the dm to get the update, got %+v", op)
+	}
+	if _, op := readOp(display); op.Op != annotationSvc.OpDelete || op.AnnotationID != stroke.ID || op.Annotation != nil {
+		t.Errorf("expected the display to be told to remove the hidden annotation, got %+v", op)
+	}
+
+	t.Run("Player_Op_Is_Rejected", func(t *testing.T) {
+		player.WriteJSON(map[string]any{
+			"type":    annotationSvc.MessageAnnotationOp,
+			"payload": annotationSvc.Op{Op: annotationSvc.OpDelete, ImageID: dungeon.ID, AnnotationID: stroke.ID},
+		})
+		// Skip the ops the player was sent while the annotation was shared.
+		var event struct {
+			Type    string          `json:"type"`
+			Payload json.RawMessage `json:"payload"`
+		}
+		for event.Type != annotationSvc.EventAnnotationError {
+			player.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if err := player.ReadJSON(&event); err != nil {
+				t.Fatalf("failed to read event: %v", err)
+			}
+		}
+		var rejected annotationSvc.OpError
+		json.Unmarshal(event.Payload, &rejected)
+		if rejected.Op.AnnotationID != stroke.ID || !strings.Contains(rejected.Error, "not permitted") {
+			t.Errorf("expected the player's delete to be rejected, got %+v", rejected)
+		}
+		var count int64
+		db.Model(&images.Annotation{}).Where("id = ?", stroke.ID).Count(&count)
+		if count != 1 {
+			t.Errorf("expected the annotation to survive a player's delete")
+		}
+	})
+}
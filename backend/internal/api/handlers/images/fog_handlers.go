@@ -0,0 +1,112 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/images"
+	mapsSvc "dmd/backend/internal/services/maps"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// FogHandler serves a map's fog mask and resets it.
+type FogHandler struct {
+	handlers.BaseHandler
+	mapsService *mapsSvc.Service
+	log         *slog.Logger
+}
+
+func NewFogHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &FogHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		mapsService: rs.MapsService,
+		log:         rs.Log,
+	}
+}
+
+// GET /images/images/{id}/fog?since={version} - returns the operations needed to rebuild the mask.
+func (h *FogHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+	if since < 0 {
+		since = 0
+	}
+	mask, err := h.mapsService.GetFogMask(id, uint(since))
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to get fog mask", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, mask)
+}
+
+// DELETE /images/images/{id}/fog - covers the whole map with fog again.
+func (h *FogHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	op, err := h.mapsService.ResetFog(id)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to reset fog", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, op)
+}
+
+// FogAreaHandler reveals or hides a single area of a map's fog.
+type FogAreaHandler struct {
+	handlers.BaseHandler
+	apply func(imageID uint, shape string, points []images.Point) (*images.FogOperation, error)
+	log   *slog.Logger
+}
+
+// NewFogRevealHandler handles POST /images/images/{id}/fog/reveal.
+func NewFogRevealHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &FogAreaHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		apply:       rs.MapsService.RevealArea,
+		log:         rs.Log,
+	}
+}
+
+// NewFogHideHandler handles POST /images/images/{id}/fog/hide.
+func NewFogHideHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &FogAreaHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		apply:       rs.MapsService.HideArea,
+		log:         rs.Log,
+	}
+}
+
+type fogAreaRequest struct {
+	Shape  string         `json:"shape"`
+	Points []images.Point `json:"points"`
+}
+
+func (h *FogAreaHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var req fogAreaRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	op, err := h.apply(id, req.Shape, req.Points)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to update fog", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, op)
+}
@@ -0,0 +1,114 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/combat"
+	"dmd/backend/internal/model/crawl"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/character_template_repo"
+	"dmd/backend/internal/platform/storage/repos/combat_repo"
+	"dmd/backend/internal/platform/storage/repos/fog_repo"
+	"dmd/backend/internal/platform/storage/repos/images_repo"
+	"dmd/backend/internal/platform/storage/repos/token_repo"
+	mapsSvc "dmd/backend/internal/services/maps"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func TestFogHandlers(t *testing.T) {
+	rs, db, _ := setupMapsTest(t)
+
+	dungeon := images.ImageEntry{Name: "Dungeon", Type: images.ImageTypeMap, FilePath: "images/dungeon.png"}
+	portrait := images.ImageEntry{Name: "Ogre", Type: images.ImageTypeImage, FilePath: "images/ogre.png"}
+	db.Create(&dungeon)
+	db.Create(&portrait)
+
+	reveal := NewFogRevealHandler(rs, "/images/images/{id}/fog/reveal")
+	fog := NewFogHandler(rs, "/images/images/{id}/fog")
+
+	postArea := func(imageID uint, body string) *httptest.ResponseRecorder {
+		id := strconv.Itoa(int(imageID))
+		req := httptest.NewRequest(http.MethodPost, "/images/images/"+id+"/fog/reveal", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr := httptest.NewRecorder()
+		reveal.Post(rr, req)
+		return rr
+	}
+
+	t.Run("Reveal_Rect", func(t *testing.T) {
+		rr := postArea(dungeon.ID, `{"shape":"rect","points":[{"x":10,"y":10},{"x":200,"y":150}]}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+	})
+
+	t.Run("Reveal_Invalid_Polygon", func(t *testing.T) {
+		rr := postArea(dungeon.ID, `{"shape":"polygon","points":[{"x":10,"y":10},{"x":200,"y":150}]}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Reveal_Not_A_Map", func(t *testing.T) {
+		rr := postArea(portrait.ID, `{"shape":"rect","points":[{"x":0,"y":0},{"x":1,"y":1}]}`)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Reset_Then_Get_Mask", func(t *testing.T) {
+		id := strconv.Itoa(int(dungeon.ID))
+		req := httptest.NewRequest(http.MethodDelete, "/images/images/"+id+"/fog", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr := httptest.NewRecorder()
+		fog.Delete(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+
+		req = httptest.NewRequest(http.MethodGet, "/images/images/"+id+"/fog", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		rr = httptest.NewRecorder()
+		fog.Get(rr, req)
+
+		var mask images.FogMask
+		json.NewDecoder(rr.Body).Decode(&mask)
+		if mask.Version != 2 || len(mask.Operations) != 1 || mask.Operations[0].Op != images.FogOpReset {
+			t.Errorf("expected mask at version 2 holding only the reset, got %+v", mask)
+		}
+	})
+}
+
+// setupMapsTest builds a maps service over a fresh database with every table it touches.
+// It also returns the images directory the map files are read from.
+func setupMapsTest(t *testing.T) (*common.RoutingServices, *gorm.DB, string) {
+	rs, db := utils.SetupTestEnvironment(t,
+		&images.ImageEntry{}, &images.FogOperation{}, &images.MapToken{},
+		&crawl.CharacterTemplate{}, &combat.Combat{}, &combat.Combatant{}, &combat.HistoryEntry{})
+	imagesDir := filepath.Join(t.TempDir(), "images")
+	if err := os.MkdirAll(imagesDir, 0755); err != nil {
+		t.Fatalf("failed to create images dir: %v", err)
+	}
+	wsManager := wsService.NewManager(rs.Log)
+	go wsManager.Run()
+	rs.MapsService = mapsSvc.NewService(rs.Log,
+		images_repo.NewImagesRepository(db),
+		fog_repo.NewFogRepository(db),
+		token_repo.NewMapTokenRepository(db),
+		character_template_repo.NewCharacterTemplateRepository(db),
+		combat_repo.NewCombatRepository(db),
+		wsManager,
+		imagesDir)
+	return rs, db, imagesDir
+}
@@ -0,0 +1,153 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/images"
+	mapsSvc "dmd/backend/internal/services/maps"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// GridHandler reads and calibrates a map's grid.
+type GridHandler struct {
+	handlers.BaseHandler
+	mapsService *mapsSvc.Service
+	log         *slog.Logger
+}
+
+func NewGridHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &GridHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		mapsService: rs.MapsService,
+		log:         rs.Log,
+	}
+}
+
+func (h *GridHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	grid, err := h.mapsService.GetGrid(id)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to get grid", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, grid)
+}
+
+// PUT /images/images/{id}/grid - calibrates the map's grid.
+func (h *GridHandler) Put(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var grid images.MapGrid
+	if err = json.NewDecoder(r.Body).Decode(&grid); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	grid, err = h.mapsService.CalibrateGrid(id, grid)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to calibrate grid", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, grid)
+}
+
+func (h *GridHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.mapsService.ClearGrid(id); err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to clear grid", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GridCellHandler converts a pixel position to a grid cell.
+type GridCellHandler struct {
+	handlers.BaseHandler
+	mapsService *mapsSvc.Service
+	log         *slog.Logger
+}
+
+func NewGridCellHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &GridCellHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		mapsService: rs.MapsService,
+		log:         rs.Log,
+	}
+}
+
+// GET /images/images/{id}/grid/cell?x={px}&y={px}
+func (h *GridCellHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	queryParams := r.URL.Query()
+	x, errX := strconv.ParseFloat(queryParams.Get("x"), 64)
+	y, errY := strconv.ParseFloat(queryParams.Get("y"), 64)
+	if errX != nil || errY != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid pixel coordinates", errX, errY))
+		return
+	}
+	cell, err := h.mapsService.CellAt(id, x, y)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to convert coordinates", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, cell)
+}
+
+// GridMeasureHandler measures the distance between two pixel positions.
+type GridMeasureHandler struct {
+	handlers.BaseHandler
+	mapsService *mapsSvc.Service
+	log         *slog.Logger
+}
+
+func NewGridMeasureHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &GridMeasureHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		mapsService: rs.MapsService,
+		log:         rs.Log,
+	}
+}
+
+type measureRequest struct {
+	From images.Point `json:"from"`
+	To   images.Point `json:"to"`
+}
+
+// POST /images/images/{id}/grid/measure
+func (h *GridMeasureHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var req measureRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	measurement, err := h.mapsService.Measure(id, req.From, req.To)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to measure distance", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, measurement)
+}
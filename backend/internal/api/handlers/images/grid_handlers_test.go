@@ -0,0 +1,161 @@
+package images
+
+import (
+	"dmd/backend/internal/model/images"
+	mapsSvc "dmd/backend/internal/services/maps"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestGridHandlers(t *testing.T) {
+	rs, db, imagesDir := setupMapsTest(t)
+
+	// Only the dungeon's image is on disk, so only its extent can be worked out.
+	writeTestMap(t, filepath.Join(imagesDir, "dungeon.png"), 1000, 600)
+	dungeon := images.ImageEntry{Name: "Dungeon", Type: images.ImageTypeMap, FilePath: "images/dungeon.png"}
+	overland := images.ImageEntry{Name: "Overland", Type: images.ImageTypeMap, FilePath: "images/overland.png"}
+	db.Create(&dungeon)
+	db.Create(&overland)
+
+	grid := NewGridHandler(rs, "/images/images/{id}/grid")
+	measure := NewGridMeasureHandler(rs, "/images/images/{id}/grid/measure")
+
+	newRequest := func(method string, imageID uint, body string) *http.Request {
+		id := strconv.Itoa(int(imageID))
+		req := httptest.NewRequest(method, "/images/images/"+id+"/grid", strings.NewReader(body))
+		return mux.SetURLVars(req, map[string]string{"id": id})
+	}
+
+	postMeasure := func(imageID uint, body string) (*httptest.ResponseRecorder, mapsSvc.Measurement) {
+		rr := httptest.NewRecorder()
+		measure.Post(rr, newRequest(http.MethodPost, imageID, body))
+		var m mapsSvc.Measurement
+		json.NewDecoder(rr.Body).Decode(&m)
+		return rr, m
+	}
+
+	t.Run("Measure_Uncalibrated", func(t *testing.T) {
+		rr, _ := postMeasure(dungeon.ID, `{"from":{"x":0,"y":0},"to":{"x":100,"y":100}}`)
+		if rr.Code != http.StatusConflict {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("Calibrate_Invalid", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			imageID uint
+			body    string
+		}{
+			{"Unknown_Type", dungeon.ID, `{"type":"triangle","cell_size":50}`},
+			{"Negative_Columns", dungeon.ID, `{"type":"square","cell_size":50,"columns":-1,"rows":10}`},
+			{"Extent_Unknown", overland.ID, `{"type":"hex","cell_size":60}`},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				grid.Put(rr, newRequest(http.MethodPut, tc.imageID, tc.body))
+				if rr.Code != http.StatusBadRequest {
+					t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+				}
+			})
+		}
+	})
+
+	t.Run("Square_Measure", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		grid.Put(rr, newRequest(http.MethodPut, dungeon.ID, `{"type":"square","cell_size":50,"offset_x":10,"offset_y":10}`))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		// 990x590 pixels from the offset hold the centres of 20 columns and 12 rows.
+		var g images.MapGrid
+		json.NewDecoder(rr.Body).Decode(&g)
+		if g.Columns != 20 || g.Rows != 12 {
+			t.Errorf("expected the extent to come from the image size, got %dx%d", g.Columns, g.Rows)
+		}
+
+		// From cell (0,0) to cell (3,1): diagonals count as one cell.
+		rr, m := postMeasure(dungeon.ID, `{"from":{"x":20,"y":20},"to":{"x":170,"y":70}}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if m.To != (images.GridCell{Col: 3, Row: 1}) || m.Cells != 3 || m.Feet != 15 {
+			t.Errorf("unexpected measurement: %+v", m)
+		}
+	})
+
+	t.Run("Hex_Measure", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		grid.Put(rr, newRequest(http.MethodPut, overland.ID, `{"type":"hex","cell_size":60,"feet_per_cell":10,"columns":30,"rows":20}`))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+
+		g := images.MapGrid{Type: images.GridTypeHex, CellSize: 60}
+		from := g.CellCenter(images.GridCell{Col: 0, Row: 0})
+		to := g.CellCenter(images.GridCell{Col: 2, Row: 2})
+		body, _ := json.Marshal(measureRequest{From: from, To: to})
+		rr, m := postMeasure(overland.ID, string(body))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if m.To != (images.GridCell{Col: 2, Row: 2}) || m.Cells != 3 || m.Feet != 30 {
+			t.Errorf("unexpected measurement: %+v", m)
+		}
+	})
+
+	t.Run("Extent_Backfilled", func(t *testing.T) {
+		// A grid saved before columns and rows were kept stays calibrated and gets them from the image.
+		db.Model(&dungeon).Updates(map[string]any{"grid_columns": 0, "grid_rows": 0})
+		rr := httptest.NewRecorder()
+		grid.Get(rr, newRequest(http.MethodGet, dungeon.ID, ""))
+		var g images.MapGrid
+		json.NewDecoder(rr.Body).Decode(&g)
+		if !g.IsCalibrated() || g.Columns != 20 || g.Rows != 12 {
+			t.Errorf("expected a 20x12 grid, got %+v", g)
+		}
+		var stored images.ImageEntry
+		db.First(&stored, dungeon.ID)
+		if stored.Grid.Columns != 20 || stored.Grid.Rows != 12 {
+			t.Errorf("expected the extent to be saved, got %+v", stored.Grid)
+		}
+	})
+
+	t.Run("Clear", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		grid.Delete(rr, newRequest(http.MethodDelete, dungeon.ID, ""))
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+		rr = httptest.NewRecorder()
+		grid.Get(rr, newRequest(http.MethodGet, dungeon.ID, ""))
+		var g images.MapGrid
+		json.NewDecoder(rr.Body).Decode(&g)
+		if g.IsCalibrated() {
+			t.Errorf("expected grid to be cleared, got %+v", g)
+		}
+	})
+}
+
+// writeTestMap writes a blank PNG of the given size.
+func writeTestMap(t *testing.T, path string, width, height int) {
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create map image: %v", err)
+	}
+	defer file.Close()
+	if err = png.Encode(file, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("failed to write map image: %v", err)
+	}
+}
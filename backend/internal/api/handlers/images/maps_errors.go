@@ -0,0 +1,25 @@
+package images
+
+import (
+	errors2 "dmd/backend/internal/api/common/errors"
+	mapsSvc "dmd/backend/internal/services/maps"
+	"errors"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// newMapsError maps errors from the maps service to the matching HTTP status.
+func newMapsError(message string, err error) errors2.AppError {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return errors2.NewNotFoundError(message, err)
+	case errors.Is(err, mapsSvc.ErrNotAMap), errors.Is(err, mapsSvc.ErrInvalidShape), errors.Is(err, mapsSvc.ErrInvalidGrid),
+		errors.Is(err, mapsSvc.ErrInvalidToken), errors.Is(err, mapsSvc.ErrOutsideGrid):
+		return errors2.NewBadRequestError(message, err)
+	case errors.Is(err, mapsSvc.ErrNotCalibrated):
+		return errors2.NewAppError(http.StatusConflict, message, err)
+	default:
+		return errors2.NewInternalError(message, err)
+	}
+}
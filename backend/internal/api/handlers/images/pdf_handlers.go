@@ -0,0 +1,335 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/images"
+	imagesSvc "dmd/backend/internal/services/images"
+	pdfSvc "dmd/backend/internal/services/pdf"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// PdfPagesHandler reports how many pages a PDF has.
+type PdfPagesHandler struct {
+	handlers.BaseHandler
+	pdfService *pdfSvc.Service
+	log        *slog.Logger
+}
+
+func NewPdfPagesHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PdfPagesHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		pdfService:  rs.PdfService,
+		log:         rs.Log,
+	}
+}
+
+// GET /images/pdfs/{id}/pages
+func (h *PdfPagesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	count, err := h.pdfService.GetPageCount(id)
+	if err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to count pages", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]int{"page_count": count})
+}
+
+// PdfPageHandler serves a single page of a PDF as a PNG.
+type PdfPageHandler struct {
+	handlers.BaseHandler
+	pdfService *pdfSvc.Service
+	log        *slog.Logger
+	thumbnail  bool
+}
+
+// NewPdfPageHandler renders at the DPI given by ?dpi=, or DefaultDPI.
+func NewPdfPageHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return newPdfPageHandler(rs, path, false)
+}
+
+// NewPdfThumbnailHandler always renders at ThumbnailDPI.
+func NewPdfThumbnailHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return newPdfPageHandler(rs, path, true)
+}
+
+func newPdfPageHandler(rs *common.RoutingServices, path string, thumbnail bool) common.IHandler {
+	return &PdfPageHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		pdfService:  rs.PdfService,
+		log:         rs.Log,
+		thumbnail:   thumbnail,
+	}
+}
+
+// GET /images/pdfs/{id}/pages/{page}?dpi={dpi}
+func (h *PdfPageHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	page, err := strconv.Atoi(mux.Vars(r)["page"])
+	if err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid page number", err))
+		return
+	}
+
+	dpi := pdfSvc.DefaultDPI
+	if h.thumbnail {
+		dpi = pdfSvc.ThumbnailDPI
+	} else if dpiStr := r.URL.Query().Get("dpi"); dpiStr != "" {
+		if dpi, err = strconv.Atoi(dpiStr); err != nil {
+			utils.RespondWithError(w, errors2.NewBadRequestError("Invalid dpi", err))
+			return
+		}
+	}
+
+	pngPath, err := h.pdfService.RenderPage(id, page, dpi)
+	if err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to render page", err))
+		return
+	}
+
+	// ServeFile answers conditional requests from the render's modification time.
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeFile(w, r, pngPath)
+}
+
+// PdfCropHandler saves a region of a PDF page as a new image.
+type PdfCropHandler struct {
+	handlers.BaseHandler
+	pdfService   *pdfSvc.Service
+	imageService *imagesSvc.Service
+	log          *slog.Logger
+}
+
+func NewPdfCropHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PdfCropHandler{
+		BaseHandler:  handlers.NewBaseHandler(path),
+		pdfService:   rs.PdfService,
+		imageService: rs.ImageService,
+		log:          rs.Log,
+	}
+}
+
+type cropRequest struct {
+	pdfSvc.CropRect
+	DPI  int    `json:"dpi"`
+	Name string `json:"name"`
+}
+
+// POST /images/pdfs/{id}/pages/{page}/crop
+// The image is written to the images directory, where the directory watcher picks it up.
+func (h *PdfCropHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	page, err := strconv.Atoi(mux.Vars(r)["page"])
+	if err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid page number", err))
+		return
+	}
+	var req cropRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if req.DPI == 0 {
+		req.DPI = pdfSvc.DefaultDPI
+	}
+
+	fileName, err := h.pdfService.CropPage(id, page, req.CropRect, req.DPI, req.Name, h.imageService.GetImagesPath())
+	if err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to crop page", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, map[string]string{"filename": fileName})
+}
+
+// PdfSearchHandler searches the text of every indexed PDF.
+type PdfSearchHandler struct {
+	handlers.BaseHandler
+	pdfService *pdfSvc.Service
+	log        *slog.Logger
+}
+
+func NewPdfSearchHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PdfSearchHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		pdfService:  rs.PdfService,
+		log:         rs.Log,
+	}
+}
+
+// GET /images/pdfs/search?q={query}&limit={n}
+func (h *PdfSearchHandler) Get(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	limit, _ := strconv.Atoi(queryParams.Get("limit"))
+
+	results, err := h.pdfService.Search(queryParams.Get("q"), limit)
+	if err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to search pdfs", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, results)
+}
+
+// PdfOutlineHandler serves a PDF's table of contents.
+type PdfOutlineHandler struct {
+	handlers.BaseHandler
+	pdfService *pdfSvc.Service
+	log        *slog.Logger
+}
+
+func NewPdfOutlineHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PdfOutlineHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		pdfService:  rs.PdfService,
+		log:         rs.Log,
+	}
+}
+
+// GET /images/pdfs/{id}/outline
+func (h *PdfOutlineHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	outline, err := h.pdfService.GetOutline(id)
+	if err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to read outline", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, outline)
+}
+
+// PdfBookmarksHandler lists and adds the DM's bookmarks in a PDF.
+type PdfBookmarksHandler struct {
+	handlers.BaseHandler
+	pdfService *pdfSvc.Service
+	log        *slog.Logger
+}
+
+func NewPdfBookmarksHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PdfBookmarksHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		pdfService:  rs.PdfService,
+		log:         rs.Log,
+	}
+}
+
+// GET /images/pdfs/{id}/bookmarks
+func (h *PdfBookmarksHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	bookmarks, err := h.pdfService.GetBookmarks(id)
+	if err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to get bookmarks", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, bookmarks)
+}
+
+// POST /images/pdfs/{id}/bookmarks
+func (h *PdfBookmarksHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var bookmark images.PdfBookmark
+	if err = json.NewDecoder(r.Body).Decode(&bookmark); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	bookmark.ImageID = id
+	if err = h.pdfService.AddBookmark(&bookmark); err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to add bookmark", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, bookmark)
+}
+
+// PdfBookmarkHandler updates and deletes a single bookmark.
+type PdfBookmarkHandler struct {
+	handlers.BaseHandler
+	pdfService *pdfSvc.Service
+	log        *slog.Logger
+}
+
+func NewPdfBookmarkHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PdfBookmarkHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		pdfService:  rs.PdfService,
+		log:         rs.Log,
+	}
+}
+
+// PUT /images/pdfs/bookmarks/{id}
+func (h *PdfBookmarkHandler) Put(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var bookmark images.PdfBookmark
+	if err = json.NewDecoder(r.Body).Decode(&bookmark); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	bookmark.ID = id
+	if err = h.pdfService.UpdateBookmark(&bookmark); err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to update bookmark", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, bookmark)
+}
+
+// DELETE /images/pdfs/bookmarks/{id}
+func (h *PdfBookmarkHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.pdfService.DeleteBookmark(id); err != nil {
+		utils.RespondWithError(w, newPdfError("Failed to delete bookmark", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Helpers
+
+func newPdfError(message string, err error) errors2.AppError {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, fs.ErrNotExist):
+		return errors2.NewNotFoundError(message, err)
+	case errors.Is(err, pdfSvc.ErrNotAPdf), errors.Is(err, pdfSvc.ErrInvalidPage), errors.Is(err, pdfSvc.ErrInvalidDPI),
+		errors.Is(err, pdfSvc.ErrInvalidCrop), errors.Is(err, pdfSvc.ErrEmptyQuery), errors.Is(err, pdfSvc.ErrInvalidBookmark):
+		return errors2.NewBadRequestError(message, err)
+	default:
+		return errors2.NewInternalError(message, err)
+	}
+}
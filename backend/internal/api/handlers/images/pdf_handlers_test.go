@@ -0,0 +1,506 @@
+package images
+
+import (
+	"bytes"
+	"dmd/backend/internal/api/common"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/bookmark_repo"
+	"dmd/backend/internal/platform/storage/repos/images_repo"
+	"dmd/backend/internal/platform/storage/repos/pdf_text_repo"
+	imagesSvc "dmd/backend/internal/services/images"
+	pdfSvc "dmd/backend/internal/services/pdf"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func TestPdfPageHandlers(t *testing.T) {
+	rs, db := setupPdfTest(t, map[string]testPdf{
+		"module.pdf": {pages: []string{"The Sunless Citadel", "Area 12: The Dragon Shrine"}},
+	})
+
+	var module images.ImageEntry
+	db.Where("file_path = ?", "pdf/module.pdf").First(&module)
+
+	pagesHandler := NewPdfPagesHandler(rs, "/images/pdfs/{id}/pages")
+	pageHandler := NewPdfPageHandler(rs, "/images/pdfs/{id}/pages/{page}")
+	thumbHandler := NewPdfThumbnailHandler(rs, "/images/pdfs/{id}/pages/{page}/thumbnail")
+
+	getPage := func(handler common.IHandler, page, query string) *httptest.ResponseRecorder {
+		id := strconv.Itoa(int(module.ID))
+		req := httptest.NewRequest(http.MethodGet, "/images/pdfs/"+id+"/pages/"+page+query, nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id, "page": page})
+		rr := httptest.NewRecorder()
+		handler.Get(rr, req)
+		return rr
+	}
+
+	t.Run("Page_Count_Indexed", func(t *testing.T) {
+		if module.PageCount != 2 {
+			t.Errorf("expected page count 2 on the entry, got %d", module.PageCount)
+		}
+		rr := getPage(pagesHandler, "", "")
+		var body map[string]int
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body["page_count"] != 2 {
+			t.Errorf("expected page_count 2, got %v", body)
+		}
+	})
+
+	t.Run("Render_Page_At_DPI", func(t *testing.T) {
+		rr := getPage(pageHandler, "2", "?dpi=72")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		img, err := png.Decode(bytes.NewReader(rr.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("response is not a PNG: %v", err)
+		}
+		// The fixture pages are 200x100 points, so 72 DPI maps one point to one pixel.
+		if b := img.Bounds(); b.Dx() != 200 || b.Dy() != 100 {
+			t.Errorf("expected a 200x100 image, got %dx%d", b.Dx(), b.Dy())
+		}
+	})
+
+	t.Run("Render_Is_Cached", func(t *testing.T) {
+		first := getPage(thumbHandler, "1", "")
+		if first.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", first.Code, http.StatusOK)
+		}
+		id := strconv.Itoa(int(module.ID))
+		req := httptest.NewRequest(http.MethodGet, "/images/pdfs/"+id+"/pages/1/thumbnail", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": id, "page": "1"})
+		req.Header.Set("If-Modified-Since", first.Header().Get("Last-Modified"))
+		rr := httptest.NewRecorder()
+		thumbHandler.Get(rr, req)
+		if rr.Code != http.StatusNotModified {
+			t.Errorf("expected the cached render to be reused, got status %v", rr.Code)
+		}
+	})
+
+	t.Run("Invalid_Requests", func(t *testing.T) {
+		testCases := []struct {
+			name  string
+			page  string
+			query string
+		}{
+			{"Page_Out_Of_Range", "3", ""},
+			{"Page_Zero", "0", ""},
+			{"DPI_Too_High", "1", "?dpi=1200"},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if rr := getPage(pageHandler, tc.page, tc.query); rr.Code != http.StatusBadRequest {
+					t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+				}
+			})
+		}
+	})
+}
+
+func TestPdfCropHandler(t *testing.T) {
+	rs, db := setupPdfTest(t, map[string]testPdf{
+		"module.pdf": {pages: []string{"Map of the Citadel"}},
+	})
+	imagesDir := filepath.Join(filepath.Dir(rs.PdfService.GetPdfPath()), "images")
+	if err := os.MkdirAll(imagesDir, 0755); err != nil {
+		t.Fatalf("failed to create images dir: %v", err)
+	}
+	rs.ImageService = imagesSvc.NewService(rs.Log, images_repo.NewImagesRepository(db), wsService.NewManager(rs.Log), imagesDir)
+
+	var module images.ImageEntry
+	db.Where("file_path = ?", "pdf/module.pdf").First(&module)
+	handler := NewPdfCropHandler(rs, "/images/pdfs/{id}/pages/{page}/crop")
+
+	crop := func(page, body string) *httptest.ResponseRecorder {
+		id := strconv.Itoa(int(module.ID))
+		req := httptest.NewRequest(http.MethodPost, "/images/pdfs/"+id+"/pages/"+page+"/crop", strings.NewReader(body))
+		req = mux.SetURLVars(req, map[string]string{"id": id, "page": page})
+		rr := httptest.NewRecorder()
+		handler.Post(rr, req)
+		return rr
+	}
+
+	t.Run("Crop_Region_To_Image", func(t *testing.T) {
+		rr := crop("1", `{"x": 0.5, "y": 0, "width": 0.5, "height": 0.5, "dpi": 72, "name": "Citadel Map"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+		var body map[string]string
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body["filename"] != "Citadel_Map.png" {
+			t.Fatalf("expected filename Citadel_Map.png, got %v", body)
+		}
+
+		f, err := os.Open(filepath.Join(imagesDir, body["filename"]))
+		if err != nil {
+			t.Fatalf("cropped image not written: %v", err)
+		}
+		defer f.Close()
+		img, err := png.Decode(f)
+		if err != nil {
+			t.Fatalf("cropped file is not a PNG: %v", err)
+		}
+		// Half of a 200x100 point page in each direction at one pixel per point.
+		if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+			t.Errorf("expected a 100x50 image, got %dx%d", b.Dx(), b.Dy())
+		}
+
+		// The images watcher registers the file on its next sync.
+		rs.ImageService.SyncImageEntriesWithDatabase()
+		var count int64
+		db.Model(&images.ImageEntry{}).Where("file_path = ?", "images/Citadel_Map.png").Count(&count)
+		if count != 1 {
+			t.Errorf("expected the cropped image to be registered, found %d entries", count)
+		}
+	})
+
+	t.Run("Existing_File_Is_Not_Overwritten", func(t *testing.T) {
+		rr := crop("1", `{"x": 0, "y": 0, "width": 1, "height": 1, "dpi": 72, "name": "Citadel Map"}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusCreated)
+		}
+		var body map[string]string
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body["filename"] != "Citadel_Map_1.png" {
+			t.Errorf("expected filename Citadel_Map_1.png, got %v", body)
+		}
+
+		entries, _ := os.ReadDir(imagesDir)
+		for _, entry := range entries {
+			if strings.HasPrefix(entry.Name(), ".") {
+				t.Errorf("expected the temp file to be cleaned up, found %s", entry.Name())
+			}
+		}
+	})
+
+	t.Run("Default_Name_From_Pdf", func(t *testing.T) {
+		rr := crop("1", `{"x": 0, "y": 0, "width": 0.25, "height": 0.25}`)
+		var body map[string]string
+		json.NewDecoder(rr.Body).Decode(&body)
+		if body["filename"] != "module_p1.png" {
+			t.Errorf("expected filename module_p1.png, got %v", body)
+		}
+	})
+
+	t.Run("Invalid_Requests", func(t *testing.T) {
+		cases := map[string]struct{ page, body string }{
+			"outside page":  {"1", `{"x": 0.8, "y": 0, "width": 0.5, "height": 0.5}`},
+			"empty region":  {"1", `{"x": 0, "y": 0, "width": 0, "height": 0.5}`},
+			"page too high": {"2", `{"x": 0, "y": 0, "width": 0.5, "height": 0.5}`},
+			"dpi too high":  {"1", `{"x": 0, "y": 0, "width": 0.5, "height": 0.5, "dpi": 1000}`},
+		}
+		for name, c := range cases {
+			if rr := crop(c.page, c.body); rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", name, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+func TestPdfSearchHandler(t *testing.T) {
+	rs, db := setupPdfTest(t, map[string]testPdf{
+		"citadel.pdf": {pages: []string{"The Sunless Citadel", "Area 12 The Dragon Shrine"}},
+		"forge.pdf":   {pages: []string{"Forge of Fury", "The Black Dragon Lair"}},
+	})
+	handler := NewPdfSearchHandler(rs, "/images/pdfs/search")
+
+	search := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/images/pdfs/search?"+query, nil)
+		rr := httptest.NewRecorder()
+		handler.Get(rr, req)
+		return rr
+	}
+
+	t.Run("Matches_Across_Pdfs", func(t *testing.T) {
+		rr := search("q=dragon")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var results []images.PdfSearchResult
+		json.NewDecoder(rr.Body).Decode(&results)
+		if len(results) != 2 {
+			t.Fatalf("expected a match in each pdf, got %+v", results)
+		}
+		for _, result := range results {
+			if result.Page != 2 || result.Snippet == "" {
+				t.Errorf("expected page 2 with a snippet, got %+v", result)
+			}
+		}
+	})
+
+	t.Run("All_Words_Must_Match", func(t *testing.T) {
+		var results []images.PdfSearchResult
+		json.NewDecoder(search("q=dragon+shrine").Body).Decode(&results)
+		if len(results) != 1 || results[0].Name != "citadel" {
+			t.Errorf("expected only the citadel, got %+v", results)
+		}
+	})
+
+	t.Run("Changed_Pdf_Is_Reindexed", func(t *testing.T) {
+		path := filepath.Join(rs.PdfService.GetPdfPath(), "forge.pdf")
+		writeTestPdf(t, path, testPdf{pages: []string{"Forge of Fury", "The Lich Lair", "Appendix"}})
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(path, later, later)
+		rs.PdfService.SyncPdfEntriesWithDatabase()
+		waitForTextIndex(t, db, rs.PdfService.GetPdfPath())
+
+		var results []images.PdfSearchResult
+		json.NewDecoder(search("q=lich").Body).Decode(&results)
+		if len(results) != 1 || results[0].Name != "forge" || results[0].Page != 2 {
+			t.Errorf("expected the new text of the forge to be found, got %+v", results)
+		}
+		json.NewDecoder(search("q=black").Body).Decode(&results)
+		if len(results) != 0 {
+			t.Errorf("expected the old text to be gone, got %+v", results)
+		}
+	})
+
+	t.Run("Empty_Query", func(t *testing.T) {
+		if rr := search("q=+"); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestPdfOutlineAndBookmarks(t *testing.T) {
+	rs, db := setupPdfTest(t, map[string]testPdf{
+		"temple.pdf": {
+			pages: []string{"Introduction", "Chapter 3", "The Sunken Temple", "Appendix"},
+			outline: []testOutlineItem{
+				{level: 1, title: "Introduction", page: 1},
+				{level: 1, title: "Chapter 3: The Sunken Temple", page: 2},
+				{level: 2, title: "Flooded Nave", page: 3},
+				{level: 1, title: "Appendix", page: 4},
+			},
+		},
+		"handouts.pdf": {pages: []string{"Handout"}},
+	})
+
+	var temple, handouts images.ImageEntry
+	db.Where("file_path = ?", "pdf/temple.pdf").First(&temple)
+	db.Where("file_path = ?", "pdf/handouts.pdf").First(&handouts)
+
+	outlineHandler := NewPdfOutlineHandler(rs, "/images/pdfs/{id}/outline")
+	bookmarksHandler := NewPdfBookmarksHandler(rs, "/images/pdfs/{id}/bookmarks")
+
+	newRequest := func(method string, id uint, body string) *http.Request {
+		idStr := strconv.Itoa(int(id))
+		req := httptest.NewRequest(method, "/images/pdfs/"+idStr, strings.NewReader(body))
+		return mux.SetURLVars(req, map[string]string{"id": idStr})
+	}
+	getOutline := func(id uint) []*images.OutlineEntry {
+		rr := httptest.NewRecorder()
+		outlineHandler.Get(rr, newRequest(http.MethodGet, id, ""))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var outline []*images.OutlineEntry
+		json.NewDecoder(rr.Body).Decode(&outline)
+		return outline
+	}
+
+	t.Run("Outline_Tree", func(t *testing.T) {
+		outline := getOutline(temple.ID)
+		if len(outline) != 3 {
+			t.Fatalf("expected 3 top-level entries, got %d", len(outline))
+		}
+		chapter := outline[1]
+		if chapter.Title != "Chapter 3: The Sunken Temple" || chapter.Page != 2 {
+			t.Errorf("unexpected chapter entry: %+v", chapter)
+		}
+		if len(chapter.Children) != 1 || chapter.Children[0].Title != "Flooded Nave" || chapter.Children[0].Page != 3 {
+			t.Errorf("expected the nave nested under the chapter, got %+v", chapter.Children)
+		}
+	})
+
+	t.Run("No_Outline", func(t *testing.T) {
+		if outline := getOutline(handouts.ID); len(outline) != 0 {
+			t.Errorf("expected an empty outline, got %+v", outline)
+		}
+	})
+
+	t.Run("Add_Bookmark", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		bookmarksHandler.Post(rr, newRequest(http.MethodPost, temple.ID, `{"name":"Altar room","page":3,"position_y":0.4}`))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+
+		rr = httptest.NewRecorder()
+		bookmarksHandler.Get(rr, newRequest(http.MethodGet, temple.ID, ""))
+		var bookmarks []images.PdfBookmark
+		json.NewDecoder(rr.Body).Decode(&bookmarks)
+		if len(bookmarks) != 1 || bookmarks[0].Name != "Altar room" || bookmarks[0].Page != 3 {
+			t.Errorf("unexpected bookmarks: %+v", bookmarks)
+		}
+	})
+
+	t.Run("Add_Bookmark_Invalid", func(t *testing.T) {
+		for _, body := range []string{`{"name":"Too far","page":5}`, `{"name":" ","page":1}`} {
+			rr := httptest.NewRecorder()
+			bookmarksHandler.Post(rr, newRequest(http.MethodPost, temple.ID, body))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code for %s: got %v want %v", body, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+// testPdf describes a fixture PDF: the text shown on each page and an optional outline.
+type testPdf struct {
+	pages   []string
+	outline []testOutlineItem
+}
+
+// testOutlineItem is an outline entry, listed depth-first; page is numbered from 1.
+type testOutlineItem struct {
+	level int
+	title string
+	page  int
+}
+
+// setupPdfTest writes the given PDFs into a temporary pdf directory and builds
+// a pdf service that has indexed them.
+func setupPdfTest(t *testing.T, pdfs map[string]testPdf) (*common.RoutingServices, *gorm.DB) {
+	rs, db := utils.SetupTestEnvironment(t, &images.ImageEntry{}, &images.PdfPage{}, &images.PdfBookmark{})
+	pdfDir := filepath.Join(t.TempDir(), "pdf")
+	if err := os.MkdirAll(pdfDir, 0755); err != nil {
+		t.Fatalf("failed to create pdf dir: %v", err)
+	}
+	for name, pdf := range pdfs {
+		writeTestPdf(t, filepath.Join(pdfDir, name), pdf)
+	}
+
+	wsManager := wsService.NewManager(rs.Log)
+	go wsManager.Run()
+	rs.PdfService = pdfSvc.NewService(rs.Log, images_repo.NewImagesRepository(db), pdf_text_repo.NewPdfTextRepository(db), bookmark_repo.NewPdfBookmarkRepository(db), wsManager, pdfDir)
+
+	// Text is extracted in the background, not while the service starts.
+	var indexed int64
+	db.Model(&images.PdfPage{}).Count(&indexed)
+	if indexed != 0 {
+		t.Fatalf("expected no text to be indexed before the indexer runs, got %d pages", indexed)
+	}
+	rs.PdfService.RunTextIndexer()
+	waitForTextIndex(t, db, pdfDir)
+	return rs, db
+}
+
+// waitForTextIndex waits until the text of every PDF has been indexed from its current file.
+func waitForTextIndex(t *testing.T, db *gorm.DB, pdfDir string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		var entries []images.ImageEntry
+		db.Where("type = ?", images.ImageTypePDF).Find(&entries)
+		done := true
+		for _, entry := range entries {
+			info, err := os.Stat(filepath.Join(filepath.Dir(pdfDir), entry.FilePath))
+			var page images.PdfPage
+			if err != nil || db.Where("image_id = ?", entry.ID).First(&page).Error != nil || !page.FileModTime.Equal(info.ModTime()) {
+				done = false
+			}
+		}
+		if done {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting for pdf text to be indexed")
+}
+
+// writeTestPdf writes a minimal PDF with one 200x100 point page per entry, each showing its text.
+func writeTestPdf(t *testing.T, path string, pdf testPdf) {
+	var buf bytes.Buffer
+	var offsets []int
+	addObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	pageObject := func(page int) int { return 4 + 2*(page-1) }
+	outlineRoot := pageObject(len(pdf.pages) + 1)
+
+	// Objects 1-3 are the catalog, page tree and font; each page then adds a page and a content stream.
+	kids := ""
+	for i := range pdf.pages {
+		kids += fmt.Sprintf("%d 0 R ", pageObject(i+1))
+	}
+	buf.WriteString("%PDF-1.4\n")
+	if len(pdf.outline) > 0 {
+		addObject(fmt.Sprintf("<< /Type /Catalog /Pages 2 0 R /Outlines %d 0 R >>", outlineRoot))
+	} else {
+		addObject("<< /Type /Catalog /Pages 2 0 R >>")
+	}
+	addObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pdf.pages)))
+	addObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	for i, text := range pdf.pages {
+		addObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 200 100] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pageObject(i+1)+1))
+		stream := fmt.Sprintf("BT /F1 8 Tf 10 50 Td (%s) Tj ET", text)
+		addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+
+	if len(pdf.outline) > 0 {
+		// Link every item to its parent and siblings; item i is object outlineRoot+1+i.
+		parents := make([]int, len(pdf.outline))
+		children := map[int][]int{}
+		var stack []int
+		for i, item := range pdf.outline {
+			stack = stack[:min(len(stack), item.level-1)]
+			parents[i] = outlineRoot
+			if len(stack) > 0 {
+				parents[i] = outlineRoot + 1 + stack[len(stack)-1]
+			}
+			children[parents[i]] = append(children[parents[i]], outlineRoot+1+i)
+			stack = append(stack, i)
+		}
+		links := func(object int) string {
+			kids := children[object]
+			if len(kids) == 0 {
+				return ""
+			}
+			return fmt.Sprintf(" /First %d 0 R /Last %d 0 R /Count %d", kids[0], kids[len(kids)-1], len(kids))
+		}
+
+		addObject("<< /Type /Outlines" + links(outlineRoot) + " >>")
+		for i, item := range pdf.outline {
+			object := outlineRoot + 1 + i
+			siblings := children[parents[i]]
+			entry := fmt.Sprintf("<< /Title (%s) /Parent %d 0 R /Dest [%d 0 R /Fit]", item.title, parents[i], pageObject(item.page))
+			for j, sibling := range siblings {
+				if sibling != object {
+					continue
+				}
+				if j > 0 {
+					entry += fmt.Sprintf(" /Prev %d 0 R", siblings[j-1])
+				}
+				if j < len(siblings)-1 {
+					entry += fmt.Sprintf(" /Next %d 0 R", siblings[j+1])
+				}
+			}
+			addObject(entry + links(object) + " >>")
+		}
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write test pdf: %v", err)
+	}
+}
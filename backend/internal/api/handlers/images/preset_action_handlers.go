@@ -0,0 +1,102 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/platform/storage/repos"
+	"dmd/backend/internal/platform/storage/repos/images_repo"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"gorm.io/gorm"
+)
+
+// PresetDuplicateHandler copies an existing preset with all of its slots.
+type PresetDuplicateHandler struct {
+	handlers.BaseHandler
+	repo repos.ImagesRepository
+	log  *slog.Logger
+}
+
+func NewPresetDuplicateHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PresetDuplicateHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		repo:        images_repo.NewImagesRepository(rs.DbConnection),
+		log:         rs.Log,
+	}
+}
+
+type duplicatePresetRequest struct {
+	Name string `json:"name"`
+}
+
+// POST /images/presets/{id}/duplicate - the body is optional.
+func (h *PresetDuplicateHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var req duplicatePresetRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	duplicate, err := h.repo.DuplicatePreset(id, req.Name)
+	if err != nil {
+		appErr := errors2.NewInternalError("Failed to duplicate preset", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr.StatusCode = http.StatusNotFound
+		}
+		utils.RespondWithError(w, appErr)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, duplicate)
+}
+
+// PresetOrderHandler sets the listing order of all presets at once.
+type PresetOrderHandler struct {
+	handlers.BaseHandler
+	repo repos.ImagesRepository
+	log  *slog.Logger
+}
+
+func NewPresetOrderHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &PresetOrderHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		repo:        images_repo.NewImagesRepository(rs.DbConnection),
+		log:         rs.Log,
+	}
+}
+
+type reorderPresetsRequest struct {
+	PresetIDs []uint `json:"preset_ids"`
+}
+
+// PUT /images/presets/order - preset_ids lists the presets in their new order.
+func (h *PresetOrderHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var req reorderPresetsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if err := h.repo.ReorderPresets(req.PresetIDs); err != nil {
+		appErr := errors2.NewInternalError("Failed to reorder presets", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr.StatusCode = http.StatusNotFound
+		}
+		utils.RespondWithError(w, appErr)
+		return
+	}
+	presets, err := h.repo.GetAllPresets()
+	if err != nil {
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to get presets", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, presets)
+}
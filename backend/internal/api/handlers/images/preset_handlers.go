@@ -9,10 +9,13 @@ import (
 	"dmd/backend/internal/platform/storage/repos"
 	"dmd/backend/internal/platform/storage/repos/images_repo"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
 type PresetHandler struct {
@@ -30,6 +33,10 @@ func NewPresetHandler(rs *common.RoutingServices, path string) common.IHandler {
 }
 
 func (h *PresetHandler) Get(w http.ResponseWriter, r *http.Request) {
+	if _, ok := mux.Vars(r)["id"]; ok {
+		h.getPresetByID(w, r)
+		return
+	}
 	presets, err := h.repo.GetAllPresets()
 	if err != nil {
 		utils.RespondWithError(w, errors2.NewInternalError("Failed to get presets", err))
@@ -72,8 +79,66 @@ func (h *PresetHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// PUT /images/presets/{id} - replaces the preset's metadata and slot set.
 func (h *PresetHandler) Put(w http.ResponseWriter, r *http.Request) {
-	// PUT method not supported for presets
-	w.WriteHeader(http.StatusMethodNotAllowed)
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var updatedPreset images.PresetLayout
+	if err = json.NewDecoder(r.Body).Decode(&updatedPreset); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if err = validatePresetSlots(&updatedPreset); err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	updatedPreset.ID = id
+	if err = h.repo.UpdatePreset(&updatedPreset); err != nil {
+		appErr := errors2.NewInternalError("Failed to update preset", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr.StatusCode = http.StatusNotFound
+		}
+		utils.RespondWithError(w, appErr)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, updatedPreset)
+}
+
+// Helper Methods
+func (h *PresetHandler) getPresetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	preset, err := h.repo.GetPresetByID(id)
+	if err != nil {
+		appErr := errors2.NewInternalError("Failed to get preset by id", err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr.StatusCode = http.StatusNotFound
+		}
+		utils.RespondWithError(w, appErr)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, preset)
+}
+
+// validatePresetSlots checks that every slot fits the preset's layout and no position is used twice.
+func validatePresetSlots(preset *images.PresetLayout) error {
+	slotCount := preset.LayoutType.SlotCount()
+	if slotCount == 0 {
+		return errors2.NewBadRequestError(fmt.Sprintf("Invalid layout type %q", preset.LayoutType))
+	}
+	seen := make(map[int]bool)
+	for _, slot := range preset.Slots {
+		if slot.SlotID < 0 || slot.SlotID >= slotCount || seen[slot.SlotID] {
+			return errors2.NewBadRequestError(fmt.Sprintf("Invalid slot_id %d for layout %q", slot.SlotID, preset.LayoutType))
+		}
+		seen[slot.SlotID] = true
+	}
+	return nil
 }
 
@@ -0,0 +1,68 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/images"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPresetUpdate(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &images.ImageEntry{}, &images.PresetLayout{}, &images.PresetLayoutSlot{})
+	handler := NewPresetHandler(rs, "/images/presets/{id}")
+
+	img := images.ImageEntry{Name: "Castle", Type: images.ImageTypeImage, FilePath: "images/castle.jpg"}
+	db.Create(&img)
+	preset := images.PresetLayout{LayoutType: images.Single, Slots: []images.PresetLayoutSlot{{ImageID: img.ID, SlotID: 0, Zoom: 1}}}
+	db.Create(&preset)
+	presetID := strconv.Itoa(int(preset.ID))
+
+	t.Run("PUT_Success", func(t *testing.T) {
+		presetJSON := `{"name":"Castle Gate","sort_order":3,"layout_type":"single","slots":[{"image_id":` + strconv.Itoa(int(img.ID)) + `,"slot_id":0,"zoom":1.75}]}`
+		req := httptest.NewRequest(http.MethodPut, "/images/presets/"+presetID, strings.NewReader(presetJSON))
+		req = mux.SetURLVars(req, map[string]string{"id": presetID})
+		rr := httptest.NewRecorder()
+		handler.Put(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", status, http.StatusOK, rr.Body.String())
+		}
+		var updated images.PresetLayout
+		json.NewDecoder(rr.Body).Decode(&updated)
+		if updated.Name != "Castle Gate" || updated.SortOrder != 3 {
+			t.Errorf("unexpected preset metadata: %+v", updated)
+		}
+		if len(updated.Slots) != 1 || updated.Slots[0].Zoom != 1.75 || updated.Slots[0].Image.Name != "Castle" {
+			t.Errorf("unexpected preset slots: %+v", updated.Slots)
+		}
+	})
+
+	t.Run("PUT_Slot_Out_Of_Range", func(t *testing.T) {
+		presetJSON := `{"layout_type":"single","slots":[{"image_id":1,"slot_id":1,"zoom":1}]}`
+		req := httptest.NewRequest(http.MethodPut, "/images/presets/"+presetID, strings.NewReader(presetJSON))
+		req = mux.SetURLVars(req, map[string]string{"id": presetID})
+		rr := httptest.NewRecorder()
+		handler.Put(rr, req)
+
+		if status := rr.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("PUT_Not_Found", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, "/images/presets/999", strings.NewReader(`{"layout_type":"single","slots":[]}`))
+		req = mux.SetURLVars(req, map[string]string{"id": "999"})
+		rr := httptest.NewRecorder()
+		handler.Put(rr, req)
+
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+package images
+
+import (
+	"dmd/backend/internal/api/common"
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	"dmd/backend/internal/model/images"
+	mapsSvc "dmd/backend/internal/services/maps"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+)
+
+// MapTokensHandler lists and places the tokens on a map.
+type MapTokensHandler struct {
+	handlers.BaseHandler
+	mapsService *mapsSvc.Service
+	log         *slog.Logger
+}
+
+func NewMapTokensHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &MapTokensHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		mapsService: rs.MapsService,
+		log:         rs.Log,
+	}
+}
+
+// GET /images/images/{id}/tokens
+func (h *MapTokensHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	tokens, err := h.mapsService.GetTokens(id)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to get tokens", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, tokens)
+}
+
+// POST /images/images/{id}/tokens - places a token on the map.
+func (h *MapTokensHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var token images.MapToken
+	if err = json.NewDecoder(r.Body).Decode(&token); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	token.ImageID = id
+	if err = h.mapsService.PlaceToken(&token); err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to place token", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusCreated, token)
+}
+
+// TokenHandler reads and removes a single token.
+type TokenHandler struct {
+	handlers.BaseHandler
+	mapsService *mapsSvc.Service
+	log         *slog.Logger
+}
+
+func NewTokenHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &TokenHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		mapsService: rs.MapsService,
+		log:         rs.Log,
+	}
+}
+
+func (h *TokenHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	token, err := h.mapsService.GetToken(id)
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to get token", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, token)
+}
+
+func (h *TokenHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	if err = h.mapsService.RemoveToken(id); err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to remove token", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TokenMoveHandler moves a token to a new cell.
+type TokenMoveHandler struct {
+	handlers.BaseHandler
+	mapsService *mapsSvc.Service
+	log         *slog.Logger
+}
+
+func NewTokenMoveHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &TokenMoveHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		mapsService: rs.MapsService,
+		log:         rs.Log,
+	}
+}
+
+// moveRequest targets either a cell or, when At is set, the cell under a pixel position.
+type moveRequest struct {
+	Col int           `json:"col"`
+	Row int           `json:"row"`
+	At  *images.Point `json:"at"`
+}
+
+// POST /images/tokens/{id}/move
+func (h *TokenMoveHandler) Post(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDFromRequest(r)
+	if err != nil {
+		utils.RespondWithError(w, err)
+		return
+	}
+	var req moveRequest
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+
+	var move *mapsSvc.TokenMove
+	if req.At != nil {
+		move, err = h.mapsService.MoveTokenToPoint(id, *req.At)
+	} else {
+		move, err = h.mapsService.MoveToken(id, images.GridCell{Col: req.Col, Row: req.Row})
+	}
+	if err != nil {
+		utils.RespondWithError(w, newMapsError("Failed to move token", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, move)
+}
@@ -0,0 +1,177 @@
+package images
+
+import (
+	"dmd/backend/internal/model/combat"
+	"dmd/backend/internal/model/crawl"
+	"dmd/backend/internal/model/images"
+	mapsSvc "dmd/backend/internal/services/maps"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTokenHandlers(t *testing.T) {
+	rs, db, _ := setupMapsTest(t)
+
+	dungeon := images.ImageEntry{
+		Name: "Dungeon", Type: images.ImageTypeMap, FilePath: "images/dungeon.png",
+		Grid: images.MapGrid{Type: images.GridTypeSquare, CellSize: 50, FeetPerCell: 5, Columns: 10, Rows: 6},
+	}
+	cave := images.ImageEntry{Name: "Cave", Type: images.ImageTypeMap, FilePath: "images/cave.png"}
+	db.Create(&dungeon)
+	db.Create(&cave)
+
+	ogre := crawl.CharacterTemplate{Name: "Ogre", Size: "Large", PhotoPath: "photos/ogre.png", Color: "#556b2f"}
+	giant := crawl.CharacterTemplate{Name: "Hill Giant", Size: "Huge"}
+	db.Create(&ogre)
+	db.Create(&giant)
+
+	mapTokens := NewMapTokensHandler(rs, "/images/images/{id}/tokens")
+	move := NewTokenMoveHandler(rs, "/images/tokens/{id}/move")
+
+	newRequest := func(method string, id uint, body string) *http.Request {
+		idStr := strconv.Itoa(int(id))
+		req := httptest.NewRequest(method, "/images/"+idStr, strings.NewReader(body))
+		return mux.SetURLVars(req, map[string]string{"id": idStr})
+	}
+
+	var token images.MapToken
+
+	t.Run("Place_Token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		mapTokens.Post(rr, newRequest(http.MethodPost, dungeon.ID, fmt.Sprintf(`{"character_template_id":%d,"col":1,"row":1}`, ogre.ID)))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+		json.NewDecoder(rr.Body).Decode(&token)
+		if token.Cells != 2 {
+			t.Errorf("expected a Large token to cover 2 cells, got %d", token.Cells)
+		}
+		if token.CharacterTemplate == nil || token.CharacterTemplate.PhotoPath != ogre.PhotoPath || token.CharacterTemplate.Color != ogre.Color {
+			t.Errorf("expected the template's photo and color, got %+v", token.CharacterTemplate)
+		}
+	})
+
+	t.Run("Place_Validation", func(t *testing.T) {
+		testCases := []struct {
+			name    string
+			imageID uint
+			body    string
+			want    int
+		}{
+			{"No_Source", dungeon.ID, `{"col":0,"row":0}`, http.StatusBadRequest},
+			{"Missing_Template", dungeon.ID, `{"character_template_id":9999,"col":0,"row":0}`, http.StatusBadRequest},
+			{"Outside_Grid", dungeon.ID, fmt.Sprintf(`{"character_template_id":%d,"col":-1,"row":0}`, ogre.ID), http.StatusBadRequest},
+			{"Past_Grid_Edge", dungeon.ID, fmt.Sprintf(`{"character_template_id":%d,"col":10,"row":0}`, ogre.ID), http.StatusBadRequest},
+			// A Huge token covers 3x3 cells, so on the 10x6 grid it fits with its corner at (7, 3) at most.
+			{"Huge_Hangs_Off_Right", dungeon.ID, fmt.Sprintf(`{"character_template_id":%d,"col":8,"row":0}`, giant.ID), http.StatusBadRequest},
+			{"Huge_Hangs_Off_Bottom", dungeon.ID, fmt.Sprintf(`{"character_template_id":%d,"col":0,"row":4}`, giant.ID), http.StatusBadRequest},
+			{"Huge_At_Corner", dungeon.ID, fmt.Sprintf(`{"character_template_id":%d,"col":7,"row":3}`, giant.ID), http.StatusCreated},
+			{"Uncalibrated_Map", cave.ID, fmt.Sprintf(`{"character_template_id":%d,"col":0,"row":0}`, ogre.ID), http.StatusConflict},
+		}
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				mapTokens.Post(rr, newRequest(http.MethodPost, tc.imageID, tc.body))
+				if rr.Code != tc.want {
+					t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, tc.want)
+				}
+			})
+		}
+	})
+
+	t.Run("Move_Outside_Combat", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		move.Post(rr, newRequest(http.MethodPost, token.ID, `{"col":4,"row":2}`))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var m mapsSvc.TokenMove
+		json.NewDecoder(rr.Body).Decode(&m)
+		if m.From != (images.GridCell{Col: 1, Row: 1}) || m.Feet != 15 {
+			t.Errorf("unexpected move: %+v", m)
+		}
+	})
+
+	t.Run("Move_Outside_Grid", func(t *testing.T) {
+		// The Large ogre covers two cells, so column 9 would put half of it off the map.
+		for _, body := range []string{`{"col":3,"row":6}`, `{"col":9,"row":0}`, `{"at":{"x":520,"y":20}}`} {
+			rr := httptest.NewRecorder()
+			move.Post(rr, newRequest(http.MethodPost, token.ID, body))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("handler returned wrong status code for %s: got %v want %v", body, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+
+	t.Run("Move_During_Combat_Is_Recorded", func(t *testing.T) {
+		fight := combat.Combat{IsActive: true, Round: 3}
+		db.Create(&fight)
+		ended := combat.Combat{Round: 5}
+		db.Create(&ended)
+		fighter := combat.Combatant{CombatID: fight.ID, CombatantID: ogre.ID, CombatantType: "monster", Name: "Ogre"}
+		bystander := combat.Combatant{CombatID: ended.ID, CombatantID: ogre.ID, CombatantType: "monster", Name: "Ogre"}
+		db.Create(&fighter)
+		db.Create(&bystander)
+
+		place := func(body string) images.MapToken {
+			rr := httptest.NewRecorder()
+			mapTokens.Post(rr, newRequest(http.MethodPost, dungeon.ID, body))
+			if rr.Code != http.StatusCreated {
+				t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+			}
+			var placed images.MapToken
+			json.NewDecoder(rr.Body).Decode(&placed)
+			return placed
+		}
+		inFight := place(fmt.Sprintf(`{"combatant_id":%d,"col":0,"row":0}`, fighter.ID))
+		notInFight := place(fmt.Sprintf(`{"combatant_id":%d,"col":0,"row":1}`, bystander.ID))
+
+		// Snap to the cell under a pixel position. Only the token in the running combat is recorded.
+		for _, id := range []uint{token.ID, notInFight.ID, inFight.ID} {
+			rr := httptest.NewRecorder()
+			move.Post(rr, newRequest(http.MethodPost, id, `{"at":{"x":260,"y":110}}`))
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+			}
+		}
+
+		var history []combat.HistoryEntry
+		db.Where("event = ?", combat.HistoryTokenMoved).Find(&history)
+		if len(history) != 1 {
+			t.Fatalf("expected 1 history entry, got %d", len(history))
+		}
+		if history[0].CombatID != fight.ID || history[0].Round != 3 {
+			t.Errorf("unexpected history entry: %+v", history[0])
+		}
+		var recorded mapsSvc.TokenMove
+		json.Unmarshal(history[0].Details, &recorded)
+		if recorded.TokenID != inFight.ID || recorded.To != (images.GridCell{Col: 5, Row: 2}) {
+			t.Errorf("expected token %d to move to (5, 2), got %+v", inFight.ID, recorded)
+		}
+	})
+
+	t.Run("Remove_Token", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		NewTokenHandler(rs, "/images/tokens/{id}").Delete(rr, newRequest(http.MethodDelete, token.ID, ""))
+		if rr.Code != http.StatusNoContent {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+		}
+
+		rr = httptest.NewRecorder()
+		mapTokens.Get(rr, newRequest(http.MethodGet, dungeon.ID, ""))
+		var tokens []images.MapToken
+		json.NewDecoder(rr.Body).Decode(&tokens)
+		for _, remaining := range tokens {
+			if remaining.ID == token.ID {
+				t.Errorf("expected token %d to be removed", token.ID)
+			}
+		}
+	})
+}
@@ -1,9 +1,10 @@
 package spotify
 
 import (
-	"dmd/backend/internal/api/common/errors"
+	errors2 "dmd/backend/internal/api/common/errors"
 	"dmd/backend/internal/api/common/utils"
 	spotifyService "dmd/backend/internal/services/spotify"
+	"errors"
 	"log/slog"
 	"net/http"
 )
@@ -11,57 +12,54 @@ import (
 type AuthHandler struct {
 	spotify *spotifyService.Service
 	log     *slog.Logger
-	states  map[string]bool // Simple in-memory state store
 }
 
 func NewAuthHandler(spotify *spotifyService.Service, log *slog.Logger) *AuthHandler {
 	return &AuthHandler{
 		spotify: spotify,
 		log:     log,
-		states:  make(map[string]bool),
 	}
 }
 
 // Login initiates the OAuth flow
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	url, state, err := h.spotify.GenerateAuthURL()
+	// The service remembers the state until the callback uses it or it expires
+	url, _, err := h.spotify.GenerateAuthURL()
 	if err != nil {
-		utils.RespondWithError(w, errors.NewInternalError("Failed to generate auth URL", err))
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to generate auth URL", err))
 		return
 	}
 
-	// Store state for validation
-	h.states[state] = true
-
 	// Redirect to Spotify auth page
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
 // Callback handles the OAuth callback from Spotify
 func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
-	// Verify state
 	state := r.URL.Query().Get("state")
-	if !h.states[state] {
-		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
-		return
-	}
-	delete(h.states, state) // Clean up used state
 
 	// Check for error from Spotify
 	if errMsg := r.URL.Query().Get("error"); errMsg != "" {
+		h.spotify.CancelLogin(state)
 		h.log.Error("Spotify auth error", "error", errMsg)
 		http.Error(w, "Authorization failed: "+errMsg, http.StatusBadRequest)
 		return
 	}
 
-	// Exchange code for token
 	code := r.URL.Query().Get("code")
 	if code == "" {
+		h.spotify.CancelLogin(state)
 		http.Error(w, "No code provided", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.spotify.ExchangeToken(r.Context(), code); err != nil {
+	// Verify state and exchange code for token
+	err := h.spotify.ExchangeToken(r.Context(), state, code)
+	if errors.Is(err, spotifyService.ErrInvalidState) {
+		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
 		h.log.Error("Failed to exchange token", "error", err)
 		http.Error(w, "Failed to complete authorization", http.StatusInternalServerError)
 		return
@@ -94,7 +92,7 @@ func (h *AuthHandler) Status(w http.ResponseWriter, r *http.Request) {
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	if err := h.spotify.DeleteToken(); err != nil {
 		h.log.Error("Failed to delete token", "error", err)
-		utils.RespondWithError(w, errors.NewInternalError("Failed to logout", err))
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to logout", err))
 		return
 	}
 	utils.RespondWithJSON(w, http.StatusOK, map[string]string{
@@ -107,10 +105,10 @@ func (h *AuthHandler) Token(w http.ResponseWriter, r *http.Request) {
 	token, err := h.spotify.GetValidToken(r.Context())
 	if err != nil {
 		if err.Error() == "record not found" {
-			utils.RespondWithError(w, errors.NewNotFoundError("No authentication token found", err))
+			utils.RespondWithError(w, errors2.NewNotFoundError("No authentication token found", err))
 			return
 		}
-		utils.RespondWithError(w, errors.NewInternalError("Failed to get token", err))
+		utils.RespondWithError(w, errors2.NewInternalError("Failed to get token", err))
 		return
 	}
 
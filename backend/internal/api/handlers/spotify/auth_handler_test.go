@@ -0,0 +1,365 @@
+package spotify
+
+import (
+	"dmd/backend/internal/api/common"
+	"dmd/backend/internal/api/common/utils"
+	wsHandlers "dmd/backend/internal/api/handlers/websocket"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/platform/storage/repos/playlist_repo"
+	"dmd/backend/internal/platform/storage/repos/track_repo"
+	spotifyService "dmd/backend/internal/services/spotify"
+	wsService "dmd/backend/internal/services/websocket"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+var testTokenKey = spotifyService.TokenKey{1, 2, 3}
+
+// fakeAccounts plays the Spotify accounts service: it hands out tokens for "good-code" when the PKCE
+// verifier matches the challenge from the login, and refuses to refresh "revoked-refresh". Like Spotify, it
+// rotates the refresh token, so each one can only be used once.
+type fakeAccounts struct {
+	mu        sync.Mutex
+	challenge string
+	used      map[string]bool
+	refreshes int
+}
+
+func (f *fakeAccounts) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/token" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	r.ParseForm()
+	w.Header().Set("Content-Type", "application/json")
+	switch r.Form.Get("grant_type") {
+	case "authorization_code":
+		f.mu.Lock()
+		challenge := f.challenge
+		f.mu.Unlock()
+		if r.Form.Get("code") != "good-code" || oauth2.S256ChallengeFromVerifier(r.Form.Get("code_verifier")) != challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token": "fresh-access", "refresh_token": "fresh-refresh", "token_type": "Bearer", "expires_in": 3600}`))
+	case "refresh_token":
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		refreshToken := r.Form.Get("refresh_token")
+		if refreshToken == "revoked-refresh" || f.used[refreshToken] {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant", "error_description": "Refresh token revoked"}`))
+			return
+		}
+		if f.used == nil {
+			f.used = make(map[string]bool)
+		}
+		f.used[refreshToken] = true
+		f.refreshes++
+		fmt.Fprintf(w, `{"access_token": "refreshed-access", "refresh_token": "rotated-%d", "token_type": "Bearer", "expires_in": 3600}`, f.refreshes)
+	}
+}
+
+func TestAuthHandler(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.SpotifyToken{})
+	accounts := &fakeAccounts{}
+	server := httptest.NewServer(accounts)
+	t.Cleanup(server.Close)
+	handler := NewAuthHandler(newTestService(t, rs, db, server), rs.Log)
+	events := connectEvents(t, rs)
+
+	login := func() url.Values {
+		rr := httptest.NewRecorder()
+		handler.Login(rr, httptest.NewRequest(http.MethodGet, "/auth/spotify/login", nil))
+		if rr.Code != http.StatusTemporaryRedirect {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusTemporaryRedirect)
+		}
+		location, _ := url.Parse(rr.Header().Get("Location"))
+		return location.Query()
+	}
+	callback := func(query string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.Callback(rr, httptest.NewRequest(http.MethodGet, "/auth/spotify/callback?"+query, nil))
+		return rr
+	}
+	storedToken := func() audio.SpotifyToken {
+		var token audio.SpotifyToken
+		db.First(&token, 1)
+		return token
+	}
+	token := func() *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		handler.Token(rr, httptest.NewRequest(http.MethodGet, "/auth/spotify/token", nil))
+		return rr
+	}
+
+	t.Run("Login_Uses_PKCE", func(t *testing.T) {
+		query := login()
+		if query.Get("state") == "" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+			t.Errorf("expected a state and an S256 code challenge, got %v", query)
+		}
+	})
+
+	t.Run("Callback_Stores_Encrypted_Token", func(t *testing.T) {
+		query := login()
+		accounts.mu.Lock()
+		accounts.challenge = query.Get("code_challenge")
+		accounts.mu.Unlock()
+
+		if rr := callback("state=" + url.QueryEscape(query.Get("state")) + "&code=good-code"); rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v, body: %s", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if status := events.next(t); !status.Authenticated || status.Reason != spotifyService.AuthReasonConnected {
+			t.Errorf("expected a connected status event, got %+v", status)
+		}
+
+		stored := storedToken()
+		if !strings.HasPrefix(stored.AccessToken, "enc:") || strings.Contains(stored.AccessToken+stored.RefreshToken, "fresh") {
+			t.Errorf("expected the stored tokens to be encrypted, got %q and %q", stored.AccessToken, stored.RefreshToken)
+		}
+		if rr := token(); !strings.Contains(rr.Body.String(), `"access_token":"fresh-access"`) {
+			t.Errorf("expected the decrypted access token, got %v: %s", rr.Code, rr.Body.String())
+		}
+
+		// A state works once.
+		if rr := callback("state=" + url.QueryEscape(query.Get("state")) + "&code=good-code"); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Callback_Rejects_Bad_Requests", func(t *testing.T) {
+		state := url.QueryEscape(login().Get("state"))
+		cases := map[string]string{
+			"unknown state":   "state=forged&code=good-code",
+			"missing code":    "state=" + state,
+			"declined access": "state=" + state + "&error=access_denied",
+		}
+		for name, query := range cases {
+			if rr := callback(query); rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", name, rr.Code, http.StatusBadRequest)
+			}
+		}
+		// The missing code cancelled the login, so the state is gone even with a code.
+		if rr := callback("state=" + state + "&code=good-code"); rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("Concurrent_Logins", func(t *testing.T) {
+		states := make(chan string, 20)
+		var wg sync.WaitGroup
+		for i := 0; i < cap(states); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rr := httptest.NewRecorder()
+				handler.Login(rr, httptest.NewRequest(http.MethodGet, "/auth/spotify/login", nil))
+				location, _ := url.Parse(rr.Header().Get("Location"))
+				states <- location.Query().Get("state")
+			}()
+		}
+		wg.Wait()
+		close(states)
+
+		seen := map[string]bool{}
+		for state := range states {
+			seen[state] = true
+		}
+		if len(seen) != cap(states) {
+			t.Errorf("expected %d distinct states, got %d", cap(states), len(seen))
+		}
+	})
+
+	t.Run("Plaintext_Token_Is_Encrypted_On_Use", func(t *testing.T) {
+		db.Save(&audio.SpotifyToken{Model: gorm.Model{ID: 1}, AccessToken: "old-access", RefreshToken: "old-refresh",
+			TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)})
+
+		if rr := token(); !strings.Contains(rr.Body.String(), `"access_token":"old-access"`) {
+			t.Errorf("expected the plaintext token to still be readable, got %v: %s", rr.Code, rr.Body.String())
+		}
+		if stored := storedToken(); !strings.HasPrefix(stored.AccessToken, "enc:") || !strings.HasPrefix(stored.RefreshToken, "enc:") {
+			t.Errorf("expected the token to be encrypted after use, got %q and %q", stored.AccessToken, stored.RefreshToken)
+		}
+	})
+
+	t.Run("Concurrent_Refreshes_Use_The_Refresh_Token_Once", func(t *testing.T) {
+		db.Save(&audio.SpotifyToken{Model: gorm.Model{ID: 1}, AccessToken: "old-access", RefreshToken: "once-refresh",
+			TokenType: "Bearer", Expiry: time.Now().Add(-time.Hour)})
+
+		var wg sync.WaitGroup
+		codes := make(chan int, 10)
+		for range cap(codes) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				codes <- token().Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+		for code := range codes {
+			if code != http.StatusOK {
+				t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+			}
+		}
+		accounts.mu.Lock()
+		refreshes := accounts.refreshes
+		accounts.mu.Unlock()
+		if refreshes != 1 {
+			t.Errorf("expected a single refresh, got %d", refreshes)
+		}
+		if rr := token(); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"access_token":"refreshed-access"`) {
+			t.Errorf("expected the refreshed token to be kept, got %v: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Revoked_Refresh_Token_Disconnects", func(t *testing.T) {
+		db.Save(&audio.SpotifyToken{Model: gorm.Model{ID: 1}, AccessToken: "old-access", RefreshToken: "revoked-refresh",
+			TokenType: "Bearer", Expiry: time.Now().Add(-time.Hour)})
+
+		if rr := token(); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+		if status := events.next(t); status.Authenticated || status.Reason != spotifyService.AuthReasonRevoked {
+			t.Errorf("expected a revoked status event, got %+v", status)
+		}
+		var count int64
+		db.Model(&audio.SpotifyToken{}).Count(&count)
+		if count != 0 {
+			t.Error("expected the revoked token to be removed")
+		}
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.Logout(rr, httptest.NewRequest(http.MethodPost, "/auth/spotify/logout", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		if status := events.next(t); status.Authenticated || status.Reason != spotifyService.AuthReasonDisconnected {
+			t.Errorf("expected a disconnected status event, got %+v", status)
+		}
+	})
+}
+
+func TestLoginTimeout(t *testing.T) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.SpotifyToken{})
+	server := httptest.NewServer(&fakeAccounts{})
+	t.Cleanup(server.Close)
+	handler := NewAuthHandler(newTestService(t, rs, db, server, spotifyService.WithLoginTimeout(time.Millisecond)), rs.Log)
+
+	rr := httptest.NewRecorder()
+	handler.Login(rr, httptest.NewRequest(http.MethodGet, "/auth/spotify/login", nil))
+	location, _ := url.Parse(rr.Header().Get("Location"))
+	time.Sleep(5 * time.Millisecond)
+
+	rr = httptest.NewRecorder()
+	handler.Callback(rr, httptest.NewRequest(http.MethodGet, "/auth/spotify/callback?code=good-code&state="+url.QueryEscape(location.Query().Get("state")), nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestLoadOrCreateTokenKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "spotify.key")
+
+	created, err := spotifyService.LoadOrCreateTokenKey(path)
+	if err != nil {
+		t.Fatalf("LoadOrCreateTokenKey failed unexpectedly: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("expected a key file only the owner can read, got %v, err %v", info, err)
+	}
+	loaded, err := spotifyService.LoadOrCreateTokenKey(path)
+	if err != nil || loaded != created {
+		t.Errorf("expected the same key to be loaded again, err %v", err)
+	}
+
+	os.WriteFile(path, []byte("too short"), 0o600)
+	if _, err := spotifyService.LoadOrCreateTokenKey(path); err == nil {
+		t.Error("expected an invalid key file to be rejected")
+	}
+}
+
+// statusEvents reads auth status events from a WebSocket connection.
+type statusEvents struct {
+	conn *websocket.Conn
+}
+
+func (e *statusEvents) next(t *testing.T) spotifyService.AuthStatus {
+	t.Helper()
+	e.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event struct {
+		Type    string                    `json:"type"`
+		Payload spotifyService.AuthStatus `json:"payload"`
+	}
+	if err := e.conn.ReadJSON(&event); err != nil {
+		t.Fatalf("failed to read event: %v", err)
+	}
+	if event.Type != spotifyService.EventAuthStatus {
+		t.Fatalf("expected %s, got %s", spotifyService.EventAuthStatus, event.Type)
+	}
+	return event.Payload
+}
+
+// connectEvents connects a WebSocket client and waits for a chat echo, so it is registered before anything is broadcast.
+func connectEvents(t *testing.T, rs *common.RoutingServices) *statusEvents {
+	router := mux.NewRouter()
+	wsHandlers.RegisterWebsocketRoutes(router, rs.Log, rs.WsManager)
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.WriteJSON(map[string]any{"type": "send_message", "payload": map[string]any{"username": "test", "content": "ready"}})
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var echo struct {
+		Type string `json:"type"`
+	}
+	if err := conn.ReadJSON(&echo); err != nil || echo.Type != "new_chat_message" {
+		t.Fatalf("expected the chat echo, got %q, err %v", echo.Type, err)
+	}
+	return &statusEvents{conn: conn}
+}
+
+// rewriteTransport sends every request, whichever Spotify host it is for, to the fake server.
+type rewriteTransport struct {
+	host string
+}
+
+func (rt rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = "http"
+	r.URL.Host = rt.host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newTestService builds a Spotify service that talks only to server, with a running WebSocket manager.
+func newTestService(t *testing.T, rs *common.RoutingServices, db *gorm.DB, server *httptest.Server, opts ...spotifyService.Option) *spotifyService.Service {
+	rs.WsManager = wsService.NewManager(rs.Log)
+	go rs.WsManager.Run()
+
+	opts = append([]spotifyService.Option{
+		spotifyService.WithHTTPClient(&http.Client{Transport: rewriteTransport{host: strings.TrimPrefix(server.URL, "http://")}}),
+		spotifyService.WithAPIBaseURL(fmt.Sprintf("%s/v1/", server.URL)),
+	}, opts...)
+	return spotifyService.NewService(rs.Log, db, track_repo.NewTrackRepository(db), playlist_repo.NewPlaylistRepository(db),
+		rs.WsManager, testTokenKey, "client-id", "client-secret", "http://127.0.0.1/callback", opts...)
+}
@@ -0,0 +1,83 @@
+package spotify
+
+import (
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	spotifyService "dmd/backend/internal/services/spotify"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/zmb3/spotify/v2"
+)
+
+// PlayerHandler proxies playback control to the user's Spotify player.
+type PlayerHandler struct {
+	spotify *spotifyService.Service
+	log     *slog.Logger
+}
+
+func NewPlayerHandler(spotify *spotifyService.Service, log *slog.Logger) *PlayerHandler {
+	return &PlayerHandler{
+		spotify: spotify,
+		log:     log,
+	}
+}
+
+// State returns what Spotify is playing and on which device
+func (h *PlayerHandler) State(w http.ResponseWriter, r *http.Request) {
+	state, err := h.spotify.GetPlayerState(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, newSpotifyError("Failed to get Spotify player state", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, state)
+}
+
+// Devices lists the devices Spotify can play on
+func (h *PlayerHandler) Devices(w http.ResponseWriter, r *http.Request) {
+	devices, err := h.spotify.GetDevices(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, newSpotifyError("Failed to get Spotify devices", err))
+		return
+	}
+	if devices == nil {
+		devices = []spotify.PlayerDevice{}
+	}
+	utils.RespondWithJSON(w, http.StatusOK, devices)
+}
+
+// Command sends a play, pause, next, previous, seek, volume, shuffle or transfer command to Spotify
+func (h *PlayerHandler) Command(w http.ResponseWriter, r *http.Request) {
+	var cmd spotifyService.PlayerCommand
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	if err := h.spotify.ExecutePlayerCommand(r.Context(), cmd); err != nil {
+		utils.RespondWithError(w, newSpotifyError("Failed to send Spotify player command", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newSpotifyError passes Spotify's own client errors through (e.g. 404 when no device is active)
+// and reports anything else Spotify rejects as a bad gateway.
+func newSpotifyError(message string, err error) errors2.AppError {
+	var apiErr spotify.Error
+	switch {
+	case errors.Is(err, spotifyService.ErrInvalidPlayerCommand):
+		return errors2.NewBadRequestError(message, err)
+	case errors.Is(err, spotifyService.ErrUnknownPlaylist):
+		return errors2.NewNotFoundError(message, err)
+	case errors.Is(err, spotifyService.ErrNotAuthenticated):
+		return errors2.NewAppError(http.StatusUnauthorized, message, err)
+	case errors.As(err, &apiErr) && apiErr.Status >= 400 && apiErr.Status < 500:
+		return errors2.NewAppError(apiErr.Status, message, err)
+	case errors.As(err, &apiErr):
+		return errors2.NewAppError(http.StatusBadGateway, message, err)
+	default:
+		return errors2.NewInternalError(message, err)
+	}
+}
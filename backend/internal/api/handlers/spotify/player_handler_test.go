@@ -0,0 +1,166 @@
+package spotify
+
+import (
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/audio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// fakeSpotify records the player requests it receives and answers like the Web API.
+type fakeSpotify struct {
+	mu       sync.Mutex
+	requests []string // "METHOD /path?query body"
+}
+
+func (f *fakeSpotify) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	f.mu.Lock()
+	f.requests = append(f.requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+	f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer test-access-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch {
+	case r.URL.Path == "/v1/me/player/devices":
+		w.Write([]byte(`{"devices": [{"id": "speaker", "is_active": true, "name": "Table Speaker", "type": "Speaker", "volume_percent": 60}]}`))
+	case r.URL.Query().Get("device_id") == "missing":
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"status": 404, "message": "Device not found"}}`))
+	case r.URL.Path == "/v1/me/player" && r.Method == http.MethodGet:
+		w.Write([]byte(`{"device": {"id": "speaker", "name": "Table Speaker"}, "shuffle_state": true, "is_playing": true}`))
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (f *fakeSpotify) last() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.requests) == 0 {
+		return ""
+	}
+	return f.requests[len(f.requests)-1]
+}
+
+func TestPlayerHandler(t *testing.T) {
+	handler, fake, db := setupPlayerTest(t)
+
+	command := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/spotify/player", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.Command(rr, req)
+		return rr
+	}
+
+	t.Run("Devices", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.Devices(rr, httptest.NewRequest(http.MethodGet, "/spotify/player/devices", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var devices []struct {
+			ID     string `json:"id"`
+			Name   string `json:"name"`
+			Volume int    `json:"volume_percent"`
+		}
+		json.NewDecoder(rr.Body).Decode(&devices)
+		if len(devices) != 1 || devices[0].ID != "speaker" || devices[0].Volume != 60 {
+			t.Errorf("expected the table speaker, got %+v", devices)
+		}
+	})
+
+	t.Run("State", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.State(rr, httptest.NewRequest(http.MethodGet, "/spotify/player", nil))
+		if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"shuffle_state":true`) {
+			t.Errorf("expected the player state, got %v: %s", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("Commands_Reach_Spotify", func(t *testing.T) {
+		cases := []struct {
+			body string
+			want string
+		}{
+			{`{"action": "play", "context_uri": "spotify:playlist:tavern", "offset": 2}`,
+				`PUT /v1/me/player/play {"context_uri":"spotify:playlist:tavern","offset":{"position":2}}`},
+			{`{"action": "play", "uris": ["spotify:track:1"], "device_id": "speaker"}`,
+				`PUT /v1/me/player/play?device_id=speaker {"uris":["spotify:track:1"]}`},
+			{`{"action": "pause"}`, `PUT /v1/me/player/pause`},
+			{`{"action": "next"}`, `POST /v1/me/player/next`},
+			{`{"action": "previous"}`, `POST /v1/me/player/previous`},
+			{`{"action": "seek", "position_ms": 30000}`, `PUT /v1/me/player/seek?position_ms=30000`},
+			{`{"action": "volume", "volume_percent": 40}`, `PUT /v1/me/player/volume?volume_percent=40`},
+			{`{"action": "shuffle", "shuffle": true}`, `PUT /v1/me/player/shuffle?state=true`},
+			{`{"action": "transfer", "device_id": "speaker", "play": true}`, `PUT /v1/me/player {"device_ids":["speaker"],"play":true}`},
+		}
+		for _, c := range cases {
+			if rr := command(c.body); rr.Code != http.StatusNoContent {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v, body: %s", c.body, rr.Code, http.StatusNoContent, rr.Body.String())
+				continue
+			}
+			if got := fake.last(); got != c.want {
+				t.Errorf("%s: expected Spotify to receive %q, got %q", c.body, c.want, got)
+			}
+		}
+	})
+
+	t.Run("Invalid_Commands", func(t *testing.T) {
+		before := fake.last()
+		for _, body := range []string{
+			`{"action": "rewind"}`,
+			`{"action": "seek"}`,
+			`{"action": "volume", "volume_percent": 150}`,
+			`{"action": "shuffle"}`,
+			`{"action": "transfer"}`,
+			`{"action": "play", "context_uri": "spotify:playlist:tavern", "uris": ["spotify:track:1"]}`,
+		} {
+			if rr := command(body); rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", body, rr.Code, http.StatusBadRequest)
+			}
+		}
+		if fake.last() != before {
+			t.Error("expected invalid commands not to reach Spotify")
+		}
+	})
+
+	t.Run("Spotify_Errors_Pass_Through", func(t *testing.T) {
+		if rr := command(`{"action": "pause", "device_id": "missing"}`); rr.Code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("Not_Authenticated", func(t *testing.T) {
+		db.Unscoped().Delete(&audio.SpotifyToken{}, 1)
+		if rr := command(`{"action": "pause"}`); rr.Code != http.StatusUnauthorized {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func setupPlayerTest(t *testing.T) (*PlayerHandler, *fakeSpotify, *gorm.DB) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.SpotifyToken{})
+	fake := &fakeSpotify{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	db.Create(&audio.SpotifyToken{
+		Model:        gorm.Model{ID: 1},
+		AccessToken:  "test-access-token",
+		RefreshToken: "test-refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	return NewPlayerHandler(newTestService(t, rs, db, server), rs.Log), fake, db
+}
@@ -0,0 +1,53 @@
+package spotify
+
+import (
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
+	spotifyService "dmd/backend/internal/services/spotify"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// PlaylistHandler lists the user's Spotify playlists and imports them as local playlists.
+type PlaylistHandler struct {
+	spotify *spotifyService.Service
+	log     *slog.Logger
+}
+
+func NewPlaylistHandler(spotify *spotifyService.Service, log *slog.Logger) *PlaylistHandler {
+	return &PlaylistHandler{
+		spotify: spotify,
+		log:     log,
+	}
+}
+
+type importRequest struct {
+	PlaylistIDs []string `json:"playlist_ids"` // Spotify playlist IDs; the whole library when empty
+}
+
+// List returns the playlists in the user's Spotify library
+func (h *PlaylistHandler) List(w http.ResponseWriter, r *http.Request) {
+	playlists, err := h.spotify.GetPlaylists(r.Context())
+	if err != nil {
+		utils.RespondWithError(w, newSpotifyError("Failed to get Spotify playlists", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, playlists)
+}
+
+// Import creates or syncs local playlists from Spotify playlists
+func (h *PlaylistHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var req importRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		utils.RespondWithError(w, errors2.NewBadRequestError("Invalid request body", err))
+		return
+	}
+	imported, err := h.spotify.ImportPlaylists(r.Context(), req.PlaylistIDs)
+	if err != nil {
+		utils.RespondWithError(w, newSpotifyError("Failed to import Spotify playlists", err))
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, imported)
+}
@@ -0,0 +1,210 @@
+package spotify
+
+import (
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/audio"
+	spotifyService "dmd/backend/internal/services/spotify"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type fakeTrack struct {
+	id, name, artist string
+}
+
+type fakePlaylist struct {
+	id, name string
+	items    []string // Track IDs; "local" and "episode" stand for items that are not importable
+}
+
+// fakeLibrary serves a Spotify library one item per page, so every import has to follow the paging.
+type fakeLibrary struct {
+	mu        sync.Mutex
+	tracks    map[string]fakeTrack
+	playlists []fakePlaylist
+}
+
+func (f *fakeLibrary) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	offset := 0
+	fmt.Sscan(r.URL.Query().Get("offset"), &offset)
+	next := func(total int) string {
+		if offset+1 >= total {
+			return "null"
+		}
+		return fmt.Sprintf(`"http://%s%s?offset=%d"`, r.Host, r.URL.Path, offset+1)
+	}
+
+	if r.URL.Path == "/v1/me/playlists" {
+		var items []string
+		for _, p := range f.playlists[min(offset, len(f.playlists)):min(offset+1, len(f.playlists))] {
+			items = append(items, fmt.Sprintf(`{"id": %q, "uri": "spotify:playlist:%s", "name": %q, "owner": {"display_name": "DM"}, "tracks": {"total": %d}}`,
+				p.id, p.id, p.name, len(p.items)))
+		}
+		fmt.Fprintf(w, `{"items": [%s], "next": %s}`, strings.Join(items, ","), next(len(f.playlists)))
+		return
+	}
+	for _, p := range f.playlists {
+		if r.URL.Path != "/v1/playlists/"+p.id+"/tracks" {
+			continue
+		}
+		var items []string
+		for _, id := range p.items[min(offset, len(p.items)):min(offset+1, len(p.items))] {
+			switch id {
+			case "local":
+				items = append(items, `{"is_local": true, "track": {"type": "track", "name": "Home Recording", "uri": "spotify:local:home"}}`)
+			case "episode":
+				items = append(items, `{"track": {"type": "episode", "name": "Podcast", "uri": "spotify:episode:1"}}`)
+			default:
+				track := f.tracks[id]
+				items = append(items, fmt.Sprintf(`{"track": {"type": "track", "id": %q, "uri": "spotify:track:%s", "name": %q, "duration_ms": 61400,
+					"artists": [{"name": %q}, {"name": "Choir"}], "album": {"images": [{"url": "large.jpg"}, {"url": "small.jpg"}]}}}`,
+					id, id, track.name, track.artist))
+			}
+		}
+		fmt.Fprintf(w, `{"items": [%s], "total": %d, "next": %s}`, strings.Join(items, ","), len(p.items), next(len(p.items)))
+		return
+	}
+	w.WriteHeader(http.StatusNotFound)
+	w.Write([]byte(`{"error": {"status": 404, "message": "Not found"}}`))
+}
+
+func TestPlaylistImport(t *testing.T) {
+	handler, library, db := setupPlaylistTest(t)
+
+	importPlaylists := func(body string) (int, []spotifyService.ImportedPlaylist) {
+		req := httptest.NewRequest(http.MethodPost, "/spotify/playlists/import", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.Import(rr, req)
+		var imported []spotifyService.ImportedPlaylist
+		json.NewDecoder(rr.Body).Decode(&imported)
+		return rr.Code, imported
+	}
+	titles := func(playlist *audio.Playlist) string {
+		var titles []string
+		for _, track := range playlist.Tracks {
+			titles = append(titles, track.Title)
+		}
+		return strings.Join(titles, ",")
+	}
+
+	t.Run("List", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.List(rr, httptest.NewRequest(http.MethodGet, "/spotify/playlists", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var playlists []spotifyService.RemotePlaylist
+		json.NewDecoder(rr.Body).Decode(&playlists)
+		if len(playlists) != 2 || playlists[1].Name != "Dungeon" || playlists[1].TrackCount != 4 || playlists[1].Owner != "DM" {
+			t.Errorf("expected both library playlists, got %+v", playlists)
+		}
+	})
+
+	t.Run("Import_Preserves_Order", func(t *testing.T) {
+		code, imported := importPlaylists(`{"playlist_ids": ["dungeon"]}`)
+		if code != http.StatusOK || len(imported) != 1 {
+			t.Fatalf("handler returned wrong status code: got %v want %v, imported %+v", code, http.StatusOK, imported)
+		}
+		result := imported[0]
+		if !result.Created || result.Skipped != 2 || result.Playlist.Source != audio.SourceSpotify || result.Playlist.SourceID != "spotify:playlist:dungeon" {
+			t.Errorf("expected a new spotify playlist with local files and episodes skipped, got %+v", result)
+		}
+		if got := titles(result.Playlist); got != "Drip,Torchlight" {
+			t.Errorf("expected tracks in order Drip,Torchlight, got %s", got)
+		}
+		track := result.Playlist.Tracks[0]
+		if track.Source != audio.SourceSpotify || track.SourceID != "spotify:track:drip" || track.Artist != "Cave, Choir" ||
+			track.Duration != 61 || track.ThumbnailURL != "small.jpg" {
+			t.Errorf("expected spotify track metadata, got %+v", track)
+		}
+	})
+
+	t.Run("Reimport_Syncs", func(t *testing.T) {
+		library.mu.Lock()
+		library.playlists[1] = fakePlaylist{id: "dungeon", name: "Deep Dungeon", items: []string{"torch", "boss"}}
+		library.tracks["torch"] = fakeTrack{name: "Torchlight (Remastered)", artist: "Fire"}
+		library.mu.Unlock()
+
+		code, imported := importPlaylists(`{"playlist_ids": ["dungeon"]}`)
+		if code != http.StatusOK || len(imported) != 1 {
+			t.Fatalf("handler returned wrong status code: got %v want %v", code, http.StatusOK)
+		}
+		result := imported[0]
+		if result.Created || result.Playlist.Name != "Deep Dungeon" {
+			t.Errorf("expected the earlier import to be renamed, got %+v", result)
+		}
+		if got := titles(result.Playlist); got != "Torchlight (Remastered),Boss Fight" {
+			t.Errorf("expected tracks in order Torchlight (Remastered),Boss Fight, got %s", got)
+		}
+
+		var playlists, tracks int64
+		db.Model(&audio.Playlist{}).Count(&playlists)
+		db.Model(&audio.Track{}).Count(&tracks)
+		if playlists != 1 || tracks != 3 {
+			t.Errorf("expected 1 playlist and 3 tracks, got %d and %d", playlists, tracks)
+		}
+	})
+
+	t.Run("Import_All_Avoids_Name_Clash", func(t *testing.T) {
+		db.Create(&audio.Playlist{Name: "Tavern"})
+
+		code, imported := importPlaylists(``)
+		if code != http.StatusOK || len(imported) != 2 {
+			t.Fatalf("handler returned wrong status code: got %v want %v, imported %+v", code, http.StatusOK, imported)
+		}
+		tavern := imported[0]
+		if !tavern.Created || tavern.Playlist.Name != "Tavern (Spotify)" {
+			t.Errorf("expected a renamed tavern import, got %+v", tavern.Playlist)
+		}
+		// The same track twice in one playlist is kept once, at its first position.
+		if got := titles(tavern.Playlist); got != "Torchlight (Remastered),Drip" {
+			t.Errorf("expected tracks in order Torchlight (Remastered),Drip, got %s", got)
+		}
+		if imported[1].Created {
+			t.Error("expected the dungeon import to be synced again")
+		}
+	})
+
+	t.Run("Unknown_Playlist", func(t *testing.T) {
+		if code, _ := importPlaylists(`{"playlist_ids": ["missing"]}`); code != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", code, http.StatusNotFound)
+		}
+	})
+}
+
+func setupPlaylistTest(t *testing.T) (*PlaylistHandler, *fakeLibrary, *gorm.DB) {
+	rs, db := utils.SetupTestEnvironment(t, &audio.SpotifyToken{}, &audio.Track{}, &audio.Playlist{}, &audio.PlaylistTrack{})
+	library := &fakeLibrary{
+		tracks: map[string]fakeTrack{
+			"torch": {name: "Torchlight", artist: "Fire"},
+			"drip":  {name: "Drip", artist: "Cave"},
+			"boss":  {name: "Boss Fight", artist: "Drums"},
+		},
+		playlists: []fakePlaylist{
+			{id: "tavern", name: "Tavern", items: []string{"torch", "drip", "torch"}},
+			{id: "dungeon", name: "Dungeon", items: []string{"drip", "local", "torch", "episode"}},
+		},
+	}
+	server := httptest.NewServer(library)
+	t.Cleanup(server.Close)
+
+	db.Create(&audio.SpotifyToken{
+		Model:        gorm.Model{ID: 1},
+		AccessToken:  "test-access-token",
+		RefreshToken: "test-refresh-token",
+		TokenType:    "Bearer",
+		Expiry:       time.Now().Add(time.Hour),
+	})
+	return NewPlaylistHandler(newTestService(t, rs, db, server), rs.Log), library, db
+}
@@ -17,3 +17,20 @@ func RegisterSpotifyAuthRoutes(router *mux.Router, spotify *spotifyService.Servi
 	router.HandleFunc("/auth/spotify/token", handler.Token).Methods("GET")
 	router.HandleFunc("/auth/spotify/logout", handler.Logout).Methods("POST")
 }
+
+// RegisterSpotifyPlayerRoutes registers the Spotify playback control endpoints
+func RegisterSpotifyPlayerRoutes(router *mux.Router, spotify *spotifyService.Service, log *slog.Logger) {
+	handler := NewPlayerHandler(spotify, log)
+
+	router.HandleFunc("/spotify/player", handler.State).Methods("GET")
+	router.HandleFunc("/spotify/player", handler.Command).Methods("POST")
+	router.HandleFunc("/spotify/player/devices", handler.Devices).Methods("GET")
+}
+
+// RegisterSpotifyPlaylistRoutes registers the Spotify playlist import endpoints
+func RegisterSpotifyPlaylistRoutes(router *mux.Router, spotify *spotifyService.Service, log *slog.Logger) {
+	handler := NewPlaylistHandler(spotify, log)
+
+	router.HandleFunc("/spotify/playlists", handler.List).Methods("GET")
+	router.HandleFunc("/spotify/playlists/import", handler.Import).Methods("POST")
+}
@@ -0,0 +1,61 @@
+package websocket
+
+import (
+	"dmd/backend/internal/api/common"
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/api/handlers"
+	wsService "dmd/backend/internal/services/websocket"
+	"log/slog"
+	"net/http"
+)
+
+// ClientsResponse lists the connected WebSocket clients.
+type ClientsResponse struct {
+	Count     int                     `json:"count"`
+	ByRole    map[wsService.Role]int  `json:"by_role"`
+	Heartbeat HeartbeatSettings       `json:"heartbeat"`
+	Clients   []wsService.ClientStats `json:"clients"`
+}
+
+// HeartbeatSettings are the manager's heartbeat settings, in seconds and bytes.
+type HeartbeatSettings struct {
+	PingInterval   float64 `json:"ping_interval"`
+	PongTimeout    float64 `json:"pong_timeout"`
+	WriteTimeout   float64 `json:"write_timeout"`
+	MaxMessageSize int64   `json:"max_message_size"`
+}
+
+type ClientsHandler struct {
+	handlers.BaseHandler
+	manager *wsService.Manager
+	log     *slog.Logger
+}
+
+func NewClientsHandler(rs *common.RoutingServices, path string) common.IHandler {
+	return &ClientsHandler{
+		BaseHandler: handlers.NewBaseHandler(path),
+		manager:     rs.WsManager,
+		log:         rs.Log,
+	}
+}
+
+// GET /ws/clients
+func (h *ClientsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	clients := h.manager.Clients()
+	heartbeat := h.manager.Heartbeat()
+	response := ClientsResponse{
+		Count:  len(clients),
+		ByRole: map[wsService.Role]int{wsService.RoleDM: 0, wsService.RoleDisplay: 0, wsService.RolePlayer: 0},
+		Heartbeat: HeartbeatSettings{
+			PingInterval:   heartbeat.PingInterval.Seconds(),
+			PongTimeout:    heartbeat.PongTimeout.Seconds(),
+			WriteTimeout:   heartbeat.WriteTimeout.Seconds(),
+			MaxMessageSize: heartbeat.MaxMessageSize,
+		},
+		Clients: clients,
+	}
+	for _, client := range clients {
+		response.ByRole[client.Role]++
+	}
+	utils.RespondWithJSON(w, http.StatusOK, response)
+}
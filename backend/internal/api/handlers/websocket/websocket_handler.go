@@ -2,9 +2,12 @@
 package websocket
 
 import (
+	errors2 "dmd/backend/internal/api/common/errors"
+	"dmd/backend/internal/api/common/utils"
 	wsService "dmd/backend/internal/services/websocket"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -24,8 +27,31 @@ func RegisterWebsocketRoutes(router *mux.Router, log *slog.Logger, manager *wsSe
 }
 
 // serveWs returns a standard http.HandlerFunc that handles the WebSocket upgrade.
+// The client picks its role and first rooms in the query, e.g. /ws?role=display&room=table&room=tv.
+// A client that reconnects adds the last sequence number it saw, as last_seq, to get the events it missed.
 func serveWs(log *slog.Logger, manager *wsService.Manager) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		role, err := wsService.ParseRole(query.Get("role"))
+		if err != nil {
+			utils.RespondWithError(w, errors2.NewBadRequestError("Invalid websocket role", err))
+			return
+		}
+		rooms := query["room"]
+		if err = wsService.ValidateRooms(rooms); err != nil {
+			utils.RespondWithError(w, errors2.NewBadRequestError("Invalid websocket room", err))
+			return
+		}
+		var lastSeq *uint64
+		if raw := query.Get("last_seq"); raw != "" {
+			seq, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				utils.RespondWithError(w, errors2.NewBadRequestError("Invalid last_seq", err))
+				return
+			}
+			lastSeq = &seq
+		}
+
 		// Upgrade the HTTP connection to a WebSocket connection.
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
@@ -36,7 +62,10 @@ func serveWs(log *slog.Logger, manager *wsService.Manager) http.HandlerFunc {
 		}
 
 		// Create a new client and register it with the manager.
-		client := wsService.NewClient(conn, manager)
+		client := wsService.NewClient(conn, manager, role, rooms...)
+		if lastSeq != nil {
+			client.ResumeFrom(*lastSeq)
+		}
 		manager.RegisterClient(client)
 
 		// Start the goroutines to handle reading and writing for this client.
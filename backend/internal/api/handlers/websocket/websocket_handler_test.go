@@ -0,0 +1,385 @@
+package websocket
+
+import (
+	"dmd/backend/internal/api/common/utils"
+	"dmd/backend/internal/model/websocket"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	gorilla "github.com/gorilla/websocket"
+)
+
+type testClient struct {
+	t    *testing.T
+	conn *gorilla.Conn
+	info wsService.ClientInfo
+}
+
+// next returns the type and payload of the next event.
+func (c *testClient) next() (string, json.RawMessage) {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var event struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	if err := c.conn.ReadJSON(&event); err != nil {
+		c.t.Fatalf("failed to read event: %v", err)
+	}
+	return event.Type, event.Payload
+}
+
+// expect fails unless the next event has the given type.
+func (c *testClient) expect(eventType string) json.RawMessage {
+	c.t.Helper()
+	got, payload := c.next()
+	if got != eventType {
+		c.t.Fatalf("%s client: expected %s, got %s", c.info.Role, eventType, got)
+	}
+	return payload
+}
+
+func TestWebsocketRolesAndRooms(t *testing.T) {
+	rs, _ := utils.SetupTestEnvironment(t)
+	manager := wsService.NewManager(rs.Log)
+	// Relays a message to everyone but its sender, like a client broadcasting its own cursor.
+	manager.RegisterHandler("relay", func(payload json.RawMessage, client *wsService.Client) {
+		manager.BroadcastExcept(client, websocket.Event{Type: "relayed", Payload: payload})
+	})
+	go manager.Run()
+
+	router := mux.NewRouter()
+	RegisterWebsocketRoutes(router, rs.Log, manager)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// dial connects and waits for client_info, so the client is registered before anything is sent.
+	dial := func(query string) *testClient {
+		conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws"+query, nil)
+		if err != nil {
+			t.Fatalf("failed to connect with %q: %v", query, err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		client := &testClient{t: t, conn: conn}
+		conn.WriteJSON(map[string]any{"type": wsService.MessageClientInfoRequest})
+		json.Unmarshal(client.expect(wsService.EventClientInfo), &client.info)
+		return client
+	}
+	dm, display, player := dial("?role=dm"), dial("?role=display&room=table&room=tv"), dial("")
+	everyone := []*testClient{dm, display, player}
+
+	// received sends a marker to everyone after the targeted event, and returns the clients that got the event first.
+	received := func(eventType string) map[*testClient]bool {
+		manager.Broadcast(websocket.Event{Type: "marker"})
+		got := make(map[*testClient]bool)
+		for _, client := range everyone {
+			if next, _ := client.next(); next == eventType {
+				got[client] = true
+				client.expect("marker")
+			} else if next != "marker" {
+				t.Fatalf("expected %s or the marker, got %s", eventType, next)
+			}
+		}
+		return got
+	}
+
+	t.Run("Client_Info", func(t *testing.T) {
+		if dm.info.Role != wsService.RoleDM || dm.info.ID == "" || dm.info.ID == display.info.ID {
+			t.Errorf("expected a dm client with its own id, got %+v", dm.info)
+		}
+		if display.info.Role != wsService.RoleDisplay || strings.Join(display.info.Rooms, ",") != "table,tv" {
+			t.Errorf("expected a display in the table and tv rooms, got %+v", display.info)
+		}
+		if player.info.Role != wsService.RolePlayer || len(player.info.Rooms) != 0 {
+			t.Errorf("expected clients without a role to be players, got %+v", player.info)
+		}
+	})
+
+	t.Run("Send_To_Role", func(t *testing.T) {
+		manager.SendToRole(wsService.RoleDM, websocket.Event{Type: "secret_roll"})
+		if got := received("secret_roll"); len(got) != 1 || !got[dm] {
+			t.Errorf("expected only the dm to get the secret roll, got %d clients", len(got))
+		}
+	})
+
+	t.Run("Rooms", func(t *testing.T) {
+		manager.SendToRoom("table", websocket.Event{Type: "table_only"})
+		if got := received("table_only"); len(got) != 1 || !got[display] {
+			t.Errorf("expected only the display to get the table event, got %d clients", len(got))
+		}
+
+		player.conn.WriteJSON(map[string]any{"type": wsService.MessageJoinRoom, "payload": map[string]any{"room": "table"}})
+		var info wsService.ClientInfo
+		json.Unmarshal(player.expect(wsService.EventClientInfo), &info)
+		if strings.Join(info.Rooms, ",") != "table" {
+			t.Errorf("expected the player to be in the table room, got %+v", info)
+		}
+		manager.SendToRoom("table", websocket.Event{Type: "table_only"})
+		if got := received("table_only"); len(got) != 2 || !got[player] {
+			t.Errorf("expected the display and the player to get the table event, got %d clients", len(got))
+		}
+
+		player.conn.WriteJSON(map[string]any{"type": wsService.MessageLeaveRoom, "payload": map[string]any{"room": "table"}})
+		player.expect(wsService.EventClientInfo)
+		player.conn.WriteJSON(map[string]any{"type": wsService.MessageJoinRoom, "payload": map[string]any{"room": ""}})
+		player.expect(wsService.EventRoomError)
+	})
+
+	t.Run("Broadcast_Except_Sender", func(t *testing.T) {
+		display.conn.WriteJSON(map[string]any{"type": "relay", "payload": map[string]any{"x": 1}})
+		dm.expect("relayed")
+		player.expect("relayed")
+		manager.Broadcast(websocket.Event{Type: "marker"})
+		for _, client := range everyone {
+			client.expect("marker")
+		}
+	})
+
+	t.Run("Invalid_Connection", func(t *testing.T) {
+		for _, query := range []string{"?role=admin", "?room=", "?room=" + strings.Repeat("x", 65)} {
+			rr := httptest.NewRecorder()
+			serveWs(rs.Log, manager)(rr, httptest.NewRequest(http.MethodGet, "/ws"+query, nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: handler returned wrong status code: got %v want %v", query, rr.Code, http.StatusBadRequest)
+			}
+		}
+	})
+}
+
+func TestHeartbeatAndClientStats(t *testing.T) {
+	rs, _ := utils.SetupTestEnvironment(t)
+	rs.WsManager = wsService.NewManager(rs.Log, wsService.WithHeartbeat(wsService.Heartbeat{
+		PingInterval:   50 * time.Millisecond,
+		PongTimeout:    200 * time.Millisecond,
+		MaxMessageSize: 1024,
+	}))
+	go rs.WsManager.Run()
+
+	router := mux.NewRouter()
+	RegisterWebsocketRoutes(router, rs.Log, rs.WsManager)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	dial := func(query string) *gorilla.Conn {
+		conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws"+query, nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conn.WriteJSON(map[string]any{"type": wsService.MessageClientInfoRequest})
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, _, err := conn.ReadMessage(); err != nil {
+			t.Fatalf("failed to read client_info: %v", err)
+		}
+		return conn
+	}
+	// keepReading answers pings, which the client library only does while reading.
+	keepReading := func(conn *gorilla.Conn) {
+		go func() {
+			for {
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	getStats := func() ClientsResponse {
+		rr := httptest.NewRecorder()
+		NewClientsHandler(rs, "/ws/clients").(*ClientsHandler).Get(rr, httptest.NewRequest(http.MethodGet, "/ws/clients", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		var stats ClientsResponse
+		json.NewDecoder(rr.Body).Decode(&stats)
+		return stats
+	}
+	waitForCount := func(want int) ClientsResponse {
+		var stats ClientsResponse
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+			if stats = getStats(); stats.Count == want {
+				break
+			}
+		}
+		return stats
+	}
+
+	dm := dial("?role=dm&room=table")
+	keepReading(dm)
+	dial("?role=display") // Never reads again, so never answers a ping, like a laptop gone to sleep
+
+	t.Run("Stats", func(t *testing.T) {
+		stats := getStats()
+		if stats.Count != 2 || stats.ByRole[wsService.RoleDM] != 1 || stats.ByRole[wsService.RoleDisplay] != 1 || stats.ByRole[wsService.RolePlayer] != 0 {
+			t.Fatalf("expected a dm and a display, got %+v", stats)
+		}
+		first := stats.Clients[0]
+		if first.Role != wsService.RoleDM || first.ID == "" || first.RemoteAddr == "" || strings.Join(first.Rooms, ",") != "table" ||
+			first.LastSeen.Before(first.ConnectedAt) {
+			t.Errorf("expected the dm first, with its details, got %+v", first)
+		}
+		if stats.Heartbeat.PingInterval != 0.05 || stats.Heartbeat.PongTimeout != 0.2 || stats.Heartbeat.WriteTimeout != 10 || stats.Heartbeat.MaxMessageSize != 1024 {
+			t.Errorf("expected the configured heartbeat with a default write timeout, got %+v", stats.Heartbeat)
+		}
+	})
+
+	t.Run("Unresponsive_Client_Is_Dropped", func(t *testing.T) {
+		stats := waitForCount(1)
+		if stats.Count != 1 || stats.Clients[0].Role != wsService.RoleDM {
+			t.Fatalf("expected only the dm to stay connected, got %+v", stats)
+		}
+		if lastSeen := stats.Clients[0].LastSeen; time.Since(lastSeen) > 150*time.Millisecond {
+			t.Errorf("expected the dm's pongs to keep it seen, last seen %v ago", time.Since(lastSeen))
+		}
+	})
+
+	t.Run("Oversized_Message_Closes_Connection", func(t *testing.T) {
+		player := dial("")
+		keepReading(player)
+		waitForCount(2)
+		player.WriteJSON(map[string]any{"type": "send_message", "payload": map[string]any{"content": strings.Repeat("x", 2048)}})
+		if stats := waitForCount(1); stats.Count != 1 {
+			t.Errorf("expected the player to be dropped, got %+v", stats)
+		}
+	})
+}
+
+func TestEventReplay(t *testing.T) {
+	rs, _ := utils.SetupTestEnvironment(t)
+	manager := wsService.NewManager(rs.Log, wsService.WithReplayBuffer(4))
+	manager.RegisterResyncHandler(func(client *wsService.Client) {
+		manager.SendTo(client, websocket.Event{Type: "state_snapshot"})
+	})
+	go manager.Run()
+
+	router := mux.NewRouter()
+	RegisterWebsocketRoutes(router, rs.Log, manager)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	type seqEvent struct {
+		Seq     uint64                 `json:"seq"`
+		Type    string                 `json:"type"`
+		Payload wsService.ResumeStatus `json:"payload"`
+	}
+	read := func(conn *gorilla.Conn) seqEvent {
+		t.Helper()
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		var event seqEvent
+		if err := conn.ReadJSON(&event); err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		return event
+	}
+	dial := func(query string) *gorilla.Conn {
+		conn, _, err := gorilla.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws"+query, nil)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	// ready waits until the client is registered: replies to a single client are not numbered.
+	ready := func(conn *gorilla.Conn) {
+		conn.WriteJSON(map[string]any{"type": wsService.MessageClientInfoRequest})
+		if event := read(conn); event.Type != wsService.EventClientInfo || event.Seq != 0 {
+			t.Fatalf("expected an unnumbered client_info, got %+v", event)
+		}
+	}
+
+	dm := dial("?role=dm")
+	ready(dm)
+	manager.Broadcast(websocket.Event{Type: "images_updated"})
+	manager.Broadcast(websocket.Event{Type: "display_updated"})
+	manager.SendToRole(wsService.RoleDM, websocket.Event{Type: "secret_roll"})
+	first, second, secret := read(dm), read(dm), read(dm)
+	if first.Seq == 0 || second.Seq != first.Seq+1 || secret.Seq != first.Seq+2 {
+		t.Fatalf("expected consecutive sequence numbers, got %d, %d and %d", first.Seq, second.Seq, secret.Seq)
+	}
+	// Sequence numbers grow across restarts, so they start from the start time.
+	if first.Seq < uint64(time.Now().Add(-time.Hour).UnixMilli())*1000 {
+		t.Errorf("expected sequence numbers based on the start time, got %d", first.Seq)
+	}
+
+	t.Run("Resume_Replays_Missed_Events", func(t *testing.T) {
+		display := dial(fmt.Sprintf("?role=display&last_seq=%d", first.Seq))
+		// The DM-only event is skipped, as it was never meant for a display.
+		if event := read(display); event.Type != "display_updated" || event.Seq != second.Seq {
+			t.Errorf("expected the missed display_updated, got %+v", event)
+		}
+		if event := read(display); event.Type != wsService.EventResumed || event.Payload.Seq != secret.Seq || event.Payload.Replayed != 1 {
+			t.Errorf("expected resumed after 1 replayed event, got %+v", event)
+		}
+		manager.Broadcast(websocket.Event{Type: "tracks_updated"})
+		if event := read(display); event.Type != "tracks_updated" || event.Seq != secret.Seq+1 {
+			t.Errorf("expected live events after the replay, got %+v", event)
+		}
+		read(dm)
+	})
+
+	t.Run("Up_To_Date_Client", func(t *testing.T) {
+		conn := dial(fmt.Sprintf("?role=dm&last_seq=%d", secret.Seq+1))
+		if event := read(conn); event.Type != wsService.EventResumed || event.Payload.Replayed != 0 {
+			t.Errorf("expected resumed with nothing to replay, got %+v", event)
+		}
+	})
+
+	t.Run("Too_Far_Behind_Resyncs", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			manager.Broadcast(websocket.Event{Type: "images_updated"})
+			read(dm)
+		}
+		// The buffer only holds 4 events, so the event after first is gone. A number from before a restart
+		// or from the future cannot be resumed from either.
+		for _, lastSeq := range []uint64{first.Seq, 12345, secret.Seq + 1000} {
+			conn := dial(fmt.Sprintf("?role=display&last_seq=%d", lastSeq))
+			if event := read(conn); event.Type != wsService.EventResync || event.Payload.Reason == "" {
+				t.Errorf("last_seq %d: expected resync, got %+v", lastSeq, event)
+			}
+			if event := read(conn); event.Type != "state_snapshot" {
+				t.Errorf("last_seq %d: expected the resync handler's state, got %+v", lastSeq, event)
+			}
+		}
+	})
+
+	t.Run("Transient_Events", func(t *testing.T) {
+		manager.Broadcast(websocket.Event{Type: "images_updated"})
+		base := read(dm).Seq
+		manager.BroadcastTransient(websocket.Event{Type: "soundboard_trigger"})
+		if event := read(dm); event.Type != "soundboard_trigger" || event.Seq != 0 {
+			t.Errorf("expected an unnumbered soundboard_trigger, got %+v", event)
+		}
+		manager.BroadcastWithReplay(websocket.Event{Type: "playback_transition"}, websocket.Event{Type: "playback_updated"})
+		if event := read(dm); event.Type != "playback_transition" || event.Seq != base+1 {
+			t.Errorf("expected a numbered playback_transition, got %+v", event)
+		}
+		manager.Broadcast(websocket.Event{Type: "tracks_updated"})
+		read(dm)
+
+		// The sound is not played late, and the transition is replaced by the state it leads to.
+		display := dial(fmt.Sprintf("?role=display&last_seq=%d", base))
+		for i, want := range []string{"playback_updated", "tracks_updated"} {
+			if event := read(display); event.Type != want || event.Seq != base+1+uint64(i) {
+				t.Errorf("expected %s to be replayed, got %+v", want, event)
+			}
+		}
+		if event := read(display); event.Type != wsService.EventResumed || event.Payload.Replayed != 2 {
+			t.Errorf("expected resumed after 2 replayed events, got %+v", event)
+		}
+	})
+
+	t.Run("Invalid_Last_Seq", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		serveWs(rs.Log, manager)(rr, httptest.NewRequest(http.MethodGet, "/ws?last_seq=abc", nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -14,6 +14,7 @@ import (
 	"dmd/backend/internal/api/handlers/healthChecker"
 	"dmd/backend/internal/api/handlers/images"
 	"dmd/backend/internal/api/handlers/system"
+	"dmd/backend/internal/api/handlers/websocket"
 )
 
 type routeDetails struct {
@@ -38,15 +39,52 @@ var apiRoutes = []routeDetails{
 	newRouteDetails("/gameplay/combat", combat.NewCombatHandler),
 	newRouteDetails("/audio", audio.NewAudioHandler),
 	newRouteDetails("/audio/tracks", audio.NewTracksHandler),
+	newRouteDetails("/audio/tracks/{id}", audio.NewTracksHandler),
+	newRouteDetails("/audio/tracks/{id}/stream", audio.NewTrackStreamHandler),
+	newRouteDetails("/audio/tracks/{id}/waveform", audio.NewTrackWaveformHandler),
 	newRouteDetails("/audio/playlists", audio.NewPlaylistsHandler),
+	newRouteDetails("/audio/playlists/{id}", audio.NewPlaylistsHandler),
+	newRouteDetails("/audio/playlists/{id}/tracks", audio.NewPlaylistTracksHandler),
+	newRouteDetails("/audio/playlists/{id}/tracks/{trackId}", audio.NewPlaylistTrackHandler),
+	newRouteDetails("/audio/soundboards", audio.NewSoundboardsHandler),
+	newRouteDetails("/audio/soundboards/pads/{id}/trigger", audio.NewSoundboardTriggerHandler),
+	newRouteDetails("/audio/soundboards/{id}", audio.NewSoundboardsHandler),
+	newRouteDetails("/audio/youtube/import", audio.NewYouTubeImportHandler),
 	newRouteDetails("/display", display.NewDisplayHandler),
+	newRouteDetails("/display/scenes", display.NewScenesHandler),
+	newRouteDetails("/display/scenes/{id}", display.NewScenesHandler),
+	newRouteDetails("/display/scenes/{id}/next", display.NewSceneNextHandler),
+	newRouteDetails("/display/scenes/{id}/previous", display.NewScenePreviousHandler),
 	newRouteDetails("/images/images", images.NewImagesHandler),
 	newRouteDetails("/images/images/{id}", images.NewImagesHandler),
+	newRouteDetails("/images/images/{id}/fog", images.NewFogHandler),
+	newRouteDetails("/images/images/{id}/fog/reveal", images.NewFogRevealHandler),
+	newRouteDetails("/images/images/{id}/fog/hide", images.NewFogHideHandler),
+	newRouteDetails("/images/images/{id}/grid", images.NewGridHandler),
+	newRouteDetails("/images/images/{id}/grid/cell", images.NewGridCellHandler),
+	newRouteDetails("/images/images/{id}/grid/measure", images.NewGridMeasureHandler),
+	newRouteDetails("/images/images/{id}/tokens", images.NewMapTokensHandler),
+	newRouteDetails("/images/tokens/{id}", images.NewTokenHandler),
+	newRouteDetails("/images/tokens/{id}/move", images.NewTokenMoveHandler),
+	newRouteDetails("/images/images/{id}/annotations", images.NewImageAnnotationsHandler),
+	newRouteDetails("/images/annotations/{id}", images.NewAnnotationHandler),
+	newRouteDetails("/images/annotations/{id}/points", images.NewAnnotationPointsHandler),
+	newRouteDetails("/images/pdfs/search", images.NewPdfSearchHandler),
+	newRouteDetails("/images/pdfs/bookmarks/{id}", images.NewPdfBookmarkHandler),
+	newRouteDetails("/images/pdfs/{id}/pages", images.NewPdfPagesHandler),
+	newRouteDetails("/images/pdfs/{id}/pages/{page}", images.NewPdfPageHandler),
+	newRouteDetails("/images/pdfs/{id}/pages/{page}/thumbnail", images.NewPdfThumbnailHandler),
+	newRouteDetails("/images/pdfs/{id}/pages/{page}/crop", images.NewPdfCropHandler),
+	newRouteDetails("/images/pdfs/{id}/outline", images.NewPdfOutlineHandler),
+	newRouteDetails("/images/pdfs/{id}/bookmarks", images.NewPdfBookmarksHandler),
 	newRouteDetails("/images/types", images.NewImageTypeHandler),
 	newRouteDetails("/images/presets", images.NewPresetHandler),
+	newRouteDetails("/images/presets/order", images.NewPresetOrderHandler), // Must precede "/images/presets/{id}"
 	newRouteDetails("/images/presets/{id}", images.NewPresetHandler),
+	newRouteDetails("/images/presets/{id}/duplicate", images.NewPresetDuplicateHandler),
 	newRouteDetails("/images/upload", images.NewUploadHandler),
 	newRouteDetails("/system", system.NewSystemHandler),
+	newRouteDetails("/ws/clients", websocket.NewClientsHandler),
 	newRouteDetails("/crawl/templates", crawl.NewCharacterTemplateHandler),
 	newRouteDetails("/crawl/templates/{id}", crawl.NewCharacterTemplateHandler),
 }
@@ -29,9 +29,11 @@ func NewRouter(rs *common.RoutingServices, staticAssetsPath string) *mux.Router
 	// Register API routes on the sub-router
 	registerRoutes(apiV1, rs)
 
-	// Register Spotify auth routes if service is available
+	// Register Spotify auth, player and playlist routes if service is available
 	if rs.SpotifyService != nil {
 		spotify.RegisterSpotifyAuthRoutes(apiV1, rs.SpotifyService, rs.Log)
+		spotify.RegisterSpotifyPlayerRoutes(apiV1, rs.SpotifyService, rs.Log)
+		spotify.RegisterSpotifyPlaylistRoutes(apiV1, rs.SpotifyService, rs.Log)
 	}
 
 	// Register crawl photo upload route
@@ -0,0 +1,77 @@
+package audio
+
+import "time"
+
+// ChannelName identifies one of the independently mixed playback channels.
+type ChannelName string
+
+const (
+	ChannelMusic    ChannelName = "music"
+	ChannelAmbience ChannelName = "ambience"
+	ChannelSFX      ChannelName = "sfx"
+)
+
+// Channels lists every playback channel in display order.
+var Channels = []ChannelName{ChannelMusic, ChannelAmbience, ChannelSFX}
+
+type PlaybackStatus string
+
+const (
+	StatusStopped PlaybackStatus = "stopped"
+	StatusPlaying PlaybackStatus = "playing"
+	StatusPaused  PlaybackStatus = "paused"
+)
+
+// ChannelState is what a single channel is playing. It is owned by the server and kept in memory.
+// Position is the offset into the current track, in seconds, at UpdatedAt; while playing, clients
+// add the time elapsed since UpdatedAt so every display plays from the same point.
+type ChannelState struct {
+	Name       ChannelName    `json:"name"`
+	Status     PlaybackStatus `json:"status"`
+	TrackID    *uint          `json:"track_id"`    // The track currently loaded
+	PlaylistID *uint          `json:"playlist_id"` // Set when the channel plays through a playlist
+	Queue      []uint         `json:"queue"`       // Track IDs in play order; shuffled when Shuffle is on
+	QueueIndex int            `json:"queue_index"` // Position of TrackID within Queue
+	Position   float64        `json:"position"`
+	Volume     float64        `json:"volume"` // 0 to 1
+	Loop       bool           `json:"loop"`   // Repeat the track, or restart the playlist after its last track
+	Shuffle    bool           `json:"shuffle"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	Crossfade  *Crossfade     `json:"crossfade,omitempty"` // Set while the channel fades from one track to another
+	Duck       *Duck          `json:"duck,omitempty"`      // Set while the channel is ducked
+
+	Track *Track `json:"track,omitempty"` // Resolved by the server from TrackID
+}
+
+// Crossfade fades From out while the channel's own track fades in, between StartsAt and EndsAt.
+// A scheduled crossfade starts in the future; until then displays keep playing From at full volume,
+// and the channel's UpdatedAt is StartsAt so its track starts from Position at that moment.
+type Crossfade struct {
+	ID       uint64        `json:"id"`
+	StartsAt time.Time     `json:"starts_at"`
+	EndsAt   time.Time     `json:"ends_at"`
+	From     *ChannelState `json:"from,omitempty"` // Nil when nothing was playing, which makes it a fade-in
+}
+
+// Duck lowers a channel to Level times its volume, e.g. the music under a sound effect. Displays ramp down
+// over Fade seconds from StartsAt, hold, and ramp back up over Fade seconds from EndsAt.
+type Duck struct {
+	ID       uint64    `json:"id"`
+	Level    float64   `json:"level"` // 0 to 1
+	Fade     float64   `json:"fade"`
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+}
+
+// PositionAt returns the channel's offset into its track at the given time.
+func (c ChannelState) PositionAt(t time.Time) float64 {
+	if c.Status != StatusPlaying || t.Before(c.UpdatedAt) {
+		return c.Position
+	}
+	return c.Position + t.Sub(c.UpdatedAt).Seconds()
+}
+
+// PlaybackState is the snapshot broadcast to every player-side browser.
+type PlaybackState struct {
+	Channels []ChannelState `json:"channels"`
+}
@@ -10,6 +10,11 @@ type Playlist struct {
     Name        string   `gorm:"not null;unique" json:"name"`
     Description string   `json:"description"`
     Tracks      []*Track `gorm:"many2many:playlist_tracks;" json:"tracks,omitempty"`
+
+    // Where the playlist was imported from, e.g. Source=spotify and SourceID=its Spotify URI.
+    // Both are empty for playlists made in DMD.
+    Source   string `gorm:"index" json:"source,omitempty"`
+    SourceID string `gorm:"index" json:"source_id,omitempty"`
 }
 
 // PlaylistTrack is the explicit join table between playlists and tracks.
@@ -0,0 +1,38 @@
+package audio
+
+import "gorm.io/gorm"
+
+// PadMode decides what a pad does when it is triggered while its sound is still playing.
+type PadMode string
+
+const (
+	PadModeRestart PadMode = "restart" // Stop the sound and play it again from the start
+	PadModeOverlap PadMode = "overlap" // Play another copy on top of the one already playing
+)
+
+// Soundboard is a grid of pads, each firing a one-shot sound effect over whatever music is playing.
+type Soundboard struct {
+	gorm.Model
+
+	Name    string          `gorm:"not null;unique" json:"name"`
+	Rows    uint            `gorm:"not null;default:4" json:"rows"`
+	Columns uint            `gorm:"not null;default:4" json:"columns"`
+	Pads    []SoundboardPad `gorm:"foreignKey:SoundboardID" json:"pads"`
+}
+
+// SoundboardPad is a single cell of a soundboard. Row and Column are zero-based.
+type SoundboardPad struct {
+	gorm.Model
+
+	SoundboardID uint     `gorm:"not null;index" json:"soundboard_id"`
+	Row          uint     `gorm:"not null" json:"row"`
+	Column       uint     `gorm:"not null" json:"column"`
+	Label        string   `json:"label"`
+	TrackID      uint     `gorm:"not null" json:"track_id"`
+	Volume       *float64 `gorm:"not null;default:1" json:"volume"` // 0 to 1; left out it defaults to full volume
+	KeyBinding   string   `json:"key_binding"`                      // Keyboard shortcut on the DM screen, e.g. "q" or "shift+1"
+	Mode         PadMode  `gorm:"not null;default:restart" json:"mode"`
+	DuckMusic    bool     `gorm:"not null;default:false" json:"duck_music"` // Lower the music while the pad plays
+
+	Track Track `gorm:"foreignKey:TrackID" json:"track"`
+}
@@ -1,6 +1,10 @@
 package audio
 
-import "gorm.io/gorm"
+import (
+    "time"
+
+    "gorm.io/gorm"
+)
 
 const (
     SourceLocal   = "local"
@@ -16,6 +20,13 @@ type Track struct {
     Duration     uint   `json:"duration"` // Duration in seconds
     ThumbnailURL string `json:"thumbnail_url"`
 
+    // Filled in by analysing local files in the background. Tracks from other sources, files that
+    // cannot be decoded and files not analysed yet have no loudness or waveform.
+    Loudness   *float64   `json:"loudness,omitempty"`    // Integrated loudness in LUFS
+    Gain       *float64   `json:"gain,omitempty"`        // Volume multiplier that brings the track to the target loudness
+    Waveform   []byte     `json:"-"`                     // Peak level of equal slices of the track, 0-255
+    AnalyzedAt *time.Time `json:"analyzed_at,omitempty"`
+
     // Composite key to ensure a SourceID is unique for its Source
     Source   string `gorm:"not null;index;uniqueIndex:idx_source_id" json:"source"`
     SourceID string `gorm:"not null;uniqueIndex:idx_source_id" json:"source_id"`
@@ -16,6 +16,9 @@ type Combat struct {
 
     // A combat has many combatants.
     Combatants []Combatant
+
+    // Everything recorded during the combat, oldest first.
+    History []HistoryEntry
 }
 
 // Combatant represents a single participant (a PC or NPC) in a combat.
@@ -33,3 +36,19 @@ type Combatant struct {
     IsActive      bool           `gorm:"default:true" json:"is_active"`
     StatusEffects datatypes.JSON `json:"status_effects"`
 }
+
+// History event types.
+const (
+    HistoryTokenMoved = "token_moved"
+)
+
+// HistoryEntry records something that happened during a combat, in the round it happened.
+type HistoryEntry struct {
+    gorm.Model
+
+    CombatID    uint           `gorm:"not null;index" json:"combat_id"`
+    Round       uint           `json:"round"`
+    Event       string         `gorm:"not null" json:"event"`
+    CombatantID *uint          `json:"combatant_id"` // The Combatant row involved, if any
+    Details     datatypes.JSON `json:"details"`
+}
@@ -0,0 +1,25 @@
+// File: internal/model/display/display.go
+package display
+
+import (
+	"dmd/backend/internal/model/images"
+	"time"
+)
+
+// State is the server-owned snapshot of what the player display is showing.
+// It is kept in memory and broadcast to every connected display on change.
+type State struct {
+	LayoutType images.LayoutType `json:"layout_type"` // Empty when the display is cleared
+	Slots      []Slot            `json:"slots"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// Slot is a single image position within the displayed layout.
+type Slot struct {
+	SlotID    int                `json:"slot_id"`         // The position in the grid (0, 1, 2, 3)
+	ImageID   uint               `json:"image_id"`        // 0 for an empty slot
+	Zoom      float64            `json:"zoom"`            // The zoom level
+	Page      int                `json:"page"`            // The page number (for PDFs)
+	PositionY float64            `json:"position_y"`      // Vertical offset in percentage (negative = up, positive = down)
+	Image     *images.ImageEntry `json:"image,omitempty"` // Resolved by the server from ImageID
+}
@@ -0,0 +1,34 @@
+package display
+
+import (
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/images"
+
+	"gorm.io/gorm"
+)
+
+// Scene is an ordered sequence of presets the DM steps through during a session.
+type Scene struct {
+	gorm.Model
+
+	Name        string     `gorm:"not null;unique" json:"name"`
+	Description string     `json:"description"`
+	ActiveCue   uint       `gorm:"not null;default:0" json:"active_cue"` // CueOrder of the cue on display, 0 before the first
+	Cues        []SceneCue `gorm:"foreignKey:SceneID" json:"cues"`
+}
+
+// SceneCue is a single step of a scene: a preset to show and optional audio to start with it.
+type SceneCue struct {
+	gorm.Model
+
+	SceneID        uint   `gorm:"not null;index" json:"-"`
+	CueOrder       uint   `gorm:"not null" json:"cue_order"` // The position of the cue in the scene, starting at 1
+	PresetLayoutID uint   `gorm:"not null" json:"preset_layout_id"`
+	TrackID        *uint  `json:"track_id"`    // Optional track to play when the cue is applied
+	PlaylistID     *uint  `json:"playlist_id"` // Optional playlist to play when the cue is applied
+	Notes          string `json:"notes"`
+
+	PresetLayout images.PresetLayout `gorm:"foreignKey:PresetLayoutID" json:"preset_layout"`
+	Track        *audio.Track        `gorm:"foreignKey:TrackID" json:"track,omitempty"`
+	Playlist     *audio.Playlist     `gorm:"foreignKey:PlaylistID" json:"playlist,omitempty"`
+}
@@ -0,0 +1,42 @@
+package images
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Annotation kinds. Points are in source-image pixels.
+const (
+	AnnotationStroke  = "stroke"  // Freehand: the points in drawing order
+	AnnotationRect    = "rect"    // Two opposite corners
+	AnnotationEllipse = "ellipse" // Two opposite corners of the bounding box
+	AnnotationText    = "text"    // One anchor point, with Text
+	AnnotationCone    = "cone"    // AoE: origin and a point the cone faces, SizeFeet long
+	AnnotationSphere  = "sphere"  // AoE: center, SizeFeet radius
+	AnnotationLine    = "line"    // AoE: origin and a point the line faces, SizeFeet long and 5 ft wide
+)
+
+// Annotation visibility.
+const (
+	VisibilityDM     = "dm"     // Only shown on the DM's screen
+	VisibilityShared = "shared" // Also shown on the player display
+)
+
+// Annotation is a single vector drawing on an image.
+type Annotation struct {
+	gorm.Model
+
+	ImageID     uint                       `gorm:"not null;index" json:"image_id"`
+	Kind        string                     `gorm:"not null" json:"kind"`
+	Visibility  string                     `gorm:"not null;default:dm" json:"visibility"`
+	Points      datatypes.JSONSlice[Point] `json:"points"`
+	Text        string                     `json:"text,omitempty"`
+	SizeFeet    float64                    `json:"size_feet,omitempty"`
+	Color       string                     `json:"color"`
+	StrokeWidth float64                    `json:"stroke_width"`
+}
+
+// IsAreaOfEffect reports whether the annotation is a spell template measured in feet.
+func (a *Annotation) IsAreaOfEffect() bool {
+	return a.Kind == AnnotationCone || a.Kind == AnnotationSphere || a.Kind == AnnotationLine
+}
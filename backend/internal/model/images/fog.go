@@ -0,0 +1,38 @@
+package images
+
+import (
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Fog operation types. A map starts fully fogged; the mask is the ordered
+// replay of every operation since the most recent reset.
+const (
+	FogOpReveal = "reveal"
+	FogOpHide   = "hide"
+	FogOpReset  = "reset"
+)
+
+// Fog area shapes.
+const (
+	FogShapeRect    = "rect"    // Points holds two opposite corners
+	FogShapePolygon = "polygon" // Points holds three or more vertices
+)
+
+// FogOperation is a single, versioned change to a map's fog mask.
+type FogOperation struct {
+	gorm.Model
+
+	ImageID uint                       `gorm:"not null;uniqueIndex:idx_fog_version" json:"image_id"`
+	Version uint                       `gorm:"not null;uniqueIndex:idx_fog_version" json:"version"` // Increments per map, starting at 1
+	Op      string                     `gorm:"not null" json:"op"`
+	Shape   string                     `json:"shape,omitempty"`
+	Points  datatypes.JSONSlice[Point] `json:"points,omitempty"`
+}
+
+// FogMask is a map's current fog state: the operations to replay, in version order.
+type FogMask struct {
+	ImageID    uint            `json:"image_id"`
+	Version    uint            `json:"version"`
+	Operations []*FogOperation `json:"operations"`
+}
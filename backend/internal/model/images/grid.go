@@ -0,0 +1,155 @@
+package images
+
+import "math"
+
+// Grid types.
+const (
+	GridTypeSquare = "square"
+	GridTypeHex    = "hex" // Pointy-top hexes; odd rows are shifted right by half a cell
+)
+
+// DefaultFeetPerCell is the standard D&D 5e grid scale.
+const DefaultFeetPerCell = 5
+
+// MapGrid describes how a map image is divided into cells. All lengths are in source-image pixels.
+type MapGrid struct {
+	Type        string  `json:"type"`          // Empty when the map has not been calibrated
+	CellSize    float64 `json:"cell_size"`     // Square: side length. Hex: width from flat side to flat side
+	OffsetX     float64 `json:"offset_x"`      // Top-left corner of cell (0, 0)
+	OffsetY     float64 `json:"offset_y"`      // Top-left corner of cell (0, 0)
+	FeetPerCell float64 `json:"feet_per_cell"` // Distance covered by moving one cell
+	Columns     int     `json:"columns"`       // Cells across the map; 0 until known
+	Rows        int     `json:"rows"`          // Cells down the map; 0 until known
+}
+
+// GridCell is a cell position on a map grid.
+type GridCell struct {
+	Col int `json:"col"`
+	Row int `json:"row"`
+}
+
+// IsCalibrated reports whether the grid can be used for conversions.
+func (g MapGrid) IsCalibrated() bool {
+	return (g.Type == GridTypeSquare || g.Type == GridTypeHex) && g.CellSize > 0
+}
+
+// HasExtent reports whether the grid knows how many cells the map holds.
+func (g MapGrid) HasExtent() bool {
+	return g.Columns > 0 && g.Rows > 0
+}
+
+// Contains reports whether a cell lies on the grid. While the extent is unknown only negative cells are outside it.
+func (g MapGrid) Contains(cell GridCell) bool {
+	if cell.Col < 0 || cell.Row < 0 {
+		return false
+	}
+	return !g.HasExtent() || (cell.Col < g.Columns && cell.Row < g.Rows)
+}
+
+// Extent returns the number of columns and rows whose cell centres lie on an image of the given size.
+func (g MapGrid) Extent(width, height int) (int, int) {
+	w, h := float64(width)-g.OffsetX, float64(height)-g.OffsetY
+	if g.Type == GridTypeHex {
+		r := g.hexRadius()
+		return countCenters(w, g.CellSize/2, g.CellSize), countCenters(h, r, 1.5*r)
+	}
+	return countCenters(w, g.CellSize/2, g.CellSize), countCenters(h, g.CellSize/2, g.CellSize)
+}
+
+// CellAt returns the cell containing the pixel (x, y).
+func (g MapGrid) CellAt(x, y float64) GridCell {
+	x -= g.OffsetX
+	y -= g.OffsetY
+	if g.Type == GridTypeHex {
+		return g.hexCellAt(x, y)
+	}
+	return GridCell{Col: int(math.Floor(x / g.CellSize)), Row: int(math.Floor(y / g.CellSize))}
+}
+
+// CellCenter returns the pixel at the center of a cell.
+func (g MapGrid) CellCenter(cell GridCell) Point {
+	if g.Type == GridTypeHex {
+		x, y := g.hexCenter(cell)
+		return Point{X: x + g.OffsetX, Y: y + g.OffsetY}
+	}
+	return Point{
+		X: g.OffsetX + (float64(cell.Col)+0.5)*g.CellSize,
+		Y: g.OffsetY + (float64(cell.Row)+0.5)*g.CellSize,
+	}
+}
+
+// Distance returns the number of cells moved between two cells.
+// Square grids use the 5e rule where diagonals count as one cell.
+func (g MapGrid) Distance(from, to GridCell) int {
+	if g.Type == GridTypeHex {
+		fq, fr := oddRToAxial(from)
+		tq, tr := oddRToAxial(to)
+		dq, dr := fq-tq, fr-tr
+		return (abs(dq) + abs(dr) + abs(dq+dr)) / 2
+	}
+	return max(abs(from.Col-to.Col), abs(from.Row-to.Row))
+}
+
+// DistanceFeet returns the distance between two cells in feet.
+func (g MapGrid) DistanceFeet(from, to GridCell) float64 {
+	return float64(g.Distance(from, to)) * g.FeetPerCell
+}
+
+// Hex helpers (see https://www.redblobgames.com/grids/hexagons/).
+
+// hexRadius is the distance from a hex's center to its corners.
+func (g MapGrid) hexRadius() float64 {
+	return g.CellSize / math.Sqrt(3)
+}
+
+func (g MapGrid) hexCenter(cell GridCell) (float64, float64) {
+	r := g.hexRadius()
+	x := g.CellSize * (float64(cell.Col) + 0.5*float64(cell.Row&1) + 0.5)
+	y := r * (1.5*float64(cell.Row) + 1)
+	return x, y
+}
+
+func (g MapGrid) hexCellAt(x, y float64) GridCell {
+	r := g.hexRadius()
+	// Shift so that cell (0, 0) is centred on the origin, then convert to fractional axial coordinates.
+	x -= g.CellSize / 2
+	y -= r
+	q := (math.Sqrt(3)/3*x - y/3) / r
+	s := (2.0 / 3 * y) / r
+	return axialToOddR(cubeRound(q, s))
+}
+
+func cubeRound(q, r float64) (int, int) {
+	s := -q - r
+	rq, rr, rs := math.Round(q), math.Round(r), math.Round(s)
+	dq, dr, ds := math.Abs(rq-q), math.Abs(rr-r), math.Abs(rs-s)
+	if dq > dr && dq > ds {
+		rq = -rr - rs
+	} else if dr > ds {
+		rr = -rq - rs
+	}
+	return int(rq), int(rr)
+}
+
+func axialToOddR(q, r int) GridCell {
+	return GridCell{Col: q + (r-(r&1))/2, Row: r}
+}
+
+func oddRToAxial(cell GridCell) (int, int) {
+	return cell.Col - (cell.Row-(cell.Row&1))/2, cell.Row
+}
+
+// countCenters returns how many of first, first+step, first+2*step... are less than length.
+func countCenters(length, first, step float64) int {
+	if length <= first {
+		return 0
+	}
+	return int(math.Ceil((length - first) / step))
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -19,4 +19,8 @@ type ImageEntry struct {
 	Description string `json:"description"`
 	Type        string `gorm:"not null;index" json:"type"`
 	FilePath    string `gorm:"not null;unique" json:"file_path"`
+	PageCount   int    `gorm:"not null;default:0" json:"page_count,omitempty"` // Only set for PDFs
+
+	// Grid calibration, only used for maps. Written through the grid endpoint, not by image updates.
+	Grid MapGrid `gorm:"embedded;embeddedPrefix:grid_" json:"grid"`
 }
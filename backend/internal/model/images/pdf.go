@@ -0,0 +1,43 @@
+package images
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PdfPage holds the extracted text of a single PDF page, for full-text search.
+type PdfPage struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	ImageID     uint      `gorm:"not null;uniqueIndex:idx_pdf_page" json:"image_id"`
+	Page        int       `gorm:"not null;uniqueIndex:idx_pdf_page" json:"page"` // Numbered from 1
+	Text        string    `json:"-"`
+	FileModTime time.Time `json:"-"` // Modification time of the PDF the text was extracted from
+}
+
+// PdfSearchResult is a page matching a search, with the matched terms marked in Snippet as **term**.
+type PdfSearchResult struct {
+	ImageID uint   `json:"image_id"`
+	Name    string `json:"name"`
+	Page    int    `json:"page"`
+	Snippet string `json:"snippet"`
+}
+
+// OutlineEntry is a node of a PDF's table of contents.
+type OutlineEntry struct {
+	Title    string          `json:"title"`
+	Page     int             `json:"page,omitempty"` // Numbered from 1; 0 when the entry links outside the document
+	URI      string          `json:"uri,omitempty"`
+	Children []*OutlineEntry `json:"children"`
+}
+
+// PdfBookmark is a DM-defined named jump target inside a PDF.
+type PdfBookmark struct {
+	gorm.Model
+
+	ImageID   uint    `gorm:"not null;index" json:"image_id"`
+	Name      string  `gorm:"not null" json:"name"`
+	Page      int     `gorm:"not null" json:"page"` // Numbered from 1
+	PositionY float64 `json:"position_y"`           // Scroll position within the page, as in PresetLayoutSlot
+	SortOrder int     `gorm:"not null;default:0" json:"sort_order"`
+}
@@ -0,0 +1,7 @@
+package images
+
+// Point is a position on a map in source-image pixels.
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
@@ -10,12 +10,29 @@ const (
 	Quad   LayoutType = "quad"
 )
 
+// SlotCount returns the number of image slots the layout provides, or 0 for an unknown layout.
+func (l LayoutType) SlotCount() int {
+	switch l {
+	case Single:
+		return 1
+	case Dual:
+		return 2
+	case Quad:
+		return 4
+	default:
+		return 0
+	}
+}
+
 // PresetLayout represents the main preset record.
 type PresetLayout struct {
 	gorm.Model
 
-	LayoutType LayoutType         `gorm:"not null" json:"layout_type"`
-	Slots      []PresetLayoutSlot `gorm:"foreignKey:PresetLayoutID" json:"slots"` // Defines the "has many" relationship
+	Name          string             `json:"name"`
+	ThumbnailPath string             `json:"thumbnail_path"`                          // Relative to 'public/', e.g. "images/dark_castle.jpg"
+	SortOrder     int                `gorm:"not null;default:0;index" json:"sort_order"` // Lower values are listed first
+	LayoutType    LayoutType         `gorm:"not null" json:"layout_type"`
+	Slots         []PresetLayoutSlot `gorm:"foreignKey:PresetLayoutID" json:"slots"` // Defines the "has many" relationship
 }
 
 // PresetLayoutSlot represents a single image within a preset layout.
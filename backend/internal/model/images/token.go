@@ -0,0 +1,42 @@
+package images
+
+import (
+	"dmd/backend/internal/model/combat"
+	"dmd/backend/internal/model/crawl"
+
+	"gorm.io/gorm"
+)
+
+// MapToken places a character template or a combatant on a map at a grid cell.
+// Col and Row are the token's top-left cell; larger creatures cover Cells x Cells cells.
+type MapToken struct {
+	gorm.Model
+
+	ImageID             uint   `gorm:"not null;index" json:"image_id"`
+	CharacterTemplateID *uint  `json:"character_template_id"` // Source of the token's name, size, photo and color
+	CombatantID         *uint  `json:"combatant_id"`          // Set when the token stands for a combatant
+	Label               string `json:"label"`                 // Overrides the template name, e.g. "Goblin 2"
+	Col                 int    `gorm:"not null" json:"col"`
+	Row                 int    `gorm:"not null" json:"row"`
+
+	// Cells is the token's footprint, derived from the template's size.
+	Cells int `gorm:"-" json:"cells"`
+
+	CharacterTemplate *crawl.CharacterTemplate `json:"character_template,omitempty"`
+	Combatant         *combat.Combatant        `json:"combatant,omitempty"`
+}
+
+// CreatureSizeCells returns how many cells across a creature of the given 5e size occupies.
+// Unknown and empty sizes are treated as Medium.
+func CreatureSizeCells(size string) int {
+	switch size {
+	case "Large":
+		return 2
+	case "Huge":
+		return 3
+	case "Gargantuan":
+		return 4
+	default:
+		return 1
+	}
+}
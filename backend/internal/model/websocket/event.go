@@ -2,7 +2,9 @@
 package websocket
 
 // Event is the structure for all outgoing WebSocket messages from the server.
+// Seq is set by the manager on events for more than one client; replies to a single client have none.
 type Event struct {
+    Seq     uint64 `json:"seq,omitempty"`
     Type    string `json:"type"`
     Payload any    `json:"payload"`
 }
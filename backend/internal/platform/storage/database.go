@@ -6,6 +6,7 @@ import (
 	"dmd/backend/internal/model/character"
 	"dmd/backend/internal/model/combat"
 	"dmd/backend/internal/model/crawl"
+	"dmd/backend/internal/model/display"
 	"dmd/backend/internal/model/gameplay"
 	"dmd/backend/internal/model/images"
 	"log/slog"
@@ -15,10 +16,11 @@ import (
 	"gorm.io/gorm"
 )
 
-// NewConnection creates a new database connection and configures its pool.
+// NewConnection creates a new database connection and configures its pool. Unique index violations come back
+// as gorm.ErrDuplicatedKey.
 func NewConnection(log *slog.Logger, dbPath string) (*gorm.DB, error) {
 
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
@@ -45,15 +47,25 @@ func AutoMigrate(db *gorm.DB) error {
 		&character.Ability{},
 		&combat.Combat{},
 		&combat.Combatant{},
+		&combat.HistoryEntry{},
 		&gameplay.Spell{},
 		&gameplay.Item{},
 		&audio.Track{},
 		&audio.Playlist{},
 		&audio.PlaylistTrack{},
 		&audio.SpotifyToken{},
+		&audio.Soundboard{},
+		&audio.SoundboardPad{},
 		&images.ImageEntry{},
 		&images.PresetLayout{},
 		&images.PresetLayoutSlot{},
+		&images.FogOperation{},
+		&images.MapToken{},
+		&images.Annotation{},
+		&images.PdfPage{},
+		&images.PdfBookmark{},
+		&display.Scene{},
+		&display.SceneCue{},
 		&crawl.CharacterTemplate{},
 	); err != nil {
 		return err
@@ -0,0 +1,90 @@
+// File: /internal/platform/storage/annotation_repo.go
+package annotation_repo
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos"
+
+	"gorm.io/gorm"
+)
+
+type annotationRepo struct {
+	db *gorm.DB
+}
+
+func NewAnnotationRepository(db *gorm.DB) repos.AnnotationRepository {
+	return &annotationRepo{db: db}
+}
+
+func (r *annotationRepo) GetAnnotationByID(id uint) (*images.Annotation, error) {
+	var annotation images.Annotation
+	if err := r.db.First(&annotation, id).Error; err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+// GetAnnotations returns an image's annotations in drawing order.
+func (r *annotationRepo) GetAnnotations(imageID uint, filters filters.AnnotationFilters) ([]*images.Annotation, error) {
+	var annotations []*images.Annotation
+	query := r.db.Where("image_id = ?", imageID)
+
+	if filters.Visibility != "" {
+		query = query.Where("visibility = ?", filters.Visibility)
+	}
+
+	if err := query.Order("id asc").Find(&annotations).Error; err != nil {
+		return nil, err
+	}
+	return annotations, nil
+}
+
+func (r *annotationRepo) CreateAnnotation(annotation *images.Annotation) error {
+	return r.db.Create(annotation).Error
+}
+
+// UpdateAnnotation replaces an annotation's drawing. The image it belongs to cannot change.
+func (r *annotationRepo) UpdateAnnotation(annotation *images.Annotation) error {
+	res := r.db.Model(annotation).
+		Select("Kind", "Visibility", "Points", "Text", "SizeFeet", "Color", "StrokeWidth").
+		Updates(annotation)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AppendAnnotationPoints extends an annotation's points, e.g. a stroke that is still being drawn.
+func (r *annotationRepo) AppendAnnotationPoints(id uint, points []images.Point) (*images.Annotation, error) {
+	var annotation images.Annotation
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&annotation, id).Error; err != nil {
+			return err
+		}
+		annotation.Points = append(annotation.Points, points...)
+		return tx.Model(&annotation).Update("points", annotation.Points).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+func (r *annotationRepo) DeleteAnnotation(id uint) error {
+	res := r.db.Delete(&images.Annotation{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *annotationRepo) DeleteAnnotationsByImageID(imageID uint) error {
+	return r.db.Where("image_id = ?", imageID).Delete(&images.Annotation{}).Error
+}
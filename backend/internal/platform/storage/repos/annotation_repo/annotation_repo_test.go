@@ -0,0 +1,52 @@
+package annotation_repo
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/common"
+	"testing"
+)
+
+func TestAnnotations(t *testing.T) {
+	db := common.SetupTestDB(t, &images.Annotation{})
+	repo := NewAnnotationRepository(db)
+
+	stroke := &images.Annotation{ImageID: 1, Kind: images.AnnotationStroke, Visibility: images.VisibilityDM, Points: []images.Point{{X: 0, Y: 0}}}
+	label := &images.Annotation{ImageID: 1, Kind: images.AnnotationText, Visibility: images.VisibilityShared, Text: "Trap!", Points: []images.Point{{X: 5, Y: 5}}}
+	for _, a := range []*images.Annotation{stroke, label} {
+		if err := repo.CreateAnnotation(a); err != nil {
+			t.Fatalf("CreateAnnotation failed: %v", err)
+		}
+	}
+
+	t.Run("Append_Points", func(t *testing.T) {
+		updated, err := repo.AppendAnnotationPoints(stroke.ID, []images.Point{{X: 1, Y: 1}, {X: 2, Y: 2}})
+		if err != nil {
+			t.Fatalf("AppendAnnotationPoints failed: %v", err)
+		}
+		reloaded, _ := repo.GetAnnotationByID(stroke.ID)
+		if len(updated.Points) != 3 || len(reloaded.Points) != 3 {
+			t.Errorf("Expected 3 stored points, got %d", len(reloaded.Points))
+		}
+	})
+
+	t.Run("Filter_By_Visibility", func(t *testing.T) {
+		shared, err := repo.GetAnnotations(1, filters.AnnotationFilters{Visibility: images.VisibilityShared})
+		if err != nil {
+			t.Fatalf("GetAnnotations failed: %v", err)
+		}
+		if len(shared) != 1 || shared[0].ID != label.ID {
+			t.Errorf("Expected only the shared label, got %d annotations", len(shared))
+		}
+	})
+
+	t.Run("Delete_By_Image", func(t *testing.T) {
+		if err := repo.DeleteAnnotationsByImageID(1); err != nil {
+			t.Fatalf("DeleteAnnotationsByImageID failed: %v", err)
+		}
+		all, _ := repo.GetAnnotations(1, filters.AnnotationFilters{})
+		if len(all) != 0 {
+			t.Errorf("Expected no annotations, got %d", len(all))
+		}
+	})
+}
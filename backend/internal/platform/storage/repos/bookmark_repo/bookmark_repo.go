@@ -0,0 +1,64 @@
+// File: /internal/platform/storage/bookmark_repo.go
+package bookmark_repo
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos"
+
+	"gorm.io/gorm"
+)
+
+type bookmarkRepo struct {
+	db *gorm.DB
+}
+
+func NewPdfBookmarkRepository(db *gorm.DB) repos.PdfBookmarkRepository {
+	return &bookmarkRepo{db: db}
+}
+
+func (r *bookmarkRepo) GetBookmarkByID(id uint) (*images.PdfBookmark, error) {
+	var bookmark images.PdfBookmark
+	if err := r.db.First(&bookmark, id).Error; err != nil {
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+// GetBookmarks returns a PDF's bookmarks by sort order, then by page.
+func (r *bookmarkRepo) GetBookmarks(imageID uint) ([]*images.PdfBookmark, error) {
+	var bookmarks []*images.PdfBookmark
+	err := r.db.Where("image_id = ?", imageID).
+		Order("sort_order asc, page asc, position_y asc").
+		Find(&bookmarks).Error
+	if err != nil {
+		return nil, err
+	}
+	return bookmarks, nil
+}
+
+func (r *bookmarkRepo) CreateBookmark(bookmark *images.PdfBookmark) error {
+	return r.db.Create(bookmark).Error
+}
+
+// UpdateBookmark changes a bookmark's name and target. The PDF it belongs to cannot change.
+func (r *bookmarkRepo) UpdateBookmark(bookmark *images.PdfBookmark) error {
+	res := r.db.Model(bookmark).Select("Name", "Page", "PositionY", "SortOrder").Updates(bookmark)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *bookmarkRepo) DeleteBookmark(id uint) error {
+	res := r.db.Delete(&images.PdfBookmark{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
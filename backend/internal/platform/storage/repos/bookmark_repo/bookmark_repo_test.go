@@ -0,0 +1,49 @@
+package bookmark_repo
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/common"
+	"testing"
+)
+
+func TestPdfBookmarks(t *testing.T) {
+	db := common.SetupTestDB(t, &images.PdfBookmark{})
+	repo := NewPdfBookmarkRepository(db)
+
+	late := &images.PdfBookmark{ImageID: 1, Name: "Appendix", Page: 90}
+	early := &images.PdfBookmark{ImageID: 1, Name: "Sunken Temple", Page: 12}
+	other := &images.PdfBookmark{ImageID: 2, Name: "Elsewhere", Page: 1}
+	for _, b := range []*images.PdfBookmark{late, early, other} {
+		if err := repo.CreateBookmark(b); err != nil {
+			t.Fatalf("CreateBookmark failed: %v", err)
+		}
+	}
+
+	t.Run("Ordered_By_Page", func(t *testing.T) {
+		bookmarks, err := repo.GetBookmarks(1)
+		if err != nil {
+			t.Fatalf("GetBookmarks failed: %v", err)
+		}
+		if len(bookmarks) != 2 || bookmarks[0].ID != early.ID {
+			t.Errorf("Expected the two bookmarks of PDF 1 by page, got %+v", bookmarks)
+		}
+	})
+
+	t.Run("Update_Keeps_Pdf", func(t *testing.T) {
+		update := &images.PdfBookmark{Name: "Appendix B", Page: 91}
+		update.ID = late.ID
+		if err := repo.UpdateBookmark(update); err != nil {
+			t.Fatalf("UpdateBookmark failed: %v", err)
+		}
+		got, _ := repo.GetBookmarkByID(late.ID)
+		if got.Name != "Appendix B" || got.Page != 91 || got.ImageID != 1 {
+			t.Errorf("Unexpected bookmark after update: %+v", got)
+		}
+	})
+
+	t.Run("Delete_Missing", func(t *testing.T) {
+		if err := repo.DeleteBookmark(9999); err == nil {
+			t.Error("Expected error for missing bookmark, got nil")
+		}
+	})
+}
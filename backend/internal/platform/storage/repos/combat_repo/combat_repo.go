@@ -29,10 +29,10 @@ func (r *combatRepo) CreateCombat(combat *combat.Combat) error {
 }
 
 // GetActiveCombat finds the first combat marked as active.
-// It uses Preload to automatically fetch the associated combatants.
+// It uses Preload to automatically fetch the associated combatants and history.
 func (r *combatRepo) GetActiveCombat() (*combat.Combat, error) {
 	var activeCombat combat.Combat
-	err := r.db.Preload("Combatants").Where("is_active = ?", true).First(&activeCombat).Error
+	err := preloadCombat(r.db).Where("is_active = ?", true).First(&activeCombat).Error
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +41,7 @@ func (r *combatRepo) GetActiveCombat() (*combat.Combat, error) {
 
 func (r *combatRepo) GetCombatByID(id uint) (*combat.Combat, error) {
 	var combat combat.Combat
-	err := r.db.Preload("Combatants").First(&combat, id).Error
+	err := preloadCombat(r.db).First(&combat, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -51,3 +51,23 @@ func (r *combatRepo) GetCombatByID(id uint) (*combat.Combat, error) {
 func (r *combatRepo) UpdateCombatant(combatant *combat.Combatant) error {
 	return r.db.Save(combatant).Error
 }
+
+func (r *combatRepo) GetCombatantByID(id uint) (*combat.Combatant, error) {
+	var combatant combat.Combatant
+	if err := r.db.First(&combatant, id).Error; err != nil {
+		return nil, err
+	}
+	return &combatant, nil
+}
+
+func (r *combatRepo) AddHistoryEntry(entry *combat.HistoryEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// Helpers
+
+func preloadCombat(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Combatants").
+		Preload("History", func(db *gorm.DB) *gorm.DB { return db.Order("id asc") })
+}
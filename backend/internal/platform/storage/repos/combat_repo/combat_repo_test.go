@@ -8,7 +8,7 @@ import (
 
 func TestCreateCombat_Success(t *testing.T) {
 	// Setup a clean DB specifically for this test.
-	db := common.SetupTestDB(t, &combat.Combat{}, &combat.Combatant{})
+	db := common.SetupTestDB(t, &combat.Combat{}, &combat.Combatant{}, &combat.HistoryEntry{})
 	repo := NewCombatRepository(db)
 
 	combatToCreate := &combat.Combat{
@@ -35,7 +35,7 @@ func TestCreateCombat_Success(t *testing.T) {
 
 func TestCreateCombat_Rollback(t *testing.T) {
 	// Setup a separate, clean DB for this test.
-	db := common.SetupTestDB(t, &combat.Combat{}, &combat.Combatant{})
+	db := common.SetupTestDB(t, &combat.Combat{}, &combat.Combatant{}, &combat.HistoryEntry{})
 	repo := NewCombatRepository(db)
 
 	// This combat is invalid because it contains duplicate participants.
@@ -7,7 +7,7 @@ import (
 )
 
 func SetupTestDB(t *testing.T, tables ...any) *gorm.DB {
-    db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+    db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{TranslateError: true})
     if err != nil {
         t.Fatalf("failed to connect to in-memory database: %v", err)
     }
@@ -0,0 +1,71 @@
+// File: /internal/platform/storage/fog_repo.go
+package fog_repo
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos"
+
+	"gorm.io/gorm"
+)
+
+type fogRepo struct {
+	db *gorm.DB
+}
+
+func NewFogRepository(db *gorm.DB) repos.FogRepository {
+	return &fogRepo{db: db}
+}
+
+// AppendFogOperation stores the operation as the map's next version.
+func (r *fogRepo) AppendFogOperation(op *images.FogOperation) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		version, err := fogVersion(tx, op.ImageID)
+		if err != nil {
+			return err
+		}
+		op.Version = version + 1
+		return tx.Create(op).Error
+	})
+}
+
+// GetFogOperations returns the operations newer than sinceVersion, in order.
+// Operations older than the latest reset are never returned, since they no longer affect the mask.
+func (r *fogRepo) GetFogOperations(imageID uint, sinceVersion uint) ([]*images.FogOperation, error) {
+	var lastReset uint
+	err := r.db.Model(&images.FogOperation{}).
+		Where("image_id = ? AND op = ?", imageID, images.FogOpReset).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&lastReset).Error
+	if err != nil {
+		return nil, err
+	}
+
+	from := sinceVersion + 1
+	if lastReset > from {
+		from = lastReset
+	}
+
+	var ops []*images.FogOperation
+	err = r.db.Where("image_id = ? AND version >= ?", imageID, from).
+		Order("version asc").
+		Find(&ops).Error
+	if err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+func (r *fogRepo) GetFogVersion(imageID uint) (uint, error) {
+	return fogVersion(r.db, imageID)
+}
+
+// Helpers
+
+func fogVersion(db *gorm.DB, imageID uint) (uint, error) {
+	var version uint
+	err := db.Model(&images.FogOperation{}).
+		Where("image_id = ?", imageID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&version).Error
+	return version, err
+}
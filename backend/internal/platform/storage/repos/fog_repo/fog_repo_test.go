@@ -0,0 +1,56 @@
+package fog_repo
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/common"
+	"testing"
+)
+
+func TestFogOperationVersioning(t *testing.T) {
+	db := common.SetupTestDB(t, &images.FogOperation{})
+	repo := NewFogRepository(db)
+
+	rect := []images.Point{{X: 0, Y: 0}, {X: 100, Y: 100}}
+	ops := []*images.FogOperation{
+		{ImageID: 1, Op: images.FogOpReveal, Shape: images.FogShapeRect, Points: rect},
+		{ImageID: 2, Op: images.FogOpReveal, Shape: images.FogShapeRect, Points: rect}, // Other map, own version sequence
+		{ImageID: 1, Op: images.FogOpHide, Shape: images.FogShapeRect, Points: rect},
+		{ImageID: 1, Op: images.FogOpReset},
+		{ImageID: 1, Op: images.FogOpReveal, Shape: images.FogShapeRect, Points: rect},
+	}
+	for _, op := range ops {
+		if err := repo.AppendFogOperation(op); err != nil {
+			t.Fatalf("AppendFogOperation failed unexpectedly: %v", err)
+		}
+	}
+
+	if ops[1].Version != 1 || ops[4].Version != 4 {
+		t.Errorf("expected per-map versions 1 and 4, got %d and %d", ops[1].Version, ops[4].Version)
+	}
+
+	testCases := []struct {
+		name          string
+		since         uint
+		expectedFirst uint
+		expectedCount int
+	}{
+		{name: "Full_Mask_Starts_At_Reset", since: 0, expectedFirst: 3, expectedCount: 2},
+		{name: "Incremental_After_Reset", since: 3, expectedFirst: 4, expectedCount: 1},
+		{name: "Up_To_Date", since: 4, expectedCount: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := repo.GetFogOperations(1, tc.since)
+			if err != nil {
+				t.Fatalf("GetFogOperations failed unexpectedly: %v", err)
+			}
+			if len(result) != tc.expectedCount {
+				t.Fatalf("expected %d operations, got %d", tc.expectedCount, len(result))
+			}
+			if tc.expectedCount > 0 && result[0].Version != tc.expectedFirst {
+				t.Errorf("expected first version %d, got %d", tc.expectedFirst, result[0].Version)
+			}
+		})
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// gridColumns are the ImageEntry columns holding the embedded MapGrid.
+var gridColumns = []string{"grid_type", "grid_cell_size", "grid_offset_x", "grid_offset_y", "grid_feet_per_cell", "grid_columns", "grid_rows"}
+
 type imagesRepo struct {
 	db *gorm.DB
 }
@@ -77,7 +80,20 @@ func (r *imagesRepo) CreateImageEntry(asset *images.ImageEntry) error {
 }
 
 func (r *imagesRepo) UpdateImageEntry(asset *images.ImageEntry) error {
-	return r.db.Save(asset).Error
+	return r.db.Omit(gridColumns...).Save(asset).Error
+}
+
+func (r *imagesRepo) UpdateImageGrid(id uint, grid images.MapGrid) error {
+	res := r.db.Model(&images.ImageEntry{}).Where("id = ?", id).
+		Select(gridColumns).
+		Updates(&images.ImageEntry{Grid: grid})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
 }
 
 func (r *imagesRepo) DeleteImage(id uint) error {
@@ -112,9 +128,19 @@ func (r *imagesRepo) CreatePreset(preset *images.PresetLayout) error {
 	return nil
 }
 
+func (r *imagesRepo) GetPresetByID(id uint) (*images.PresetLayout, error) {
+	var preset images.PresetLayout
+	if err := r.db.Preload("Slots", orderBySlotID).Preload("Slots.Image").First(&preset, id).Error; err != nil {
+		return nil, err
+	}
+	return &preset, nil
+}
+
 func (r *imagesRepo) GetAllPresets() ([]*images.PresetLayout, error) {
 	var presets []*images.PresetLayout
-	err := r.db.Preload("Slots.Image").Order("created_at desc").Find(&presets).Error
+	err := r.db.Preload("Slots", orderBySlotID).Preload("Slots.Image").
+		Order("sort_order asc").Order("created_at desc").
+		Find(&presets).Error
 	if err != nil {
 		return nil, err
 	}
@@ -122,6 +148,110 @@ func (r *imagesRepo) GetAllPresets() ([]*images.PresetLayout, error) {
 	return presets, nil
 }
 
+// UpdatePreset replaces the preset's metadata and its whole slot set in a single transaction.
+func (r *imagesRepo) UpdatePreset(preset *images.PresetLayout) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing images.PresetLayout
+		if err := tx.First(&existing, preset.ID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&existing).
+			Select("Name", "ThumbnailPath", "SortOrder", "LayoutType").
+			Updates(preset).Error
+		if err != nil {
+			return err
+		}
+
+		// Slots are owned by the preset, so old ones are removed permanently.
+		if err := tx.Unscoped().Where("preset_layout_id = ?", preset.ID).Delete(&images.PresetLayoutSlot{}).Error; err != nil {
+			return err
+		}
+		return createPresetSlots(tx, preset.ID, preset.Slots)
+	})
+	if err != nil {
+		return err
+	}
+
+	updated, err := r.GetPresetByID(preset.ID)
+	if err != nil {
+		return err
+	}
+	*preset = *updated
+	return nil
+}
+
+// DuplicatePreset copies a preset and its slots under a new name.
+// An empty name defaults to the original name with a " (copy)" suffix.
+func (r *imagesRepo) DuplicatePreset(id uint, name string) (*images.PresetLayout, error) {
+	var copyID uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var original images.PresetLayout
+		if err := tx.Preload("Slots").First(&original, id).Error; err != nil {
+			return err
+		}
+
+		if name == "" {
+			name = original.Name + " (copy)"
+		}
+		duplicate := images.PresetLayout{
+			Name:          name,
+			ThumbnailPath: original.ThumbnailPath,
+			SortOrder:     original.SortOrder,
+			LayoutType:    original.LayoutType,
+		}
+		if err := tx.Omit("Slots").Create(&duplicate).Error; err != nil {
+			return err
+		}
+		copyID = duplicate.ID
+		return createPresetSlots(tx, duplicate.ID, original.Slots)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetPresetByID(copyID)
+}
+
+// ReorderPresets sets each preset's sort order to its position in presetIDs.
+func (r *imagesRepo) ReorderPresets(presetIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range presetIDs {
+			res := tx.Model(&images.PresetLayout{}).Where("id = ?", id).Update("sort_order", i)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+		}
+		return nil
+	})
+}
+
 func (r *imagesRepo) DeletePreset(id uint) error {
 	return r.db.Delete(&images.PresetLayout{}, id).Error
 }
+
+// Helpers
+
+// createPresetSlots inserts fresh copies of the given slots under presetID.
+func createPresetSlots(tx *gorm.DB, presetID uint, slots []images.PresetLayoutSlot) error {
+	for _, slot := range slots {
+		newSlot := images.PresetLayoutSlot{
+			PresetLayoutID: presetID,
+			ImageID:        slot.ImageID,
+			SlotID:         slot.SlotID,
+			Zoom:           slot.Zoom,
+			Page:           slot.Page,
+			PositionY:      slot.PositionY,
+		}
+		if err := tx.Omit("Image").Create(&newSlot).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func orderBySlotID(db *gorm.DB) *gorm.DB {
+	return db.Order("slot_id asc")
+}
@@ -63,3 +63,102 @@ func TestBulkCreateMediaAssets(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateAndDuplicatePreset(t *testing.T) {
+	db := common.SetupTestDB(t, &images.ImageEntry{}, &images.PresetLayout{}, &images.PresetLayoutSlot{})
+	repo := NewImagesRepository(db)
+
+	preset := &images.PresetLayout{
+		Name:       "Tavern",
+		LayoutType: images.Dual,
+		Slots: []images.PresetLayoutSlot{
+			{ImageID: 1, SlotID: 0, Zoom: 1},
+			{ImageID: 2, SlotID: 1, Zoom: 1},
+		},
+	}
+	if err := repo.CreatePreset(preset); err != nil {
+		t.Fatalf("CreatePreset failed unexpectedly: %v", err)
+	}
+
+	t.Run("Update_Replaces_Slots", func(t *testing.T) {
+		update := &images.PresetLayout{
+			Name:       "Tavern at Night",
+			LayoutType: images.Single,
+			Slots:      []images.PresetLayoutSlot{{ImageID: 2, SlotID: 0, Zoom: 2.5}},
+		}
+		update.ID = preset.ID
+		if err := repo.UpdatePreset(update); err != nil {
+			t.Fatalf("UpdatePreset failed unexpectedly: %v", err)
+		}
+
+		var slotCount int64
+		db.Unscoped().Model(&images.PresetLayoutSlot{}).Where("preset_layout_id = ?", preset.ID).Count(&slotCount)
+		if slotCount != 1 {
+			t.Errorf("expected 1 slot after update, got %d", slotCount)
+		}
+		if update.Name != "Tavern at Night" || update.Slots[0].Zoom != 2.5 {
+			t.Errorf("expected updated preset to be reloaded, got %+v", update)
+		}
+	})
+
+	t.Run("Update_Missing_Preset", func(t *testing.T) {
+		update := &images.PresetLayout{LayoutType: images.Single}
+		update.ID = 999
+		if err := repo.UpdatePreset(update); err == nil {
+			t.Fatal("UpdatePreset was expected to fail but did not")
+		}
+	})
+
+	t.Run("Duplicate_Copies_Slots", func(t *testing.T) {
+		duplicate, err := repo.DuplicatePreset(preset.ID, "")
+		if err != nil {
+			t.Fatalf("DuplicatePreset failed unexpectedly: %v", err)
+		}
+		if duplicate.ID == preset.ID || duplicate.Name != "Tavern at Night (copy)" {
+			t.Errorf("unexpected duplicate: id %d name %q", duplicate.ID, duplicate.Name)
+		}
+		if len(duplicate.Slots) != 1 || duplicate.Slots[0].Zoom != 2.5 {
+			t.Errorf("expected duplicate to copy the slot, got %+v", duplicate.Slots)
+		}
+	})
+}
+
+func TestUpdateImageGrid(t *testing.T) {
+	db := common.SetupTestDB(t, &images.ImageEntry{})
+	repo := NewImagesRepository(db)
+
+	dungeon := &images.ImageEntry{Name: "Dungeon", Type: images.ImageTypeMap, FilePath: "maps/dungeon.png"}
+	if err := repo.CreateImageEntry(dungeon); err != nil {
+		t.Fatalf("Failed to create image: %v", err)
+	}
+
+	grid := images.MapGrid{Type: images.GridTypeSquare, CellSize: 70, OffsetX: 12, FeetPerCell: 5, Columns: 24, Rows: 16}
+	if err := repo.UpdateImageGrid(dungeon.ID, grid); err != nil {
+		t.Fatalf("UpdateImageGrid failed: %v", err)
+	}
+
+	t.Run("Update_Entry_Keeps_Grid", func(t *testing.T) {
+		// A rescan or rename carries no grid; it must not wipe the calibration.
+		dungeon.Name = "Dungeon Level 1"
+		if err := repo.UpdateImageEntry(dungeon); err != nil {
+			t.Fatalf("UpdateImageEntry failed: %v", err)
+		}
+
+		got, err := repo.GetImageByID(dungeon.ID)
+		if err != nil {
+			t.Fatalf("GetImageByID failed: %v", err)
+		}
+		if got.Name != "Dungeon Level 1" {
+			t.Errorf("Expected name to be updated, got %q", got.Name)
+		}
+		if got.Grid != grid {
+			t.Errorf("Expected grid %+v, got %+v", grid, got.Grid)
+		}
+	})
+
+	t.Run("Missing_Image", func(t *testing.T) {
+		if err := repo.UpdateImageGrid(9999, grid); err == nil {
+			t.Error("Expected error for missing image, got nil")
+		}
+	})
+}
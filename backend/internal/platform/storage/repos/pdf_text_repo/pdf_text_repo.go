@@ -0,0 +1,214 @@
+// File: /internal/platform/storage/pdf_text_repo.go
+package pdf_text_repo
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// searchTable is an FTS5 index over pdf_pages, kept in sync by searchTriggers. It only works when SQLite
+// was built with FTS5 (go build -tags sqlite_fts5); otherwise searches fall back to LIKE.
+const searchTable = "pdf_page_search"
+
+var searchTriggers = []string{"pdf_pages_ai", "pdf_pages_ad", "pdf_pages_au"}
+
+// snippetRadius is how many characters of context the LIKE fallback keeps around a match.
+const snippetRadius = 60
+
+type pdfTextRepo struct {
+	db     *gorm.DB
+	hasFTS bool
+}
+
+func NewPdfTextRepository(db *gorm.DB) repos.PdfTextRepository {
+	return &pdfTextRepo{db: db, hasFTS: ensureSearchIndex(db)}
+}
+
+// ReplacePdfPages stores the text of every page of a PDF, replacing what was stored before.
+// fileModTime is the modification time of the file the text was extracted from.
+func (r *pdfTextRepo) ReplacePdfPages(imageID uint, pages []string, fileModTime time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("image_id = ?", imageID).Delete(&images.PdfPage{}).Error; err != nil {
+			return err
+		}
+		if len(pages) == 0 {
+			return nil
+		}
+		rows := make([]images.PdfPage, len(pages))
+		for i, text := range pages {
+			rows[i] = images.PdfPage{ImageID: imageID, Page: i + 1, Text: text, FileModTime: fileModTime}
+		}
+		return tx.CreateInBatches(rows, 100).Error
+	})
+}
+
+func (r *pdfTextRepo) DeletePdfPages(imageID uint) error {
+	return r.db.Where("image_id = ?", imageID).Delete(&images.PdfPage{}).Error
+}
+
+func (r *pdfTextRepo) GetPdfPagesModTime(imageID uint) (*time.Time, error) {
+	var pages []images.PdfPage
+	err := r.db.Select("file_mod_time").Where("image_id = ?", imageID).Limit(1).Find(&pages).Error
+	if err != nil || len(pages) == 0 {
+		return nil, err
+	}
+	return &pages[0].FileModTime, nil
+}
+
+// HasFullTextIndex reports whether searches use the FTS5 index rather than LIKE.
+func (r *pdfTextRepo) HasFullTextIndex() bool {
+	return r.hasFTS
+}
+
+// SearchPdfPages returns the pages containing every term in query, best matches first.
+func (r *pdfTextRepo) SearchPdfPages(query string, limit int) ([]*images.PdfSearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return []*images.PdfSearchResult{}, nil
+	}
+	if r.hasFTS {
+		return r.searchFTS(terms, limit)
+	}
+	return r.searchLike(terms, limit)
+}
+
+// Helpers
+
+func (r *pdfTextRepo) searchFTS(terms []string, limit int) ([]*images.PdfSearchResult, error) {
+	// Quote every term so user input is never parsed as FTS5 query syntax.
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+
+	results := []*images.PdfSearchResult{}
+	err := r.db.Raw(`
+		SELECT p.image_id, e.name, p.page,
+		       snippet(`+searchTable+`, 0, '**', '**', '…', 16) AS snippet
+		FROM `+searchTable+`
+		JOIN pdf_pages p ON p.id = `+searchTable+`.rowid
+		JOIN image_entries e ON e.id = p.image_id AND e.deleted_at IS NULL
+		WHERE `+searchTable+` MATCH ?
+		ORDER BY rank
+		LIMIT ?`, strings.Join(quoted, " "), limit).
+		Scan(&results).Error
+	return results, err
+}
+
+func (r *pdfTextRepo) searchLike(terms []string, limit int) ([]*images.PdfSearchResult, error) {
+	query := r.db.Table("pdf_pages p").
+		Select("p.image_id, e.name, p.page, p.text").
+		Joins("JOIN image_entries e ON e.id = p.image_id AND e.deleted_at IS NULL")
+	for _, term := range terms {
+		query = query.Where("p.text LIKE ?", "%"+term+"%")
+	}
+
+	var rows []struct {
+		ImageID uint
+		Name    string
+		Page    int
+		Text    string
+	}
+	if err := query.Order("p.image_id, p.page").Limit(limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]*images.PdfSearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = &images.PdfSearchResult{
+			ImageID: row.ImageID,
+			Name:    row.Name,
+			Page:    row.Page,
+			Snippet: likeSnippet(row.Text, terms[0]),
+		}
+	}
+	return results, nil
+}
+
+// likeSnippet cuts the text around the first match of term and marks it like the FTS5 snippet does.
+func likeSnippet(text, term string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	idx := strings.Index(strings.ToLower(text), strings.ToLower(term))
+	if idx < 0 {
+		return ""
+	}
+	end := idx + len(term)
+
+	start := max(0, idx-snippetRadius)
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	stop := min(len(text), end+snippetRadius)
+	for stop < len(text) && !utf8.RuneStart(text[stop]) {
+		stop++
+	}
+
+	snippet := text[start:idx] + "**" + text[idx:end] + "**" + text[end:stop]
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if stop < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// ensureSearchIndex creates the FTS5 index and the triggers that keep it in sync with pdf_pages, rebuilding
+// the index when any of them was missing. It reports false when this SQLite build has no FTS5; the triggers of
+// an index created by a build that had it are then dropped, as they would make every write to pdf_pages fail,
+// and the next build with FTS5 rebuilds the index.
+func ensureSearchIndex(db *gorm.DB) bool {
+	if !hasFTS5(db) {
+		for _, trigger := range searchTriggers {
+			db.Exec("DROP TRIGGER IF EXISTS " + trigger)
+		}
+		return false
+	}
+
+	var existing int64
+	names := append([]string{searchTable}, searchTriggers...)
+	if err := db.Raw("SELECT count(*) FROM sqlite_master WHERE name IN ?", names).Scan(&existing).Error; err != nil {
+		return false
+	}
+	if existing == int64(len(names)) {
+		return true
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`CREATE VIRTUAL TABLE IF NOT EXISTS ` + searchTable + ` USING fts5(text, content='pdf_pages', content_rowid='id')`,
+			`CREATE TRIGGER IF NOT EXISTS pdf_pages_ai AFTER INSERT ON pdf_pages BEGIN
+				INSERT INTO ` + searchTable + `(rowid, text) VALUES (new.id, new.text);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS pdf_pages_ad AFTER DELETE ON pdf_pages BEGIN
+				INSERT INTO ` + searchTable + `(` + searchTable + `, rowid, text) VALUES ('delete', old.id, old.text);
+			END`,
+			`CREATE TRIGGER IF NOT EXISTS pdf_pages_au AFTER UPDATE ON pdf_pages BEGIN
+				INSERT INTO ` + searchTable + `(` + searchTable + `, rowid, text) VALUES ('delete', old.id, old.text);
+				INSERT INTO ` + searchTable + `(rowid, text) VALUES (new.id, new.text);
+			END`,
+			// Index pages stored before the index existed, or while its triggers were missing.
+			`INSERT INTO ` + searchTable + `(` + searchTable + `) VALUES ('rebuild')`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err == nil
+}
+
+// hasFTS5 checks that the fts5 module is actually available by creating a throwaway table with it.
+func hasFTS5(db *gorm.DB) bool {
+	if err := db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS temp.fts5_probe USING fts5(text)").Error; err != nil {
+		return false
+	}
+	db.Exec("DROP TABLE temp.fts5_probe")
+	return true
+}
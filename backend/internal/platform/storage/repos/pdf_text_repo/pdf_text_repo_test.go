@@ -0,0 +1,86 @@
+package pdf_text_repo
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/common"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchPdfPages(t *testing.T) {
+	db := common.SetupTestDB(t, &images.ImageEntry{}, &images.PdfPage{})
+	repo := NewPdfTextRepository(db)
+
+	book := images.ImageEntry{Name: "Sunless Citadel", Type: images.ImageTypePDF, FilePath: "pdf/citadel.pdf"}
+	db.Create(&book)
+
+	modTime := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.Local)
+	err := repo.ReplacePdfPages(book.ID, []string{
+		"Chapter 1. The ravine and the old fortress.",
+		"Area 12: The Dragon Shrine. A white dragon wyrmling rests here.",
+		"Appendix: goblin tactics.",
+	}, modTime)
+	if err != nil {
+		t.Fatalf("ReplacePdfPages failed: %v", err)
+	}
+
+	t.Run("All_Terms_Must_Match", func(t *testing.T) {
+		results, err := repo.SearchPdfPages("dragon shrine", 10)
+		if err != nil {
+			t.Fatalf("SearchPdfPages failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Page != 2 || results[0].Name != book.Name {
+			t.Fatalf("Expected only page 2, got %+v", results)
+		}
+		if !strings.Contains(strings.ToLower(results[0].Snippet), "**dragon**") {
+			t.Errorf("Expected the match to be marked in the snippet, got %q", results[0].Snippet)
+		}
+	})
+
+	t.Run("Query_Syntax_Is_Literal", func(t *testing.T) {
+		if _, err := repo.SearchPdfPages(`goblin" OR "`, 10); err != nil {
+			t.Errorf("Expected a quoted query to be searched literally, got error: %v", err)
+		}
+	})
+
+	t.Run("File_Mod_Time", func(t *testing.T) {
+		stored, err := repo.GetPdfPagesModTime(book.ID)
+		if err != nil || stored == nil || !stored.Equal(modTime) {
+			t.Errorf("Expected the file's mod time %v, got %v (%v)", modTime, stored, err)
+		}
+		if stored, _ := repo.GetPdfPagesModTime(9999); stored != nil {
+			t.Errorf("Expected no mod time for a PDF without text, got %v", stored)
+		}
+	})
+
+	t.Run("Replace_Drops_Old_Pages", func(t *testing.T) {
+		if err := repo.ReplacePdfPages(book.ID, []string{"Errata only."}, modTime); err != nil {
+			t.Fatalf("ReplacePdfPages failed: %v", err)
+		}
+		results, _ := repo.SearchPdfPages("dragon", 10)
+		if len(results) != 0 {
+			t.Errorf("Expected no matches after replacing the pages, got %d", len(results))
+		}
+	})
+}
+
+// A database last opened by a build with FTS5 has triggers that write to the index. Every build must still be
+// able to write pages to it: one without FTS5 drops them, one with FTS5 completes and rebuilds the index.
+func TestSearchIndexFromOtherBuild(t *testing.T) {
+	db := common.SetupTestDB(t, &images.ImageEntry{}, &images.PdfPage{})
+	// Only one trigger is left, and the index itself is gone or cannot be opened.
+	db.Exec("DROP TABLE IF EXISTS " + searchTable)
+	db.Exec("CREATE TRIGGER pdf_pages_ai AFTER INSERT ON pdf_pages BEGIN INSERT INTO " + searchTable + "(rowid, text) VALUES (new.id, new.text); END")
+	repo := NewPdfTextRepository(db)
+
+	book := images.ImageEntry{Name: "Tomb of Horrors", Type: images.ImageTypePDF, FilePath: "pdf/tomb.pdf"}
+	db.Create(&book)
+	if err := repo.ReplacePdfPages(book.ID, []string{"The false crypt."}, time.Now()); err != nil {
+		t.Fatalf("ReplacePdfPages failed: %v", err)
+	}
+	results, err := repo.SearchPdfPages("crypt", 10)
+	if err != nil || len(results) != 1 {
+		t.Errorf("Expected one match with full-text index %v, got %+v (%v)", repo.HasFullTextIndex(), results, err)
+	}
+}
@@ -19,8 +19,21 @@ func NewPlaylistRepository(db *gorm.DB) repos.PlaylistRepository {
 
 func (r *playlistRepo) GetPlaylistByID(id uint) (*audio.Playlist, error) {
 	var playlist audio.Playlist
-	// Use Preload to automatically fetch the associated Tracks.
-	if err := r.db.Preload("Tracks").First(&playlist, id).Error; err != nil {
+	if err := r.db.First(&playlist, id).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadTracks(&playlist); err != nil {
+		return nil, err
+	}
+	return &playlist, nil
+}
+
+func (r *playlistRepo) GetPlaylistBySourceID(source, sourceID string) (*audio.Playlist, error) {
+	var playlist audio.Playlist
+	if err := r.db.Where("source = ? AND source_id = ?", source, sourceID).First(&playlist).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadTracks(&playlist); err != nil {
 		return nil, err
 	}
 	return &playlist, nil
@@ -28,7 +41,7 @@ func (r *playlistRepo) GetPlaylistByID(id uint) (*audio.Playlist, error) {
 
 func (r *playlistRepo) GetAllPlaylists(filters filters.PlaylistFilters) ([]*audio.Playlist, error) {
 	var playlists []*audio.Playlist
-	query := r.db.Model(&audio.Playlist{}).Preload("Tracks")
+	query := r.db.Model(&audio.Playlist{})
 
 	if filters.Name != "" {
 		query = query.Where("name LIKE ?", "%"+filters.Name+"%")
@@ -42,9 +55,14 @@ func (r *playlistRepo) GetAllPlaylists(filters filters.PlaylistFilters) ([]*audi
 	if err := query.Find(&playlists).Error; err != nil {
 		return nil, err
 	}
+	if err := r.loadAllTracks(playlists); err != nil {
+		return nil, err
+	}
 	return playlists, nil
 }
 
+// CreatePlaylist creates the playlist with the given tracks, in the given order.
+// A track listed more than once keeps its first position.
 func (r *playlistRepo) CreatePlaylist(playlist *audio.Playlist, trackIDs []uint) (*audio.Playlist, error) {
 	err := r.db.Transaction(func(tx *gorm.DB) error {
 		// 1. Create the playlist record first.
@@ -54,7 +72,7 @@ func (r *playlistRepo) CreatePlaylist(playlist *audio.Playlist, trackIDs []uint)
 
 		// 2. If tracks are provided, manually create the join table records.
 		if len(trackIDs) > 0 {
-			for i, trackID := range trackIDs {
+			for i, trackID := range uniqueIDs(trackIDs) {
 				joinRecord := audio.PlaylistTrack{
 					PlaylistID: playlist.ID,
 					TrackID:    trackID,
@@ -74,3 +92,201 @@ func (r *playlistRepo) CreatePlaylist(playlist *audio.Playlist, trackIDs []uint)
 	// Reload the playlist to include the newly associated tracks in the response.
 	return r.GetPlaylistByID(playlist.ID)
 }
+
+func (r *playlistRepo) UpdatePlaylist(playlist *audio.Playlist) (*audio.Playlist, error) {
+	res := r.db.Model(&audio.Playlist{}).Where("id = ?", playlist.ID).
+		Select("Name", "Description").
+		Updates(playlist)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetPlaylistByID(playlist.ID)
+}
+
+// DeletePlaylist removes a playlist and its track associations. Playlists are deleted permanently,
+// since a soft-deleted row would keep its name taken under the unique index.
+func (r *playlistRepo) DeletePlaylist(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("playlist_id = ?", id).Delete(&audio.PlaylistTrack{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Delete(&audio.Playlist{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// AddPlaylistTracks appends tracks after the playlist's last track. Tracks already in the playlist are skipped.
+func (r *playlistRepo) AddPlaylistTracks(playlistID uint, trackIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&audio.Playlist{}, playlistID).Error; err != nil {
+			return err
+		}
+		var found int64
+		if err := tx.Model(&audio.Track{}).Where("id IN ?", trackIDs).Count(&found).Error; err != nil {
+			return err
+		}
+		if int(found) != len(uniqueIDs(trackIDs)) {
+			return gorm.ErrRecordNotFound
+		}
+
+		var lastOrder uint
+		if err := tx.Model(&audio.PlaylistTrack{}).Where("playlist_id = ?", playlistID).
+			Select("COALESCE(MAX(track_order), 0)").Scan(&lastOrder).Error; err != nil {
+			return err
+		}
+		for _, trackID := range trackIDs {
+			joinRecord := audio.PlaylistTrack{PlaylistID: playlistID, TrackID: trackID, TrackOrder: lastOrder + 1}
+			res := tx.Where(audio.PlaylistTrack{PlaylistID: playlistID, TrackID: trackID}).FirstOrCreate(&joinRecord)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected > 0 {
+				lastOrder++
+			}
+		}
+		return nil
+	})
+}
+
+func (r *playlistRepo) RemovePlaylistTrack(playlistID uint, trackID uint) error {
+	res := r.db.Where("playlist_id = ? AND track_id = ?", playlistID, trackID).Delete(&audio.PlaylistTrack{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ReorderPlaylistTracks renumbers the playlist so the given tracks come first, in the given order.
+// Tracks left out (e.g. soft-deleted local tracks that clients cannot see) keep their relative order after them.
+// Fails with ErrRecordNotFound if a given track is not in the playlist.
+func (r *playlistRepo) ReorderPlaylistTracks(playlistID uint, trackIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current []audio.PlaylistTrack
+		if err := tx.Where("playlist_id = ?", playlistID).Order("track_order").Find(&current).Error; err != nil {
+			return err
+		}
+		listed := make(map[uint]bool, len(trackIDs))
+		for _, trackID := range trackIDs {
+			listed[trackID] = true
+		}
+		order := append([]uint{}, uniqueIDs(trackIDs)...)
+		inPlaylist := 0
+		for _, joinRecord := range current {
+			if listed[joinRecord.TrackID] {
+				inPlaylist++
+			} else {
+				order = append(order, joinRecord.TrackID)
+			}
+		}
+		if inPlaylist != len(listed) {
+			return gorm.ErrRecordNotFound
+		}
+
+		for i, trackID := range order {
+			if err := tx.Model(&audio.PlaylistTrack{}).
+				Where("playlist_id = ? AND track_id = ?", playlistID, trackID).
+				Update("track_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReplacePlaylistTracks makes the given tracks the playlist's whole track list, in the given order.
+// A track listed more than once keeps its first position.
+func (r *playlistRepo) ReplacePlaylistTracks(playlistID uint, trackIDs []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&audio.Playlist{}, playlistID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("playlist_id = ?", playlistID).Delete(&audio.PlaylistTrack{}).Error; err != nil {
+			return err
+		}
+		for i, trackID := range uniqueIDs(trackIDs) {
+			joinRecord := audio.PlaylistTrack{PlaylistID: playlistID, TrackID: trackID, TrackOrder: uint(i + 1)}
+			if err := tx.Create(&joinRecord).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Helpers
+
+// loadTracks fills in the playlist's tracks in TrackOrder. A many2many Preload cannot order by the join table.
+func (r *playlistRepo) loadTracks(playlist *audio.Playlist) error {
+	playlist.Tracks = nil
+	return r.db.Joins("JOIN playlist_tracks ON playlist_tracks.track_id = tracks.id").
+		Where("playlist_tracks.playlist_id = ?", playlist.ID).
+		Order("playlist_tracks.track_order").
+		Find(&playlist.Tracks).Error
+}
+
+// loadAllTracks fills in the tracks of every playlist like loadTracks, with one query for the whole list
+// instead of one per playlist.
+func (r *playlistRepo) loadAllTracks(playlists []*audio.Playlist) error {
+	if len(playlists) == 0 {
+		return nil
+	}
+	byID := make(map[uint]*audio.Playlist, len(playlists))
+	for _, playlist := range playlists {
+		playlist.Tracks = nil
+		byID[playlist.ID] = playlist
+	}
+	playlistIDs := make([]uint, 0, len(byID))
+	for id := range byID {
+		playlistIDs = append(playlistIDs, id)
+	}
+
+	var joinRecords []audio.PlaylistTrack
+	if err := r.db.Where("playlist_id IN ?", playlistIDs).Order("playlist_id, track_order").Find(&joinRecords).Error; err != nil {
+		return err
+	}
+	trackIDs := make([]uint, len(joinRecords))
+	for i, joinRecord := range joinRecords {
+		trackIDs[i] = joinRecord.TrackID
+	}
+	var tracks []*audio.Track
+	if err := r.db.Where("id IN ?", uniqueIDs(trackIDs)).Find(&tracks).Error; err != nil {
+		return err
+	}
+	tracksByID := make(map[uint]*audio.Track, len(tracks))
+	for _, track := range tracks {
+		tracksByID[track.ID] = track
+	}
+
+	for _, joinRecord := range joinRecords {
+		// Soft-deleted tracks are missing from tracksByID and left out, as in loadTracks.
+		if track, ok := tracksByID[joinRecord.TrackID]; ok {
+			playlist := byID[joinRecord.PlaylistID]
+			playlist.Tracks = append(playlist.Tracks, track)
+		}
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	var unique []uint
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -1,9 +1,13 @@
 package playlist_repo
 
 import (
+	"dmd/backend/internal/api/common/filters"
 	"dmd/backend/internal/model/audio"
 	"dmd/backend/internal/platform/storage/repos/common"
+	"errors"
 	"testing"
+
+	"gorm.io/gorm"
 )
 
 func TestCreatePlaylistTransaction(t *testing.T) {
@@ -46,3 +50,121 @@ func TestCreatePlaylistTransaction(t *testing.T) {
 		}
 	})
 }
+
+func TestPlaylistTrackOrder(t *testing.T) {
+	db := common.SetupTestDB(t, &audio.Playlist{}, &audio.Track{}, &audio.PlaylistTrack{})
+	repo := NewPlaylistRepository(db)
+
+	var tracks []*audio.Track
+	for _, title := range []string{"A", "B", "C", "D"} {
+		track := &audio.Track{Title: title, Source: audio.SourceLocal, SourceID: title + ".mp3"}
+		db.Create(track)
+		tracks = append(tracks, track)
+	}
+	a, b, c, d := tracks[0].ID, tracks[1].ID, tracks[2].ID, tracks[3].ID
+
+	titles := func(playlistID uint) string {
+		playlist, err := repo.GetPlaylistByID(playlistID)
+		if err != nil {
+			t.Fatalf("GetPlaylistByID failed: %v", err)
+		}
+		result := ""
+		for _, track := range playlist.Tracks {
+			result += track.Title
+		}
+		return result
+	}
+
+	playlist, _ := repo.CreatePlaylist(&audio.Playlist{Name: "Dungeon"}, []uint{c, a, b})
+
+	t.Run("Read_In_Track_Order", func(t *testing.T) {
+		if got := titles(playlist.ID); got != "CAB" {
+			t.Errorf("expected tracks in order CAB, got %s", got)
+		}
+	})
+
+	t.Run("Add_Appends_And_Skips_Existing", func(t *testing.T) {
+		if err := repo.AddPlaylistTracks(playlist.ID, []uint{a, d}); err != nil {
+			t.Fatalf("AddPlaylistTracks failed: %v", err)
+		}
+		if got := titles(playlist.ID); got != "CABD" {
+			t.Errorf("expected tracks in order CABD, got %s", got)
+		}
+		if err := repo.AddPlaylistTracks(playlist.ID, []uint{9999}); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound for an unknown track, got %v", err)
+		}
+	})
+
+	t.Run("Reorder_Moves_Listed_To_Front", func(t *testing.T) {
+		if err := repo.ReorderPlaylistTracks(playlist.ID, []uint{d, b}); err != nil {
+			t.Fatalf("ReorderPlaylistTracks failed: %v", err)
+		}
+		if got := titles(playlist.ID); got != "DBCA" {
+			t.Errorf("expected tracks in order DBCA, got %s", got)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		if err := repo.RemovePlaylistTrack(playlist.ID, b); err != nil {
+			t.Fatalf("RemovePlaylistTrack failed: %v", err)
+		}
+		if got := titles(playlist.ID); got != "DCA" {
+			t.Errorf("expected tracks in order DCA, got %s", got)
+		}
+		if err := repo.ReorderPlaylistTracks(playlist.ID, []uint{b}); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound reordering a removed track, got %v", err)
+		}
+	})
+
+	t.Run("Replace_Sets_Whole_List", func(t *testing.T) {
+		if err := repo.ReplacePlaylistTracks(playlist.ID, []uint{b, a, b}); err != nil {
+			t.Fatalf("ReplacePlaylistTracks failed: %v", err)
+		}
+		if got := titles(playlist.ID); got != "BA" {
+			t.Errorf("expected tracks in order BA, got %s", got)
+		}
+		if err := repo.ReplacePlaylistTracks(9999, []uint{a}); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound for an unknown playlist, got %v", err)
+		}
+	})
+
+	t.Run("List_Loads_Every_Playlist_In_Order", func(t *testing.T) {
+		other, _ := repo.CreatePlaylist(&audio.Playlist{Name: "Tavern"}, []uint{d, c, d})
+		empty, _ := repo.CreatePlaylist(&audio.Playlist{Name: "Empty"}, nil)
+		db.Delete(&audio.Track{}, c)
+		defer db.Unscoped().Model(&audio.Track{}).Where("id = ?", c).Update("deleted_at", nil)
+
+		playlists, err := repo.GetAllPlaylists(filters.PlaylistFilters{})
+		if err != nil {
+			t.Fatalf("GetAllPlaylists failed: %v", err)
+		}
+		got := map[uint]string{}
+		for _, listed := range playlists {
+			for _, track := range listed.Tracks {
+				got[listed.ID] += track.Title
+			}
+		}
+		if got[playlist.ID] != "BA" || got[other.ID] != "D" || got[empty.ID] != "" {
+			t.Errorf("expected BA, D and no tracks without the deleted track, got %v", got)
+		}
+		repo.DeletePlaylist(other.ID)
+		repo.DeletePlaylist(empty.ID)
+	})
+
+	t.Run("Delete_Frees_Name", func(t *testing.T) {
+		if err := repo.DeletePlaylist(playlist.ID); err != nil {
+			t.Fatalf("DeletePlaylist failed: %v", err)
+		}
+		var joins int64
+		db.Model(&audio.PlaylistTrack{}).Where("playlist_id = ?", playlist.ID).Count(&joins)
+		if joins != 0 {
+			t.Errorf("expected track associations to be removed, got %d", joins)
+		}
+		if _, err := repo.CreatePlaylist(&audio.Playlist{Name: "Dungeon"}, nil); err != nil {
+			t.Errorf("expected the name to be reusable after delete: %v", err)
+		}
+		if err := repo.DeletePlaylist(playlist.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("expected ErrRecordNotFound deleting twice, got %v", err)
+		}
+	})
+}
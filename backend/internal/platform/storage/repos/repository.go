@@ -7,8 +7,10 @@ import (
 	"dmd/backend/internal/model/character"
 	"dmd/backend/internal/model/combat"
 	"dmd/backend/internal/model/crawl"
+	"dmd/backend/internal/model/display"
 	"dmd/backend/internal/model/gameplay"
 	"dmd/backend/internal/model/images"
+	"time"
 )
 
 type CharacterRepository interface {
@@ -43,6 +45,8 @@ type CombatRepository interface {
 	GetActiveCombat() (*combat.Combat, error)
 	GetCombatByID(id uint) (*combat.Combat, error)
 	UpdateCombatant(combatant *combat.Combatant) error
+	GetCombatantByID(id uint) (*combat.Combatant, error)
+	AddHistoryEntry(entry *combat.HistoryEntry) error
 }
 
 type ItemRepository interface {
@@ -65,17 +69,85 @@ type SpellRepository interface {
 
 type TrackRepository interface {
 	GetTrackByID(id uint) (*audio.Track, error)
+	GetTrackBySourceID(source, sourceID string) (*audio.Track, error)
 	GetAllTracks(filters filters.TrackFilters) ([]*audio.Track, error)
 	CreateTrack(track *audio.Track) error
 	UpdateTrack(track *audio.Track) error
+	UpdateTrackAnalysis(track *audio.Track) error // Only writes the duration and analysis fields
 	DeleteTrack(id uint) error
-	BulkCreateTracks(tracks []*audio.Track) error // Transactional
+	BulkCreateTracks(tracks []*audio.Track) error // Transactional, updates tracks that already exist
+	RestoreSoftDeletedBySourceID(source, sourceID string) (bool, error)
 }
 
 type PlaylistRepository interface {
 	GetPlaylistByID(id uint) (*audio.Playlist, error)
+	GetPlaylistBySourceID(source, sourceID string) (*audio.Playlist, error)
 	GetAllPlaylists(filters filters.PlaylistFilters) ([]*audio.Playlist, error)
 	CreatePlaylist(playlist *audio.Playlist, trackIDs []uint) (*audio.Playlist, error) // Transactional
+	UpdatePlaylist(playlist *audio.Playlist) (*audio.Playlist, error)
+	DeletePlaylist(id uint) error                             // Transactional
+	AddPlaylistTracks(playlistID uint, trackIDs []uint) error // Transactional, appends in the given order
+	RemovePlaylistTrack(playlistID uint, trackID uint) error
+	ReorderPlaylistTracks(playlistID uint, trackIDs []uint) error // Transactional
+	ReplacePlaylistTracks(playlistID uint, trackIDs []uint) error // Transactional
+}
+
+type SoundboardRepository interface {
+	GetSoundboardByID(id uint) (*audio.Soundboard, error)
+	GetAllSoundboards(filters filters.SoundboardFilters) ([]*audio.Soundboard, error)
+	CreateSoundboard(board *audio.Soundboard) error // Transactional
+	UpdateSoundboard(board *audio.Soundboard) error // Transactional
+	DeleteSoundboard(id uint) error                 // Transactional
+	GetPadByID(id uint) (*audio.SoundboardPad, error)
+}
+
+type FogRepository interface {
+	AppendFogOperation(op *images.FogOperation) error // Transactional, assigns the next version
+	GetFogOperations(imageID uint, sinceVersion uint) ([]*images.FogOperation, error)
+	GetFogVersion(imageID uint) (uint, error)
+}
+
+type PdfTextRepository interface {
+	ReplacePdfPages(imageID uint, pages []string, fileModTime time.Time) error // Transactional
+	DeletePdfPages(imageID uint) error
+	GetPdfPagesModTime(imageID uint) (*time.Time, error) // Nil when the PDF has no stored text
+	SearchPdfPages(query string, limit int) ([]*images.PdfSearchResult, error)
+	HasFullTextIndex() bool
+}
+
+type PdfBookmarkRepository interface {
+	GetBookmarkByID(id uint) (*images.PdfBookmark, error)
+	GetBookmarks(imageID uint) ([]*images.PdfBookmark, error)
+	CreateBookmark(bookmark *images.PdfBookmark) error
+	UpdateBookmark(bookmark *images.PdfBookmark) error
+	DeleteBookmark(id uint) error
+}
+
+type MapTokenRepository interface {
+	GetTokenByID(id uint) (*images.MapToken, error)
+	GetTokensByImageID(imageID uint) ([]*images.MapToken, error)
+	CreateToken(token *images.MapToken) error
+	MoveToken(id uint, col, row int) error
+	DeleteToken(id uint) error
+}
+
+type AnnotationRepository interface {
+	GetAnnotationByID(id uint) (*images.Annotation, error)
+	GetAnnotations(imageID uint, filters filters.AnnotationFilters) ([]*images.Annotation, error)
+	CreateAnnotation(annotation *images.Annotation) error
+	UpdateAnnotation(annotation *images.Annotation) error
+	AppendAnnotationPoints(id uint, points []images.Point) (*images.Annotation, error) // Transactional
+	DeleteAnnotation(id uint) error
+	DeleteAnnotationsByImageID(imageID uint) error
+}
+
+type SceneRepository interface {
+	GetSceneByID(id uint) (*display.Scene, error)
+	GetAllScenes(filters filters.SceneFilters) ([]*display.Scene, error)
+	CreateScene(scene *display.Scene) error // Transactional
+	UpdateScene(scene *display.Scene) error // Transactional
+	DeleteScene(id uint) error
+	SetActiveCue(id uint, cueOrder uint) error
 }
 
 type CharacterTemplateRepository interface {
@@ -91,12 +163,17 @@ type ImagesRepository interface {
 	GetAllImages(filters filters.ImagesFilters) ([]*images.ImageEntry, error)
 	GetAllTypes() ([]string, error)
 	CreateImageEntry(asset *images.ImageEntry) error
-	UpdateImageEntry(asset *images.ImageEntry) error
+	UpdateImageEntry(asset *images.ImageEntry) error // Leaves the grid calibration untouched
+	UpdateImageGrid(id uint, grid images.MapGrid) error
 	DeleteImage(id uint) error
 	RestoreSoftDeletedByPath(path string) (bool, error)
 	GetImageByPath(path string) (*images.ImageEntry, error)
 	BulkCreateImageEntries(assets []*images.ImageEntry) error // Transactional
 	CreatePreset(preset *images.PresetLayout) error
+	GetPresetByID(id uint) (*images.PresetLayout, error)
 	GetAllPresets() ([]*images.PresetLayout, error)
+	UpdatePreset(preset *images.PresetLayout) error                     // Transactional
+	DuplicatePreset(id uint, name string) (*images.PresetLayout, error) // Transactional
+	ReorderPresets(presetIDs []uint) error                              // Transactional
 	DeletePreset(id uint) error
 }
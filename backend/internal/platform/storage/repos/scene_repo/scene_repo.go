@@ -0,0 +1,147 @@
+// File: /internal/platform/storage/scene_repo.go
+package scene_repo
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/display"
+	"dmd/backend/internal/platform/storage/repos"
+
+	"gorm.io/gorm"
+)
+
+type sceneRepo struct {
+	db *gorm.DB
+}
+
+func NewSceneRepository(db *gorm.DB) repos.SceneRepository {
+	return &sceneRepo{db: db}
+}
+
+func (r *sceneRepo) GetSceneByID(id uint) (*display.Scene, error) {
+	var scene display.Scene
+	if err := preloadCues(r.db).First(&scene, id).Error; err != nil {
+		return nil, err
+	}
+	return &scene, nil
+}
+
+func (r *sceneRepo) GetAllScenes(filters filters.SceneFilters) ([]*display.Scene, error) {
+	var scenes []*display.Scene
+	query := preloadCues(r.db.Model(&display.Scene{}))
+
+	if filters.Name != "" {
+		query = query.Where("name LIKE ?", "%"+filters.Name+"%")
+	}
+
+	if filters.PageSize > 0 && filters.Page > 0 {
+		offset := (filters.Page - 1) * filters.PageSize
+		query = query.Limit(filters.PageSize).Offset(offset)
+	}
+
+	if err := query.Find(&scenes).Error; err != nil {
+		return nil, err
+	}
+	return scenes, nil
+}
+
+// CreateScene creates the scene and its cues, numbering the cues in the order given.
+func (r *sceneRepo) CreateScene(scene *display.Scene) error {
+	cues := scene.Cues
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		scene.ActiveCue = 0
+		if err := tx.Omit("Cues").Create(scene).Error; err != nil {
+			return err
+		}
+		return createCues(tx, scene.ID, cues)
+	})
+	if err != nil {
+		return err
+	}
+	return r.reload(scene)
+}
+
+// UpdateScene replaces the scene's details and its whole cue list, rewinding it to the start.
+func (r *sceneRepo) UpdateScene(scene *display.Scene) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing display.Scene
+		if err := tx.First(&existing, scene.ID).Error; err != nil {
+			return err
+		}
+
+		scene.ActiveCue = 0
+		err := tx.Model(&existing).
+			Select("Name", "Description", "ActiveCue").
+			Updates(scene).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Unscoped().Where("scene_id = ?", scene.ID).Delete(&display.SceneCue{}).Error; err != nil {
+			return err
+		}
+		return createCues(tx, scene.ID, scene.Cues)
+	})
+	if err != nil {
+		return err
+	}
+	return r.reload(scene)
+}
+
+func (r *sceneRepo) DeleteScene(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("scene_id = ?", id).Delete(&display.SceneCue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&display.Scene{}, id).Error
+	})
+}
+
+func (r *sceneRepo) SetActiveCue(id uint, cueOrder uint) error {
+	res := r.db.Model(&display.Scene{}).Where("id = ?", id).Update("active_cue", cueOrder)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Helpers
+
+func (r *sceneRepo) reload(scene *display.Scene) error {
+	reloaded, err := r.GetSceneByID(scene.ID)
+	if err != nil {
+		return err
+	}
+	*scene = *reloaded
+	return nil
+}
+
+// createCues inserts fresh copies of the given cues under sceneID, numbered from 1.
+func createCues(tx *gorm.DB, sceneID uint, cues []display.SceneCue) error {
+	for i, cue := range cues {
+		newCue := display.SceneCue{
+			SceneID:        sceneID,
+			CueOrder:       uint(i + 1),
+			PresetLayoutID: cue.PresetLayoutID,
+			TrackID:        cue.TrackID,
+			PlaylistID:     cue.PlaylistID,
+			Notes:          cue.Notes,
+		}
+		if err := tx.Omit("PresetLayout", "Track", "Playlist").Create(&newCue).Error; err != nil {
+			return err // This will trigger a rollback
+		}
+	}
+	return nil
+}
+
+// preloadCues fetches the cues in order, together with everything needed to apply them.
+func preloadCues(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Cues", func(db *gorm.DB) *gorm.DB { return db.Order("cue_order asc") }).
+		Preload("Cues.PresetLayout.Slots", func(db *gorm.DB) *gorm.DB { return db.Order("slot_id asc") }).
+		Preload("Cues.PresetLayout.Slots.Image").
+		Preload("Cues.Track").
+		Preload("Cues.Playlist")
+}
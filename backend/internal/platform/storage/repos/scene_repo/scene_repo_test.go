@@ -0,0 +1,57 @@
+package scene_repo
+
+import (
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/display"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/common"
+	"testing"
+)
+
+func TestCreateSceneTransaction(t *testing.T) {
+	db := common.SetupTestDB(t, &display.Scene{}, &display.SceneCue{}, &images.PresetLayout{}, &images.PresetLayoutSlot{},
+		&images.ImageEntry{}, &audio.Track{}, &audio.Playlist{})
+	repo := NewSceneRepository(db)
+
+	first := images.PresetLayout{Name: "Town", LayoutType: images.Single}
+	second := images.PresetLayout{Name: "Forest", LayoutType: images.Single}
+	db.Create(&first)
+	db.Create(&second)
+
+	t.Run("Success_Case", func(t *testing.T) {
+		scene := &display.Scene{
+			Name: "Session 1",
+			Cues: []display.SceneCue{
+				{PresetLayoutID: second.ID},
+				{PresetLayoutID: first.ID},
+			},
+		}
+		if err := repo.CreateScene(scene); err != nil {
+			t.Fatalf("CreateScene failed unexpectedly: %v", err)
+		}
+		if len(scene.Cues) != 2 {
+			t.Fatalf("expected 2 cues, got %d", len(scene.Cues))
+		}
+		if scene.Cues[0].CueOrder != 1 || scene.Cues[0].PresetLayout.Name != "Forest" {
+			t.Errorf("expected first cue to be Forest with order 1, got %q with order %d",
+				scene.Cues[0].PresetLayout.Name, scene.Cues[0].CueOrder)
+		}
+	})
+
+	t.Run("Rollback_Case", func(t *testing.T) {
+		// The name is unique, so the second scene must fail without leaving cues behind.
+		var cuesBefore int64
+		db.Model(&display.SceneCue{}).Count(&cuesBefore)
+
+		scene := &display.Scene{Name: "Session 1", Cues: []display.SceneCue{{PresetLayoutID: first.ID}}}
+		if err := repo.CreateScene(scene); err == nil {
+			t.Fatal("CreateScene was expected to fail but did not")
+		}
+
+		var cuesAfter int64
+		db.Model(&display.SceneCue{}).Count(&cuesAfter)
+		if cuesAfter != cuesBefore {
+			t.Errorf("expected cue count to stay %d after rollback, got %d", cuesBefore, cuesAfter)
+		}
+	})
+}
@@ -0,0 +1,206 @@
+// File: /internal/platform/storage/soundboard_repo.go
+package soundboard_repo
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/platform/storage/repos"
+
+	"gorm.io/gorm"
+)
+
+type soundboardRepo struct {
+	db *gorm.DB
+}
+
+func NewSoundboardRepository(db *gorm.DB) repos.SoundboardRepository {
+	return &soundboardRepo{db: db}
+}
+
+func (r *soundboardRepo) GetSoundboardByID(id uint) (*audio.Soundboard, error) {
+	var board audio.Soundboard
+	if err := preloadPads(r.db).First(&board, id).Error; err != nil {
+		return nil, err
+	}
+	return &board, nil
+}
+
+func (r *soundboardRepo) GetAllSoundboards(filters filters.SoundboardFilters) ([]*audio.Soundboard, error) {
+	var boards []*audio.Soundboard
+	query := preloadPads(r.db.Model(&audio.Soundboard{}))
+
+	if filters.Name != "" {
+		query = query.Where("name LIKE ?", "%"+filters.Name+"%")
+	}
+
+	if filters.PageSize > 0 && filters.Page > 0 {
+		offset := (filters.Page - 1) * filters.PageSize
+		query = query.Limit(filters.PageSize).Offset(offset)
+	}
+
+	if err := query.Find(&boards).Error; err != nil {
+		return nil, err
+	}
+	return boards, nil
+}
+
+// CreateSoundboard creates the soundboard together with its pads.
+func (r *soundboardRepo) CreateSoundboard(board *audio.Soundboard) error {
+	pads := board.Pads
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Pads").Create(board).Error; err != nil {
+			return err
+		}
+		return createPads(tx, board.ID, pads)
+	})
+	if err != nil {
+		return err
+	}
+	return r.reload(board)
+}
+
+// UpdateSoundboard replaces the soundboard's details and its set of pads. Pads that are still on the board are
+// updated in place, so clients holding their IDs can keep triggering them; see matchPads.
+func (r *soundboardRepo) UpdateSoundboard(board *audio.Soundboard) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing audio.Soundboard
+		if err := tx.Preload("Pads").First(&existing, board.ID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&existing).
+			Select("Name", "Rows", "Columns").
+			Updates(board).Error
+		if err != nil {
+			return err
+		}
+
+		kept, added, removed := matchPads(existing.Pads, board.Pads)
+		if len(removed) > 0 {
+			if err := tx.Unscoped().Delete(&audio.SoundboardPad{}, removed).Error; err != nil {
+				return err
+			}
+		}
+		for id, pad := range kept {
+			pad.Model = gorm.Model{} // The ID the client sent, if any, is not the one being updated
+			err := tx.Model(&audio.SoundboardPad{Model: gorm.Model{ID: id}}).
+				Select("Row", "Column", "Label", "TrackID", "Volume", "KeyBinding", "Mode", "DuckMusic").
+				Updates(&pad).Error
+			if err != nil {
+				return err
+			}
+		}
+		return createPads(tx, board.ID, added)
+	})
+	if err != nil {
+		return err
+	}
+	return r.reload(board)
+}
+
+func (r *soundboardRepo) DeleteSoundboard(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("soundboard_id = ?", id).Delete(&audio.SoundboardPad{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Delete(&audio.Soundboard{}, id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *soundboardRepo) GetPadByID(id uint) (*audio.SoundboardPad, error) {
+	var pad audio.SoundboardPad
+	if err := r.db.Preload("Track").First(&pad, id).Error; err != nil {
+		return nil, err
+	}
+	return &pad, nil
+}
+
+// Helpers
+
+func (r *soundboardRepo) reload(board *audio.Soundboard) error {
+	reloaded, err := r.GetSoundboardByID(board.ID)
+	if err != nil {
+		return err
+	}
+	*board = *reloaded
+	return nil
+}
+
+// createPads inserts fresh copies of the given pads under boardID.
+func createPads(tx *gorm.DB, boardID uint, pads []audio.SoundboardPad) error {
+	for _, pad := range pads {
+		newPad := audio.SoundboardPad{
+			SoundboardID: boardID,
+			Row:          pad.Row,
+			Column:       pad.Column,
+			Label:        pad.Label,
+			TrackID:      pad.TrackID,
+			Volume:       pad.Volume,
+			KeyBinding:   pad.KeyBinding,
+			Mode:         pad.Mode,
+			DuckMusic:    pad.DuckMusic,
+		}
+		if err := tx.Omit("Track").Create(&newPad).Error; err != nil {
+			return err // This will trigger a rollback
+		}
+	}
+	return nil
+}
+
+// matchPads pairs the pads sent for a board with the ones it has. A pad is matched by its ID, or failing that
+// by its cell, so a client that sends pads without IDs still keeps them. kept maps the ID of each matched pad
+// to its new values, added are the pads to create and removed the IDs of the pads that are gone.
+func matchPads(existing, pads []audio.SoundboardPad) (kept map[uint]audio.SoundboardPad, added []audio.SoundboardPad, removed []uint) {
+	unmatched := make(map[uint]audio.SoundboardPad, len(existing))
+	for _, pad := range existing {
+		unmatched[pad.ID] = pad
+	}
+
+	kept = make(map[uint]audio.SoundboardPad)
+	var byCell []audio.SoundboardPad
+	for _, pad := range pads {
+		if _, ok := unmatched[pad.ID]; ok && pad.ID != 0 {
+			kept[pad.ID] = pad
+			delete(unmatched, pad.ID)
+		} else {
+			byCell = append(byCell, pad)
+		}
+	}
+	for _, pad := range byCell {
+		id, ok := padAt(unmatched, pad.Row, pad.Column)
+		if !ok {
+			added = append(added, pad)
+			continue
+		}
+		kept[id] = pad
+		delete(unmatched, id)
+	}
+
+	for id := range unmatched {
+		removed = append(removed, id)
+	}
+	return kept, added, removed
+}
+
+func padAt(pads map[uint]audio.SoundboardPad, row, column uint) (uint, bool) {
+	for id, pad := range pads {
+		if pad.Row == row && pad.Column == column {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// preloadPads fetches the pads in grid order, row by row, with their tracks.
+func preloadPads(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Pads", func(db *gorm.DB) *gorm.DB { return db.Order("`row` asc, `column` asc") }).
+		Preload("Pads.Track")
+}
@@ -0,0 +1,82 @@
+package soundboard_repo
+
+import (
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/platform/storage/repos/common"
+	"testing"
+)
+
+func TestSoundboardPads(t *testing.T) {
+	db := common.SetupTestDB(t, &audio.Soundboard{}, &audio.SoundboardPad{}, &audio.Track{})
+	repo := NewSoundboardRepository(db)
+
+	roar := audio.Track{Title: "Roar", Source: audio.SourceLocal, SourceID: "roar.mp3"}
+	door := audio.Track{Title: "Door", Source: audio.SourceLocal, SourceID: "door.mp3"}
+	db.Create(&roar)
+	db.Create(&door)
+
+	board := &audio.Soundboard{
+		Name: "Dungeon",
+		Pads: []audio.SoundboardPad{
+			{Row: 1, Column: 0, TrackID: roar.ID},
+			{Row: 0, Column: 1, TrackID: door.ID},
+			{Row: 0, Column: 0, TrackID: roar.ID},
+		},
+	}
+
+	t.Run("Pads_In_Grid_Order", func(t *testing.T) {
+		if err := repo.CreateSoundboard(board); err != nil {
+			t.Fatalf("CreateSoundboard failed unexpectedly: %v", err)
+		}
+		if board.Rows != 4 || board.Columns != 4 {
+			t.Errorf("expected a default 4x4 grid, got %dx%d", board.Rows, board.Columns)
+		}
+		if len(board.Pads) != 3 {
+			t.Fatalf("expected 3 pads, got %d", len(board.Pads))
+		}
+		got := [][2]uint{}
+		for _, pad := range board.Pads {
+			got = append(got, [2]uint{pad.Row, pad.Column})
+		}
+		if got[0] != [2]uint{0, 0} || got[1] != [2]uint{0, 1} || got[2] != [2]uint{1, 0} {
+			t.Errorf("expected pads ordered row by row, got %v", got)
+		}
+		if board.Pads[1].Track.Title != "Door" || board.Pads[0].Volume == nil || *board.Pads[0].Volume != 1 || board.Pads[0].Mode != audio.PadModeRestart {
+			t.Errorf("expected tracks preloaded and column defaults applied, got %+v", board.Pads[:2])
+		}
+
+		pad, err := repo.GetPadByID(board.Pads[1].ID)
+		if err != nil || pad.SoundboardID != board.ID || pad.Track.Title != "Door" {
+			t.Errorf("expected the door pad with its track, got %+v (%v)", pad, err)
+		}
+	})
+
+	t.Run("Rollback_Case", func(t *testing.T) {
+		// The name is unique, so the second board must fail without leaving pads behind.
+		var padsBefore int64
+		db.Model(&audio.SoundboardPad{}).Count(&padsBefore)
+
+		duplicate := &audio.Soundboard{Name: "Dungeon", Pads: []audio.SoundboardPad{{TrackID: roar.ID}}}
+		if err := repo.CreateSoundboard(duplicate); err == nil {
+			t.Fatal("CreateSoundboard was expected to fail but did not")
+		}
+
+		var padsAfter int64
+		db.Model(&audio.SoundboardPad{}).Count(&padsAfter)
+		if padsAfter != padsBefore {
+			t.Errorf("expected pad count to stay %d after rollback, got %d", padsBefore, padsAfter)
+		}
+	})
+
+	t.Run("Delete_Frees_Name", func(t *testing.T) {
+		if err := repo.DeleteSoundboard(board.ID); err != nil {
+			t.Fatalf("DeleteSoundboard failed unexpectedly: %v", err)
+		}
+		if err := repo.DeleteSoundboard(board.ID); err == nil {
+			t.Error("expected deleting a missing soundboard to fail")
+		}
+		if err := repo.CreateSoundboard(&audio.Soundboard{Name: "Dungeon"}); err != nil {
+			t.Errorf("expected the name to be reusable after delete, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+// File: /internal/platform/storage/token_repo.go
+package token_repo
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos"
+
+	"gorm.io/gorm"
+)
+
+type tokenRepo struct {
+	db *gorm.DB
+}
+
+func NewMapTokenRepository(db *gorm.DB) repos.MapTokenRepository {
+	return &tokenRepo{db: db}
+}
+
+func (r *tokenRepo) GetTokenByID(id uint) (*images.MapToken, error) {
+	var token images.MapToken
+	if err := preloadToken(r.db).First(&token, id).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *tokenRepo) GetTokensByImageID(imageID uint) ([]*images.MapToken, error) {
+	var tokens []*images.MapToken
+	err := preloadToken(r.db).Where("image_id = ?", imageID).Order("id asc").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+func (r *tokenRepo) CreateToken(token *images.MapToken) error {
+	if err := r.db.Omit("CharacterTemplate", "Combatant").Create(token).Error; err != nil {
+		return err
+	}
+	reloaded, err := r.GetTokenByID(token.ID)
+	if err != nil {
+		return err
+	}
+	*token = *reloaded
+	return nil
+}
+
+func (r *tokenRepo) MoveToken(id uint, col, row int) error {
+	res := r.db.Model(&images.MapToken{}).Where("id = ?", id).Updates(map[string]any{"col": col, "row": row})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *tokenRepo) DeleteToken(id uint) error {
+	res := r.db.Delete(&images.MapToken{}, id)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Helpers
+
+// preloadToken fetches what a client needs to render a token, without the full character sheet.
+func preloadToken(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("CharacterTemplate", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "name", "size", "photo_path", "photo_offset_y", "color")
+		}).
+		Preload("Combatant")
+}
@@ -0,0 +1,58 @@
+package token_repo
+
+import (
+	"dmd/backend/internal/model/combat"
+	"dmd/backend/internal/model/crawl"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos/common"
+	"testing"
+)
+
+func TestMapTokens(t *testing.T) {
+	db := common.SetupTestDB(t, &images.MapToken{}, &crawl.CharacterTemplate{}, &combat.Combatant{})
+	repo := NewMapTokenRepository(db)
+
+	tmpl := crawl.CharacterTemplate{Name: "Ogre", Size: "Large", Color: "#556b2f"}
+	db.Create(&tmpl)
+
+	token := &images.MapToken{ImageID: 1, CharacterTemplateID: &tmpl.ID, Col: 2, Row: 3}
+	if err := repo.CreateToken(token); err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if token.CharacterTemplate == nil || token.CharacterTemplate.Size != "Large" {
+		t.Errorf("Expected the template to be loaded with the token, got %+v", token.CharacterTemplate)
+	}
+
+	t.Run("Move", func(t *testing.T) {
+		if err := repo.MoveToken(token.ID, 5, 6); err != nil {
+			t.Fatalf("MoveToken failed: %v", err)
+		}
+		moved, err := repo.GetTokenByID(token.ID)
+		if err != nil {
+			t.Fatalf("GetTokenByID failed: %v", err)
+		}
+		if moved.Col != 5 || moved.Row != 6 {
+			t.Errorf("Expected token at (5, 6), got (%d, %d)", moved.Col, moved.Row)
+		}
+	})
+
+	t.Run("Move_Missing_Token", func(t *testing.T) {
+		if err := repo.MoveToken(9999, 0, 0); err == nil {
+			t.Error("Expected error for missing token, got nil")
+		}
+	})
+
+	t.Run("List_By_Map", func(t *testing.T) {
+		other := &images.MapToken{ImageID: 2, CharacterTemplateID: &tmpl.ID}
+		if err := repo.CreateToken(other); err != nil {
+			t.Fatalf("CreateToken failed: %v", err)
+		}
+		tokens, err := repo.GetTokensByImageID(1)
+		if err != nil {
+			t.Fatalf("GetTokensByImageID failed: %v", err)
+		}
+		if len(tokens) != 1 || tokens[0].ID != token.ID {
+			t.Errorf("Expected only the first map's token, got %d tokens", len(tokens))
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"dmd/backend/internal/platform/storage/repos"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type trackRepo struct {
@@ -25,6 +26,14 @@ func (r *trackRepo) GetTrackByID(id uint) (*audio.Track, error) {
 	return &track, nil
 }
 
+func (r *trackRepo) GetTrackBySourceID(source, sourceID string) (*audio.Track, error) {
+	var track audio.Track
+	if err := r.db.Where("source = ? AND source_id = ?", source, sourceID).First(&track).Error; err != nil {
+		return nil, err
+	}
+	return &track, nil
+}
+
 func (r *trackRepo) GetAllTracks(filters filters.TrackFilters) ([]*audio.Track, error) {
 	var tracks []*audio.Track
 	query := r.db.Model(&audio.Track{})
@@ -58,17 +67,42 @@ func (r *trackRepo) UpdateTrack(track *audio.Track) error {
 	return r.db.Save(track).Error
 }
 
+// UpdateTrackAnalysis saves the results of analysing a track's file without touching its other fields,
+// which may have changed while the file was being decoded.
+func (r *trackRepo) UpdateTrackAnalysis(track *audio.Track) error {
+	return r.db.Model(track).Select("duration", "loudness", "gain", "waveform", "analyzed_at").Updates(track).Error
+}
+
 func (r *trackRepo) DeleteTrack(id uint) error {
 	return r.db.Delete(&audio.Track{}, id).Error
 }
 
+// BulkCreateTracks inserts the tracks, deduplicating on the unique (source, source_id) index: a track that is
+// already stored has its metadata updated instead, and is restored if it was soft-deleted. Every track comes
+// back with the ID of its row, so the same source ID listed twice resolves to the same track.
 func (r *trackRepo) BulkCreateTracks(tracks []*audio.Track) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
 		for _, track := range tracks {
-			if err := tx.Create(track).Error; err != nil {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "source"}, {Name: "source_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"title", "artist", "duration", "thumbnail_url", "updated_at", "deleted_at"}),
+			}).Create(track).Error
+			if err != nil {
 				return err
 			}
 		}
 		return nil
 	})
 }
+
+// RestoreSoftDeletedBySourceID un-deletes a track, since the unique (source, source_id) index
+// also covers soft-deleted rows and would reject a new one.
+func (r *trackRepo) RestoreSoftDeletedBySourceID(source, sourceID string) (bool, error) {
+	res := r.db.Unscoped().Model(&audio.Track{}).
+		Where("source = ? AND source_id = ? AND deleted_at IS NOT NULL", source, sourceID).
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
@@ -4,6 +4,8 @@ import (
 	"dmd/backend/internal/model/audio"
 	"dmd/backend/internal/platform/storage/repos/common"
 	"testing"
+
+	"gorm.io/gorm"
 )
 
 func TestBulkCreateTracks(t *testing.T) {
@@ -30,10 +32,10 @@ func TestBulkCreateTracks(t *testing.T) {
 	t.Run("Rollback_Case", func(t *testing.T) {
 		db.Exec("DELETE FROM tracks")
 
-		// This will fail because the combination of Source and SourceID is a UNIQUE constraint.
+		// This will fail because both tracks claim the same primary key.
 		tracksToCreate := []*audio.Track{
-			{Title: "Valid Track", Source: audio.SourceSpotify, SourceID: "spotify1"},
-			{Title: "Duplicate Track", Source: audio.SourceSpotify, SourceID: "spotify1"},
+			{Model: gorm.Model{ID: 7}, Title: "Valid Track", Source: audio.SourceSpotify, SourceID: "spotify1"},
+			{Model: gorm.Model{ID: 7}, Title: "Clashing Track", Source: audio.SourceSpotify, SourceID: "spotify2"},
 		}
 
 		err := repo.BulkCreateTracks(tracksToCreate)
@@ -47,4 +49,77 @@ func TestBulkCreateTracks(t *testing.T) {
 			t.Errorf("expected count to be 0 after rollback, got %d", count)
 		}
 	})
+
+	t.Run("Deduplicate_Case", func(t *testing.T) {
+		db.Exec("DELETE FROM tracks")
+
+		stored := &audio.Track{Title: "Old Title", Source: audio.SourceSpotify, SourceID: "spotify:track:1"}
+		repo.CreateTrack(stored)
+		repo.DeleteTrack(stored.ID)
+
+		// The same source ID twice in one batch, and once more for a soft-deleted row.
+		tracksToCreate := []*audio.Track{
+			{Title: "New Title", Artist: "Bard", Source: audio.SourceSpotify, SourceID: "spotify:track:1"},
+			{Title: "Fresh", Source: audio.SourceSpotify, SourceID: "spotify:track:2"},
+			{Title: "New Title", Artist: "Bard", Source: audio.SourceSpotify, SourceID: "spotify:track:1"},
+		}
+		if err := repo.BulkCreateTracks(tracksToCreate); err != nil {
+			t.Fatalf("BulkCreate failed unexpectedly: %v", err)
+		}
+
+		if tracksToCreate[0].ID != stored.ID || tracksToCreate[2].ID != stored.ID {
+			t.Errorf("expected duplicates to resolve to track %d, got %d and %d", stored.ID, tracksToCreate[0].ID, tracksToCreate[2].ID)
+		}
+		if tracksToCreate[1].ID == 0 || tracksToCreate[1].ID == stored.ID {
+			t.Errorf("expected a new track to get its own ID, got %d", tracksToCreate[1].ID)
+		}
+		updated, err := repo.GetTrackByID(stored.ID)
+		if err != nil {
+			t.Fatalf("expected the soft-deleted track to be restored: %v", err)
+		}
+		if updated.Title != "New Title" || updated.Artist != "Bard" {
+			t.Errorf("expected metadata to be updated, got %+v", updated)
+		}
+
+		var count int64
+		db.Model(&audio.Track{}).Count(&count)
+		if count != 2 {
+			t.Errorf("expected count to be 2, got %d", count)
+		}
+	})
+}
+
+func TestTrackSourceLookups(t *testing.T) {
+	db := common.SetupTestDB(t, &audio.Track{})
+	repo := NewTrackRepository(db)
+
+	track := &audio.Track{Title: "Tavern", Source: audio.SourceLocal, SourceID: "tavern.mp3"}
+	repo.CreateTrack(track)
+
+	t.Run("Get_By_Source_ID", func(t *testing.T) {
+		found, err := repo.GetTrackBySourceID(audio.SourceLocal, "tavern.mp3")
+		if err != nil || found.ID != track.ID {
+			t.Fatalf("expected to find track %d, got %+v, err %v", track.ID, found, err)
+		}
+		if _, err = repo.GetTrackBySourceID(audio.SourceSpotify, "tavern.mp3"); err == nil {
+			t.Error("expected lookup with another source to fail")
+		}
+	})
+
+	t.Run("Restore_Soft_Deleted", func(t *testing.T) {
+		repo.DeleteTrack(track.ID)
+
+		restored, err := repo.RestoreSoftDeletedBySourceID(audio.SourceLocal, "tavern.mp3")
+		if err != nil || !restored {
+			t.Fatalf("expected track to be restored, got %v, err %v", restored, err)
+		}
+		if _, err = repo.GetTrackByID(track.ID); err != nil {
+			t.Errorf("expected restored track to be visible again: %v", err)
+		}
+
+		restored, _ = repo.RestoreSoftDeletedBySourceID(audio.SourceLocal, "tavern.mp3")
+		if restored {
+			t.Error("expected nothing to restore for a live track")
+		}
+	})
 }
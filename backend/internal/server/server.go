@@ -6,11 +6,30 @@ import (
 	"dmd/backend/internal/api/routes"
 	"dmd/backend/internal/platform/logger"
 	"dmd/backend/internal/platform/storage"
+	"dmd/backend/internal/platform/storage/repos/annotation_repo"
+	"dmd/backend/internal/platform/storage/repos/bookmark_repo"
+	"dmd/backend/internal/platform/storage/repos/character_template_repo"
+	"dmd/backend/internal/platform/storage/repos/combat_repo"
+	"dmd/backend/internal/platform/storage/repos/fog_repo"
 	"dmd/backend/internal/platform/storage/repos/images_repo"
+	"dmd/backend/internal/platform/storage/repos/pdf_text_repo"
+	"dmd/backend/internal/platform/storage/repos/playlist_repo"
+	"dmd/backend/internal/platform/storage/repos/scene_repo"
+	"dmd/backend/internal/platform/storage/repos/soundboard_repo"
+	"dmd/backend/internal/platform/storage/repos/token_repo"
+	"dmd/backend/internal/platform/storage/repos/track_repo"
+	"dmd/backend/internal/services/annotations"
+	"dmd/backend/internal/services/audio"
+	"dmd/backend/internal/services/display"
 	"dmd/backend/internal/services/images"
+	"dmd/backend/internal/services/maps"
 	"dmd/backend/internal/services/pdf"
+	"dmd/backend/internal/services/playback"
+	"dmd/backend/internal/services/scenes"
+	"dmd/backend/internal/services/soundboard"
 	"dmd/backend/internal/services/spotify"
 	"dmd/backend/internal/services/websocket"
+	"dmd/backend/internal/services/youtube"
 	"encoding/json"
 	"log/slog"
 	"net/http"
@@ -28,7 +47,9 @@ type Server struct {
 	wsManager      *websocket.Manager
 	imgService     *images.Service
 	pdfService     *pdf.Service
+	audioService   *audio.Service
 	spotifyService *spotify.Service
+	youtubeService *youtube.Service
 }
 
 // New is the main constructor for our server. It orchestrates the setup.
@@ -41,19 +62,40 @@ func New() *Server {
 	runMigrations(log, db)
 
 	// Initialize services.
-	wsManager := websocket.NewManager(log)
+	wsManager := websocket.NewManager(log, websocket.WithHeartbeat(websocket.Heartbeat{
+		PingInterval:   time.Duration(configs.WsPingInterval) * time.Second,
+		PongTimeout:    time.Duration(configs.WsPongTimeout) * time.Second,
+		WriteTimeout:   time.Duration(configs.WsWriteTimeout) * time.Second,
+		MaxMessageSize: configs.WsMaxMessageSize,
+	}), websocket.WithReplayBuffer(configs.WsReplayBuffer))
 	imgService := initImagesService(log, db, wsManager, configs.ImagesPath)
 	pdfService := initPdfService(log, db, wsManager, configs.PdfPath)
-	spotifyService := initSpotifyService(log, db, configs.SpotifyClientID, configs.SpotifyClientSecret, configs.SpotifyRedirectURI)
+	audioService := initAudioService(log, db, wsManager, configs.AudioPath)
+	playbackService := initPlaybackService(log, db, wsManager)
+	soundboardService := initSoundboardService(log, db, playbackService, wsManager)
+	spotifyService := initSpotifyService(log, db, wsManager, configs)
+	youtubeService := initYouTubeService(log, db, wsManager, configs.YouTubeMetadataLookup)
+	displayService := initDisplayService(log, db, wsManager)
+	sceneService := initSceneService(log, db, displayService, wsManager)
+	mapsService := initMapsService(log, db, wsManager, configs.ImagesPath)
+	annotationService := initAnnotationService(log, db, wsManager)
 
 	// Initialize router
 	router := routes.NewRouter(&common.RoutingServices{
-		Log:            log,
-		DbConnection:   db,
-		WsManager:      wsManager,
-		ImageService:   imgService,
-		PdfService:     pdfService,
-		SpotifyService: spotifyService,
+		Log:               log,
+		DbConnection:      db,
+		WsManager:         wsManager,
+		ImageService:      imgService,
+		PdfService:        pdfService,
+		AudioService:      audioService,
+		PlaybackService:   playbackService,
+		SoundboardService: soundboardService,
+		SpotifyService:    spotifyService,
+		YouTubeService:    youtubeService,
+		DisplayService:    displayService,
+		SceneService:      sceneService,
+		MapsService:       mapsService,
+		AnnotationService: annotationService,
 	}, configs.AssetsPath)
 
 	// Initialize server
@@ -65,7 +107,9 @@ func New() *Server {
 		wsManager:      wsManager,
 		imgService:     imgService,
 		pdfService:     pdfService,
+		audioService:   audioService,
 		spotifyService: spotifyService,
+		youtubeService: youtubeService,
 	}
 }
 
@@ -74,6 +118,10 @@ func (s *Server) RunServer() {
 	go s.wsManager.Run()
 	s.imgService.RunImagesDirWatcher()
 	s.pdfService.RunPdfDirWatcher()
+	s.pdfService.RunTextIndexer()
+	s.audioService.RunAudioDirWatcher()
+	s.audioService.RunTrackAnalyzer()
+	s.youtubeService.RunMetadataResolver()
 
 	s.log.Info("Starting server", "addr", s.server.Addr)
 	if err := s.server.ListenAndServe(); err != nil {
@@ -109,16 +157,81 @@ func initImagesService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manag
 
 func initPdfService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager, pdfPath string) *pdf.Service {
 	pdfRepo := images_repo.NewImagesRepository(db)
-	pdfService := pdf.NewService(log, pdfRepo, wsManager, pdfPath)
+	textRepo := pdf_text_repo.NewPdfTextRepository(db)
+	bookmarkRepo := bookmark_repo.NewPdfBookmarkRepository(db)
+	pdfService := pdf.NewService(log, pdfRepo, textRepo, bookmarkRepo, wsManager, pdfPath)
 	return pdfService
 }
 
-func initSpotifyService(log *slog.Logger, db *gorm.DB, clientID, clientSecret, redirectURI string) *spotify.Service {
-	if clientID == "" || clientSecret == "" {
+func initAudioService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager, audioPath string) *audio.Service {
+	trackRepo := track_repo.NewTrackRepository(db)
+	return audio.NewService(log, trackRepo, wsManager, audioPath)
+}
+
+func initPlaybackService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager) *playback.Service {
+	trackRepo := track_repo.NewTrackRepository(db)
+	playlistRepo := playlist_repo.NewPlaylistRepository(db)
+	return playback.NewService(log, trackRepo, playlistRepo, wsManager)
+}
+
+func initSoundboardService(log *slog.Logger, db *gorm.DB, playbackService *playback.Service, wsManager *websocket.Manager) *soundboard.Service {
+	soundboardRepo := soundboard_repo.NewSoundboardRepository(db)
+	trackRepo := track_repo.NewTrackRepository(db)
+	return soundboard.NewService(log, soundboardRepo, trackRepo, playbackService, wsManager)
+}
+
+func initDisplayService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager) *display.Service {
+	imgRepo := images_repo.NewImagesRepository(db)
+	return display.NewService(log, imgRepo, wsManager)
+}
+
+func initSceneService(log *slog.Logger, db *gorm.DB, displayService *display.Service, wsManager *websocket.Manager) *scenes.Service {
+	sceneRepo := scene_repo.NewSceneRepository(db)
+	return scenes.NewService(log, sceneRepo, displayService, wsManager)
+}
+
+func initMapsService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager, imagesPath string) *maps.Service {
+	imgRepo := images_repo.NewImagesRepository(db)
+	fogRepo := fog_repo.NewFogRepository(db)
+	tokenRepo := token_repo.NewMapTokenRepository(db)
+	templateRepo := character_template_repo.NewCharacterTemplateRepository(db)
+	combatRepo := combat_repo.NewCombatRepository(db)
+	return maps.NewService(log, imgRepo, fogRepo, tokenRepo, templateRepo, combatRepo, wsManager, imagesPath)
+}
+
+func initAnnotationService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager) *annotations.Service {
+	imgRepo := images_repo.NewImagesRepository(db)
+	annotationRepo := annotation_repo.NewAnnotationRepository(db)
+	return annotations.NewService(log, imgRepo, annotationRepo, wsManager)
+}
+
+func initSpotifyService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager, configs ServerConfig) *spotify.Service {
+	if configs.SpotifyClientID == "" || configs.SpotifyClientSecret == "" {
 		log.Warn("Spotify credentials not configured, Spotify features disabled")
 		return nil
 	}
-	return spotify.NewService(log, db, clientID, clientSecret, redirectURI)
+	keyPath := configs.SpotifyKeyPath
+	if keyPath == "" {
+		keyPath = newDefaultConfigs().SpotifyKeyPath // Config files written before the key file existed
+	}
+	tokenKey, err := spotify.LoadOrCreateTokenKey(keyPath)
+	if err != nil {
+		log.Error("Failed to load Spotify token key", "path", keyPath, "error", err)
+		os.Exit(1)
+	}
+	trackRepo := track_repo.NewTrackRepository(db)
+	playlistRepo := playlist_repo.NewPlaylistRepository(db)
+	return spotify.NewService(log, db, trackRepo, playlistRepo, wsManager, tokenKey,
+		configs.SpotifyClientID, configs.SpotifyClientSecret, configs.SpotifyRedirectURI)
+}
+
+func initYouTubeService(log *slog.Logger, db *gorm.DB, wsManager *websocket.Manager, metadataLookup bool) *youtube.Service {
+	trackRepo := track_repo.NewTrackRepository(db)
+	var resolver youtube.Resolver = youtube.OfflineResolver{}
+	if metadataLookup {
+		resolver = youtube.NewOEmbedResolver()
+	}
+	return youtube.NewService(log, trackRepo, resolver, wsManager)
 }
 
 func newHttpServer(router *mux.Router, port string) *http.Server {
@@ -139,8 +252,9 @@ func newHttpServer(router *mux.Router, port string) *http.Server {
 	}
 }
 
+// loadConfiguration reads the configuration file over the defaults, so keys missing from an older file keep their default.
 func loadConfiguration(log *slog.Logger, filename string) ServerConfig {
-	var config ServerConfig
+	config := newDefaultConfigs()
 
 	configFile, err := os.Open(filename)
 	if err != nil {
@@ -161,26 +275,42 @@ func loadConfiguration(log *slog.Logger, filename string) ServerConfig {
 
 func newDefaultConfigs() ServerConfig {
 	return ServerConfig{
-		ServerPort:          "8080",
-		DBPath:              "dmd.db",
-		AssetsPath:          "public",
-		ImagesPath:          "public/images",
-		PdfPath:             "public/pdf",
-		AudioPath:           "public/audio",
-		SpotifyClientID:     "",
-		SpotifyClientSecret: "",
-		SpotifyRedirectURI:  "http://127.0.0.1:8080/api/v1/auth/spotify/callback",
+		ServerPort:            "8080",
+		DBPath:                "dmd.db",
+		AssetsPath:            "public",
+		ImagesPath:            "public/images",
+		PdfPath:               "public/pdf",
+		AudioPath:             "public/audio",
+		SpotifyClientID:       "",
+		SpotifyClientSecret:   "",
+		SpotifyRedirectURI:    "http://127.0.0.1:8080/api/v1/auth/spotify/callback",
+		SpotifyKeyPath:        "spotify.key",
+		YouTubeMetadataLookup: false,
+		WsPingInterval:        30,
+		WsPongTimeout:         60,
+		WsWriteTimeout:        10,
+		WsMaxMessageSize:      1 << 20,
+		WsReplayBuffer:        websocket.DefaultReplayBuffer,
 	}
 }
 
 type ServerConfig struct {
-	ServerPort          string `json:"server_port"`
-	DBPath              string `json:"db_path"`
-	AssetsPath          string `json:"assets_path"`
-	ImagesPath          string `json:"images_path"`
-	AudioPath           string `json:"audios_path"`
-	PdfPath             string `json:"pdf_path"`
-	SpotifyClientID     string `json:"spotify_client_id"`
-	SpotifyClientSecret string `json:"spotify_client_secret"`
-	SpotifyRedirectURI  string `json:"spotify_redirect_uri"`
+	ServerPort            string `json:"server_port"`
+	DBPath                string `json:"db_path"`
+	AssetsPath            string `json:"assets_path"`
+	ImagesPath            string `json:"images_path"`
+	AudioPath             string `json:"audios_path"`
+	PdfPath               string `json:"pdf_path"`
+	SpotifyClientID       string `json:"spotify_client_id"`
+	SpotifyClientSecret   string `json:"spotify_client_secret"`
+	SpotifyRedirectURI    string `json:"spotify_redirect_uri"`
+	SpotifyKeyPath        string `json:"spotify_key_path"`        // Key file the stored Spotify tokens are encrypted with; created if missing
+	YouTubeMetadataLookup bool   `json:"youtube_metadata_lookup"` // Look up titles of imported YouTube videos online, off by default
+	// WebSocket heartbeat, in seconds; clients that do not answer pings within ws_pong_timeout are dropped.
+	// Unset values use the defaults.
+	WsPingInterval   int   `json:"ws_ping_interval"`
+	WsPongTimeout    int   `json:"ws_pong_timeout"`
+	WsWriteTimeout   int   `json:"ws_write_timeout"`
+	WsMaxMessageSize int64 `json:"ws_max_message_size"` // Bytes
+	WsReplayBuffer   int   `json:"ws_replay_buffer"`    // Recent events kept for clients that reconnect
 }
@@ -0,0 +1,275 @@
+// File: /internal/services/annotations/annotation_service.go
+package annotations
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	EventAnnotationOp    = "annotation_op"
+	EventAnnotationError = "annotation_error"
+	MessageAnnotationOp  = "annotation_op"
+)
+
+// Op types. Every change to an annotation layer is streamed to clients as one of these.
+const (
+	OpAdd    = "add"    // Annotation holds the new annotation
+	OpUpdate = "update" // Annotation holds the whole updated annotation
+	OpAppend = "append" // Points holds the points added to AnnotationID
+	OpDelete = "delete" // AnnotationID was removed
+	OpClear  = "clear"  // Every annotation on ImageID was removed
+)
+
+var ErrInvalidAnnotation = errors.New("invalid annotation")
+
+// Op is an incremental change to an image's annotation layer. Clients send ops
+// over WebSocket while drawing, and the server broadcasts each applied op.
+type Op struct {
+	Op           string             `json:"op"`
+	ImageID      uint               `json:"image_id"`
+	AnnotationID uint               `json:"annotation_id,omitempty"`
+	Visibility   string             `json:"visibility,omitempty"` // Ops on DM-only annotations only reach DM clients
+	Annotation   *images.Annotation `json:"annotation,omitempty"`
+	Points       []images.Point     `json:"points,omitempty"`
+}
+
+// OpError is sent back to the client whose op could not be applied.
+type OpError struct {
+	Op    Op     `json:"op"`
+	Error string `json:"error"`
+}
+
+// Service owns the vector annotation layers drawn over images.
+type Service struct {
+	log       *slog.Logger
+	imageRepo repos.ImagesRepository
+	repo      repos.AnnotationRepository
+	wsManager *wsService.Manager
+}
+
+func NewService(log *slog.Logger, imageRepo repos.ImagesRepository, repo repos.AnnotationRepository, wsManager *wsService.Manager) *Service {
+	svc := &Service{
+		log:       log,
+		imageRepo: imageRepo,
+		repo:      repo,
+		wsManager: wsManager,
+	}
+
+	// The DM's client streams ops while sketching instead of making a request per point.
+	wsManager.RegisterHandler(MessageAnnotationOp, svc.handleOpMessage)
+
+	return svc
+}
+
+// GetAnnotations returns an image's annotations, optionally only those with the given visibility.
+// Only the DM role sees DM-only annotations; every other role gets the shared ones.
+func (s *Service) GetAnnotations(imageID uint, visibility string, role wsService.Role) ([]*images.Annotation, error) {
+	if visibility != "" && !validVisibility(visibility) {
+		return nil, fmt.Errorf("%w: visibility %q", ErrInvalidAnnotation, visibility)
+	}
+	if _, err := s.imageRepo.GetImageByID(imageID); err != nil {
+		return nil, err
+	}
+	if role != wsService.RoleDM {
+		if visibility == images.VisibilityDM {
+			return []*images.Annotation{}, nil
+		}
+		visibility = images.VisibilityShared
+	}
+	return s.repo.GetAnnotations(imageID, filters.AnnotationFilters{Visibility: visibility})
+}
+
+// Add validates and stores a new annotation, then broadcasts it.
+func (s *Service) Add(annotation *images.Annotation) error {
+	if err := normalize(annotation); err != nil {
+		return err
+	}
+	if _, err := s.imageRepo.GetImageByID(annotation.ImageID); err != nil {
+		return err
+	}
+	annotation.ID = 0
+	if err := s.repo.CreateAnnotation(annotation); err != nil {
+		return err
+	}
+	s.broadcast(Op{Op: OpAdd, ImageID: annotation.ImageID, AnnotationID: annotation.ID, Visibility: annotation.Visibility, Annotation: annotation})
+	return nil
+}
+
+// Update replaces an existing annotation's drawing, then broadcasts it.
+func (s *Service) Update(annotation *images.Annotation) error {
+	existing, err := s.repo.GetAnnotationByID(annotation.ID)
+	if err != nil {
+		return err
+	}
+	annotation.ImageID = existing.ImageID
+	if err = normalize(annotation); err != nil {
+		return err
+	}
+	if err = s.repo.UpdateAnnotation(annotation); err != nil {
+		return err
+	}
+	updated, err := s.repo.GetAnnotationByID(annotation.ID)
+	if err != nil {
+		return err
+	}
+	*annotation = *updated
+
+	s.broadcast(Op{Op: OpUpdate, ImageID: annotation.ImageID, AnnotationID: annotation.ID, Visibility: annotation.Visibility, Annotation: annotation})
+	return nil
+}
+
+// AppendPoints extends a freehand stroke, then broadcasts only the new points.
+func (s *Service) AppendPoints(id uint, points []images.Point) (*images.Annotation, error) {
+	existing, err := s.repo.GetAnnotationByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Kind != images.AnnotationStroke {
+		return nil, fmt.Errorf("%w: points can only be appended to a %s", ErrInvalidAnnotation, images.AnnotationStroke)
+	}
+	if len(points) == 0 {
+		return existing, nil
+	}
+	annotation, err := s.repo.AppendAnnotationPoints(id, points)
+	if err != nil {
+		return nil, err
+	}
+	s.broadcast(Op{Op: OpAppend, ImageID: annotation.ImageID, AnnotationID: id, Visibility: annotation.Visibility, Points: points})
+	return annotation, nil
+}
+
+// Delete removes an annotation and broadcasts the removal.
+func (s *Service) Delete(id uint) error {
+	existing, err := s.repo.GetAnnotationByID(id)
+	if err != nil {
+		return err
+	}
+	if err = s.repo.DeleteAnnotation(id); err != nil {
+		return err
+	}
+	s.broadcast(Op{Op: OpDelete, ImageID: existing.ImageID, AnnotationID: id, Visibility: existing.Visibility})
+	return nil
+}
+
+// Clear removes every annotation on an image and broadcasts the removal.
+func (s *Service) Clear(imageID uint) error {
+	if _, err := s.imageRepo.GetImageByID(imageID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAnnotationsByImageID(imageID); err != nil {
+		return err
+	}
+	s.log.Info("Annotations cleared", "image_id", imageID)
+	s.broadcast(Op{Op: OpClear, ImageID: imageID})
+	return nil
+}
+
+// Apply performs an op received from a client.
+func (s *Service) Apply(op Op) error {
+	switch op.Op {
+	case OpAdd, OpUpdate:
+		if op.Annotation == nil {
+			return fmt.Errorf("%w: %s requires an annotation", ErrInvalidAnnotation, op.Op)
+		}
+		if op.Op == OpAdd {
+			op.Annotation.ImageID = op.ImageID
+			return s.Add(op.Annotation)
+		}
+		op.Annotation.ID = op.AnnotationID
+		return s.Update(op.Annotation)
+	case OpAppend:
+		_, err := s.AppendPoints(op.AnnotationID, op.Points)
+		return err
+	case OpDelete:
+		return s.Delete(op.AnnotationID)
+	case OpClear:
+		return s.Clear(op.ImageID)
+	default:
+		return fmt.Errorf("%w: unknown op %q", ErrInvalidAnnotation, op.Op)
+	}
+}
+
+// broadcast sends an op to every client, except that ops on DM-only annotations only go to DM clients.
+// Visibility may have changed in an update, so the other clients are told to delete the annotation instead.
+func (s *Service) broadcast(op Op) {
+	event := websocket.Event{Type: EventAnnotationOp, Payload: op}
+	if op.Visibility != images.VisibilityDM {
+		s.wsManager.Broadcast(event)
+		return
+	}
+	s.wsManager.SendToRole(wsService.RoleDM, event)
+	if op.Op == OpUpdate {
+		hide := Op{Op: OpDelete, ImageID: op.ImageID, AnnotationID: op.AnnotationID, Visibility: op.Visibility}
+		s.wsManager.SendWhere(func(c *wsService.Client) bool { return c.Role() != wsService.RoleDM },
+			websocket.Event{Type: EventAnnotationOp, Payload: hide})
+	}
+}
+
+func (s *Service) handleOpMessage(payload json.RawMessage, client *wsService.Client) {
+	var op Op
+	if err := json.Unmarshal(payload, &op); err != nil {
+		s.log.Warn("Failed to unmarshal annotation op", "error", err)
+		return
+	}
+	if err := client.RequireRole(wsService.RoleDM); err != nil {
+		s.wsManager.SendTo(client, websocket.Event{Type: EventAnnotationError, Payload: OpError{Op: op, Error: err.Error()}})
+		return
+	}
+	if err := s.Apply(op); err != nil {
+		s.log.Warn("Failed to apply annotation op", "op", op.Op, "image_id", op.ImageID, "error", err)
+		s.wsManager.SendTo(client, websocket.Event{Type: EventAnnotationError, Payload: OpError{Op: op, Error: err.Error()}})
+	}
+}
+
+// Helpers
+
+// normalize fills in defaults and checks the annotation's points match its kind.
+func normalize(a *images.Annotation) error {
+	if a.Visibility == "" {
+		a.Visibility = images.VisibilityDM
+	}
+	if !validVisibility(a.Visibility) {
+		return fmt.Errorf("%w: visibility %q", ErrInvalidAnnotation, a.Visibility)
+	}
+	if a.StrokeWidth <= 0 {
+		a.StrokeWidth = 2
+	}
+
+	var wantPoints int
+	switch a.Kind {
+	case images.AnnotationStroke:
+		if len(a.Points) == 0 {
+			return fmt.Errorf("%w: a stroke needs at least one point", ErrInvalidAnnotation)
+		}
+		return nil
+	case images.AnnotationRect, images.AnnotationEllipse, images.AnnotationCone, images.AnnotationLine:
+		wantPoints = 2
+	case images.AnnotationText, images.AnnotationSphere:
+		wantPoints = 1
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrInvalidAnnotation, a.Kind)
+	}
+
+	if len(a.Points) != wantPoints {
+		return fmt.Errorf("%w: %s needs %d points, got %d", ErrInvalidAnnotation, a.Kind, wantPoints, len(a.Points))
+	}
+	if a.Kind == images.AnnotationText && a.Text == "" {
+		return fmt.Errorf("%w: text label is empty", ErrInvalidAnnotation)
+	}
+	if a.IsAreaOfEffect() && a.SizeFeet <= 0 {
+		return fmt.Errorf("%w: %s needs a positive size_feet", ErrInvalidAnnotation, a.Kind)
+	}
+	return nil
+}
+
+func validVisibility(visibility string) bool {
+	return visibility == images.VisibilityDM || visibility == images.VisibilityShared
+}
@@ -0,0 +1,384 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gomp3 "github.com/hajimehoshi/go-mp3"
+)
+
+const (
+	// TargetLoudness is the level every analysed track is normalised to, in LUFS. Spotify and YouTube
+	// normalise to about the same level, so local tracks play at a volume close to theirs.
+	TargetLoudness = -14.0
+	// maxGain keeps very quiet tracks, e.g. near-silent ambience, from being boosted into clipping noise.
+	maxGain = 4.0 // About +12 dB
+	// waveformPoints is the number of peaks stored per track, enough for a full-width waveform.
+	waveformPoints = 1000
+)
+
+var ErrUnsupportedFormat = errors.New("audio format cannot be analysed")
+
+// analyzableFormats lists the formats that can be decoded for analysis.
+var analyzableFormats = map[string]bool{".mp3": true, ".wav": true}
+
+func canAnalyze(name string) bool {
+	return analyzableFormats[strings.ToLower(filepath.Ext(name))]
+}
+
+// trackAnalysis is what decoding a whole file tells about it.
+type trackAnalysis struct {
+	Duration time.Duration
+	Loudness *float64 // Nil for silent files
+	Peaks    []byte
+}
+
+// Gain returns the volume multiplier that brings the track to TargetLoudness, or nil for silent files.
+func (a trackAnalysis) Gain() *float64 {
+	if a.Loudness == nil {
+		return nil
+	}
+	gain := math.Min(math.Pow(10, (TargetLoudness-*a.Loudness)/20), maxGain)
+	gain = math.Round(gain*1000) / 1000
+	return &gain
+}
+
+// analyzeAudio decodes the file and measures its duration, loudness and waveform.
+func analyzeAudio(r io.ReadSeeker, name string) (trackAnalysis, error) {
+	var (
+		stream *pcmStream
+		err    error
+	)
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mp3":
+		stream, err = openMp3(r)
+	case ".wav":
+		stream, err = openWav(r)
+	default:
+		return trackAnalysis{}, fmt.Errorf("%w: %s", ErrUnsupportedFormat, filepath.Ext(name))
+	}
+	if err != nil {
+		return trackAnalysis{}, err
+	}
+	return stream.analyze()
+}
+
+// pcmStream is decoded audio: interleaved samples of a fixed width.
+type pcmStream struct {
+	r          io.Reader
+	sampleRate int
+	channels   int
+	frames     int64 // Samples per channel
+	width      int   // Bytes per sample
+	sample     func(b []byte) float64
+}
+
+func openMp3(r io.ReadSeeker) (*pcmStream, error) {
+	decoder, err := gomp3.NewDecoder(r)
+	if err != nil {
+		return nil, err
+	}
+	// The decoder always produces 16-bit stereo, so mono files are measured as if played on both speakers.
+	return &pcmStream{
+		r:          decoder,
+		sampleRate: decoder.SampleRate(),
+		channels:   2,
+		frames:     decoder.Length() / 4,
+		width:      2,
+		sample:     pcm16,
+	}, nil
+}
+
+// openWav reads the fmt chunk and returns the stream in the data chunk. Integer PCM of 8 to 32 bits
+// and 32 or 64-bit float are supported.
+func openWav(r io.Reader) (*pcmStream, error) {
+	riff := make([]byte, 12)
+	if _, err := io.ReadFull(r, riff); err != nil {
+		return nil, err
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, errors.New("not a wav file")
+	}
+
+	var stream *pcmStream
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return nil, err
+		}
+		id, size := string(chunk[0:4]), binary.LittleEndian.Uint32(chunk[4:8])
+		switch id {
+		case "fmt ":
+			if size > maxWavFormatSize {
+				return nil, errors.New("invalid wav fmt chunk")
+			}
+			format := make([]byte, paddedChunkSize(size))
+			if _, err := io.ReadFull(r, format); err != nil {
+				return nil, err
+			}
+			var err error
+			if stream, err = wavFormat(format[:size]); err != nil {
+				return nil, err
+			}
+		case "data":
+			if stream == nil {
+				return nil, errors.New("wav data chunk before fmt chunk")
+			}
+			stream.r = io.LimitReader(r, int64(size))
+			stream.frames = int64(size) / int64(stream.width*stream.channels)
+			return stream, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, paddedChunkSize(size)); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// maxWavFormatSize is well above the 40 bytes of WAVE_FORMAT_EXTENSIBLE, the largest fmt chunk in use.
+// Anything bigger is a corrupt file, and reading it would allocate whatever size the file claims.
+const maxWavFormatSize = 64
+
+// paddedChunkSize is the size a RIFF chunk takes up in the file; chunks are padded to an even size.
+// It is computed in int64 so a size near the uint32 limit does not wrap around.
+func paddedChunkSize(size uint32) int64 {
+	return int64(size) + int64(size%2)
+}
+
+func wavFormat(format []byte) (*pcmStream, error) {
+	if len(format) < 16 {
+		return nil, errors.New("invalid wav fmt chunk")
+	}
+	tag := binary.LittleEndian.Uint16(format[0:2])
+	channels := int(binary.LittleEndian.Uint16(format[2:4]))
+	sampleRate := int(binary.LittleEndian.Uint32(format[4:8]))
+	blockAlign := int(binary.LittleEndian.Uint16(format[12:14]))
+	// WAVE_FORMAT_EXTENSIBLE keeps the actual format in the first bytes of its sub-format GUID.
+	if tag == 0xFFFE && len(format) >= 26 {
+		tag = binary.LittleEndian.Uint16(format[24:26])
+	}
+	if channels == 0 || sampleRate == 0 || blockAlign%channels != 0 {
+		return nil, errors.New("invalid wav fmt chunk")
+	}
+
+	stream := &pcmStream{sampleRate: sampleRate, channels: channels, width: blockAlign / channels}
+	switch {
+	case tag == 1 && stream.width == 1:
+		stream.sample = pcm8
+	case tag == 1 && stream.width == 2:
+		stream.sample = pcm16
+	case tag == 1 && stream.width == 3:
+		stream.sample = pcm24
+	case tag == 1 && stream.width == 4:
+		stream.sample = pcm32
+	case tag == 3 && stream.width == 4:
+		stream.sample = float32Sample
+	case tag == 3 && stream.width == 8:
+		stream.sample = float64Sample
+	default:
+		return nil, fmt.Errorf("%w: wav format %d with %d-byte samples", ErrUnsupportedFormat, tag, stream.width)
+	}
+	return stream, nil
+}
+
+// Samples are scaled to [-1, 1].
+
+func pcm8(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+
+func pcm16(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+
+func pcm24(b []byte) float64 {
+	return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+}
+
+func pcm32(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+
+func float32Sample(b []byte) float64 {
+	return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+}
+
+func float64Sample(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+
+// analyze reads the whole stream once, measuring loudness as described in ITU-R BS.1770 (the basis of
+// EBU R128) and recording the peak level of each waveform slice.
+func (p *pcmStream) analyze() (trackAnalysis, error) {
+	if p.frames <= 0 {
+		return trackAnalysis{}, errors.New("audio stream is empty")
+	}
+	points := int64(min(waveformPoints, p.frames))
+	peaks := make([]float64, points)
+	filters := make([]kWeighting, p.channels)
+	for i := range filters {
+		filters[i] = newKWeighting(float64(p.sampleRate))
+	}
+	weights := channelWeights(p.channels)
+
+	// Energy is summed per 100 ms; gating blocks are four of these, so they overlap by 75%.
+	stepFrames := int64(max(p.sampleRate/10, 1))
+	var (
+		steps                   []float64
+		stepEnergy, totalEnergy float64
+		frame, frameInStep      int64
+		frameSize               = p.width * p.channels
+		buf                     = make([]byte, 4096*frameSize)
+		pending                 int
+	)
+	for frame < p.frames {
+		n, err := p.r.Read(buf[pending:])
+		pending += n
+		whole := pending - pending%frameSize
+		for offset := 0; offset < whole && frame < p.frames; offset += frameSize {
+			bucket := frame * points / p.frames
+			var energy float64
+			for ch := 0; ch < p.channels; ch++ {
+				s := p.sample(buf[offset+ch*p.width:])
+				peaks[bucket] = math.Max(peaks[bucket], math.Abs(s))
+				filtered := filters[ch].process(s)
+				energy += weights[ch] * filtered * filtered
+			}
+			stepEnergy += energy
+			totalEnergy += energy
+			frame++
+			if frameInStep++; frameInStep == stepFrames {
+				steps = append(steps, stepEnergy/float64(stepFrames))
+				stepEnergy, frameInStep = 0, 0
+			}
+		}
+		pending = copy(buf, buf[whole:pending])
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			return trackAnalysis{}, err
+		}
+	}
+	if frame == 0 {
+		return trackAnalysis{}, errors.New("audio stream is empty")
+	}
+
+	var blocks []float64
+	for i := 0; i+4 <= len(steps); i++ {
+		blocks = append(blocks, (steps[i]+steps[i+1]+steps[i+2]+steps[i+3])/4)
+	}
+	if len(blocks) == 0 {
+		// Shorter than one 400 ms block, e.g. a sound effect: measure the whole thing.
+		blocks = []float64{totalEnergy / float64(frame)}
+	}
+
+	result := trackAnalysis{
+		Duration: time.Duration(float64(frame) / float64(p.sampleRate) * float64(time.Second)),
+		Loudness: gatedLoudness(blocks),
+		Peaks:    make([]byte, points),
+	}
+	for i, peak := range peaks {
+		result.Peaks[i] = byte(math.Round(math.Min(peak, 1) * 255))
+	}
+	return result, nil
+}
+
+// gatedLoudness drops blocks below -70 LUFS, then blocks 10 LU below the loudness of the rest, and
+// returns the loudness of what remains.
+func gatedLoudness(blocks []float64) *float64 {
+	gate := func(blocks []float64, threshold float64) (kept []float64, mean float64) {
+		for _, power := range blocks {
+			if blockLoudness(power) > threshold {
+				kept = append(kept, power)
+				mean += power
+			}
+		}
+		if len(kept) > 0 {
+			mean /= float64(len(kept))
+		}
+		return kept, mean
+	}
+
+	kept, mean := gate(blocks, -70)
+	if len(kept) == 0 {
+		return nil
+	}
+	if _, mean = gate(kept, blockLoudness(mean)-10); mean == 0 {
+		return nil
+	}
+	loudness := math.Round(blockLoudness(mean)*100) / 100
+	return &loudness
+}
+
+func blockLoudness(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+// channelWeights follows BS.1770: surround channels count for more and the LFE channel is left out.
+// Channels are in WAV order (L, R, C, LFE, Ls, Rs).
+func channelWeights(channels int) []float64 {
+	weights := make([]float64, channels)
+	for i := range weights {
+		switch {
+		case channels < 6 || i < 3:
+			weights[i] = 1
+		case i == 3:
+			weights[i] = 0
+		case i < 6:
+			weights[i] = 1.41
+		default:
+			weights[i] = 1
+		}
+	}
+	return weights
+}
+
+// kWeighting is the BS.1770 pre-filter, a high shelf followed by a high pass, designed for any sample rate.
+type kWeighting struct {
+	shelf, highPass biquad
+}
+
+func newKWeighting(sampleRate float64) kWeighting {
+	// High shelf: +4 dB above about 1.5 kHz, modelling the effect of the head.
+	k := math.Tan(math.Pi * 1681.974450955533 / sampleRate)
+	q := 0.7071752369554196
+	vh := math.Pow(10, 3.999843853973347/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// High pass at about 38 Hz.
+	k = math.Tan(math.Pi * 38.13547087602444 / sampleRate)
+	q = 0.5003270373238773
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+	return kWeighting{shelf: shelf, highPass: highPass}
+}
+
+func (k *kWeighting) process(s float64) float64 {
+	return k.highPass.process(k.shelf.process(s))
+}
+
+// biquad is a second-order IIR filter in direct form II transposed.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(s float64) float64 {
+	out := f.b0*s + f.z1
+	f.z1 = f.b1*s - f.a1*out + f.z2
+	f.z2 = f.b2*s - f.a2*out
+	return out
+}
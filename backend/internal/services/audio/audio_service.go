@@ -0,0 +1,216 @@
+// File: /internal/services/audio/audio_service.go
+package audio
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	"dmd/backend/internal/services/watcher"
+	wsService "dmd/backend/internal/services/websocket"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const EventTracksUpdated = "tracks_updated"
+
+// writeSettleDelay is how long a file must go without writes before its tags are read again,
+// so a file that is still being copied is only read once it is complete.
+const writeSettleDelay = 2 * time.Second
+
+type Service struct {
+	log        *slog.Logger
+	repo       repos.TrackRepository
+	wsManager  *wsService.Manager
+	dirWatcher *watcher.Service
+	audioPath  string
+
+	pendingMu sync.Mutex
+	pending   map[string]*time.Timer // Metadata refreshes waiting for writes to settle, by file name
+
+	analysisMu    sync.Mutex
+	analysisQueue map[string]bool // Files waiting for the background analyser
+	analysisWake  chan struct{}
+}
+
+func NewService(log *slog.Logger, repo repos.TrackRepository, wsManager *wsService.Manager, audioPath string) *Service {
+	svc := &Service{
+		log:       log,
+		repo:      repo,
+		wsManager: wsManager,
+		audioPath: audioPath,
+		pending:   make(map[string]*time.Timer),
+
+		analysisQueue: make(map[string]bool),
+		analysisWake:  make(chan struct{}, 1),
+	}
+	svc.dirWatcher = watcher.NewService(log, audioPath, svc.audioDirEventHandler)
+
+	// Unlike images, the audio folder is optional, so create it rather than failing to watch it.
+	if err := os.MkdirAll(audioPath, 0755); err != nil {
+		log.Error("Failed to create audio directory", "path", audioPath, "error", err)
+	}
+	svc.SyncTracksWithDatabase()
+
+	return svc
+}
+
+func (s *Service) RunAudioDirWatcher() {
+	s.dirWatcher.Run()
+}
+
+func (s *Service) GetAudioPath() string {
+	return s.audioPath
+}
+
+// SyncTracksWithDatabase performs a two-way sync between the audio directory and the local tracks in the database.
+func (s *Service) SyncTracksWithDatabase() {
+	diskFiles, err := s.getDiskTracks()
+	if err != nil {
+		s.log.Error("Failed to read audio directory", "error", err)
+		return
+	}
+
+	dbTracks, err := s.getDatabaseTracks()
+	if err != nil {
+		s.log.Error("Failed to fetch local tracks from DB", "error", err)
+		return
+	}
+
+	s.removeOrphanedTracks(diskFiles, dbTracks)
+
+	s.addNewTracks(diskFiles, dbTracks)
+}
+
+func (s *Service) audioDirEventHandler(event fsnotify.Event) error {
+	if event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		s.SyncTracksWithDatabase()
+		s.wsManager.Broadcast(websocket.Event{Type: EventTracksUpdated})
+	}
+	if event.Has(fsnotify.Write) && isAudioFile(event.Name) {
+		s.scheduleMetadataRefresh(filepath.Base(event.Name))
+	}
+
+	return nil
+}
+
+// Helpers
+
+// getDiskTracks returns the names of the audio files in the audio directory, which are the SourceIDs of local tracks.
+func (s *Service) getDiskTracks() (map[string]bool, error) {
+	diskFiles := make(map[string]bool)
+	files, err := os.ReadDir(s.audioPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if !file.IsDir() && isAudioFile(file.Name()) {
+			diskFiles[file.Name()] = true
+		}
+	}
+	return diskFiles, nil
+}
+
+// getDatabaseTracks returns a map of SourceIDs to IDs for every local track.
+func (s *Service) getDatabaseTracks() (map[string]uint, error) {
+	tracks, err := s.repo.GetAllTracks(filters.TrackFilters{Source: audio.SourceLocal})
+	if err != nil {
+		return nil, err
+	}
+	dbTracks := make(map[string]uint)
+	for _, track := range tracks {
+		dbTracks[track.SourceID] = track.ID
+	}
+	return dbTracks, nil
+}
+
+// removeOrphanedTracks soft-deletes local tracks whose file no longer exists.
+func (s *Service) removeOrphanedTracks(diskFiles map[string]bool, dbTracks map[string]uint) {
+	for sourceID, id := range dbTracks {
+		if _, foundOnDisk := diskFiles[sourceID]; !foundOnDisk {
+			if err := s.repo.DeleteTrack(id); err != nil {
+				s.log.Error("Failed to delete orphan track record", "file", sourceID, "error", err)
+			} else {
+				s.log.Info("Removed orphan track record from database", "file", sourceID)
+			}
+		}
+	}
+}
+
+// addNewTracks creates track records for new audio files.
+// If a soft-deleted record exists for the same file, it is restored and its metadata re-read.
+func (s *Service) addNewTracks(diskFiles map[string]bool, dbTracks map[string]uint) {
+	for fileName := range diskFiles {
+		if _, foundInDb := dbTracks[fileName]; foundInDb {
+			continue
+		}
+		if restored, err := s.repo.RestoreSoftDeletedBySourceID(audio.SourceLocal, fileName); err == nil && restored {
+			s.log.Info("Restored previously deleted track record", "file", fileName)
+			s.refreshMetadata(fileName)
+			s.queueAnalysis(fileName)
+			continue
+		}
+
+		track := &audio.Track{Source: audio.SourceLocal, SourceID: fileName}
+		s.applyMetadata(track)
+		if err := s.repo.CreateTrack(track); err != nil {
+			s.log.Error("Failed to create track record", "file", fileName, "error", err)
+		} else {
+			s.log.Info("New track found and added to database", "file", fileName)
+			s.queueAnalysis(fileName)
+		}
+	}
+}
+
+// applyMetadata fills in the track's title, artist and duration from its file.
+// Unreadable files still get a title from their name.
+func (s *Service) applyMetadata(track *audio.Track) {
+	meta, err := readMetadata(filepath.Join(s.audioPath, track.SourceID))
+	if err != nil {
+		s.log.Warn("Failed to read audio metadata", "file", track.SourceID, "error", err)
+	}
+	track.Title = meta.Title
+	track.Artist = meta.Artist
+	track.Duration = meta.DurationSeconds()
+}
+
+// refreshMetadata re-reads a local track's file and saves any changes.
+func (s *Service) refreshMetadata(fileName string) {
+	track, err := s.repo.GetTrackBySourceID(audio.SourceLocal, fileName)
+	if err != nil {
+		return
+	}
+	before := *track
+	s.applyMetadata(track)
+	if track.Title == before.Title && track.Artist == before.Artist && track.Duration == before.Duration {
+		return
+	}
+	if err = s.repo.UpdateTrack(track); err != nil {
+		s.log.Error("Failed to update track metadata", "file", fileName, "error", err)
+		return
+	}
+	s.wsManager.Broadcast(websocket.Event{Type: EventTracksUpdated})
+}
+
+// scheduleMetadataRefresh re-reads and re-analyses a file once writes to it have settled.
+func (s *Service) scheduleMetadataRefresh(fileName string) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if timer, ok := s.pending[fileName]; ok {
+		timer.Reset(writeSettleDelay)
+		return
+	}
+	s.pending[fileName] = time.AfterFunc(writeSettleDelay, func() {
+		s.pendingMu.Lock()
+		delete(s.pending, fileName)
+		s.pendingMu.Unlock()
+		s.refreshMetadata(fileName)
+		s.queueAnalysis(fileName)
+	})
+}
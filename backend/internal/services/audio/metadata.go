@@ -0,0 +1,160 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/tcolgate/mp3"
+)
+
+// contentTypes lists the audio formats picked up from the audio directory.
+var contentTypes = map[string]string{
+	".mp3":  "audio/mpeg",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".aac":  "audio/aac",
+}
+
+func isAudioFile(name string) bool {
+	_, ok := contentTypes[strings.ToLower(filepath.Ext(name))]
+	return ok
+}
+
+// trackMetadata is what can be learned about a track from its file.
+type trackMetadata struct {
+	Title    string
+	Artist   string
+	Duration time.Duration
+}
+
+// DurationSeconds rounds the duration to the whole seconds stored on a Track.
+func (m trackMetadata) DurationSeconds() uint {
+	return uint(math.Round(m.Duration.Seconds()))
+}
+
+// readMetadata reads title and artist from the file's tags, falling back to the file name.
+// The duration comes from an ID3 TLEN frame when present, otherwise from the audio stream
+// itself for MP3, WAV and FLAC files.
+func readMetadata(path string) (trackMetadata, error) {
+	fileName := filepath.Base(path)
+	meta := trackMetadata{Title: strings.TrimSuffix(fileName, filepath.Ext(fileName))}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return meta, err
+	}
+	defer f.Close()
+
+	if tags, err := tag.ReadFrom(f); err == nil {
+		if title := strings.TrimSpace(tags.Title()); title != "" {
+			meta.Title = title
+		}
+		meta.Artist = strings.TrimSpace(tags.Artist())
+		meta.Duration = tagDuration(tags)
+	}
+	if meta.Duration > 0 {
+		return meta, nil
+	}
+
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		return meta, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		meta.Duration, err = mp3Duration(f)
+	case ".wav":
+		meta.Duration, err = wavDuration(f)
+	case ".flac":
+		meta.Duration, err = flacDuration(f)
+	}
+	return meta, err
+}
+
+// tagDuration reads the ID3v2 TLEN frame, the track length in milliseconds.
+func tagDuration(tags tag.Metadata) time.Duration {
+	raw, ok := tags.Raw()["TLEN"].(string)
+	if !ok {
+		return 0
+	}
+	ms, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil || ms <= 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// mp3Duration adds up the length of every MPEG frame, which is exact for VBR files too.
+func mp3Duration(r io.ReadSeeker) (time.Duration, error) {
+	if err := skipID3v2(r); err != nil {
+		return 0, err
+	}
+	var (
+		total   time.Duration
+		frame   mp3.Frame
+		skipped int
+	)
+	decoder := mp3.NewDecoder(r)
+	for {
+		if err := decoder.Decode(&frame, &skipped); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return total, nil
+			}
+			return total, err
+		}
+		total += frame.Duration()
+	}
+}
+
+// skipID3v2 moves past a leading ID3v2 tag, whose bytes could otherwise be mistaken for frame syncs.
+func skipID3v2(r io.ReadSeeker) error {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.HasPrefix(header, []byte("ID3")) {
+		_, err = r.Seek(0, io.SeekStart)
+		return err
+	}
+	// The tag size is a 28-bit "syncsafe" integer, seven bits per byte.
+	size := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+	_, err := r.Seek(10+size, io.SeekStart)
+	return err
+}
+
+// wavDuration divides the number of frames in the data chunk by the sample rate. Only the formats the
+// analyser can decode are understood (see openWav).
+func wavDuration(r io.Reader) (time.Duration, error) {
+	stream, err := openWav(r)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(float64(stream.frames) / float64(stream.sampleRate) * float64(time.Second)), nil
+}
+
+// flacDuration reads the sample rate and total sample count from the STREAMINFO block,
+// which the format requires to come first.
+func flacDuration(r io.Reader) (time.Duration, error) {
+	header := make([]byte, 4+4+18)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, err
+	}
+	if string(header[0:4]) != "fLaC" {
+		return 0, errors.New("not a flac file")
+	}
+	info := header[8:]
+	sampleRate := uint64(info[10])<<12 | uint64(info[11])<<4 | uint64(info[12])>>4
+	totalSamples := uint64(info[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(info[14:18]))
+	if sampleRate == 0 {
+		return 0, errors.New("invalid flac sample rate")
+	}
+	return time.Duration(float64(totalSamples) / float64(sampleRate) * float64(time.Second)), nil
+}
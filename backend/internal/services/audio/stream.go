@@ -0,0 +1,81 @@
+package audio
+
+import (
+	"dmd/backend/internal/model/audio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNotLocalTrack  = errors.New("track is not a local file")
+	ErrUnsafeSourceID = errors.New("track source id points outside the audio directory")
+)
+
+// TrackFile is an open local audio file, ready to be served.
+type TrackFile struct {
+	*os.File
+	Info        os.FileInfo
+	ContentType string
+}
+
+// OpenTrackFile opens the file behind a local track, which must be inside the audio directory.
+// The caller must close the returned file.
+func (s *Service) OpenTrackFile(id uint) (*TrackFile, error) {
+	track, err := s.repo.GetTrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if track.Source != audio.SourceLocal {
+		return nil, fmt.Errorf("%w: track %d has source %q", ErrNotLocalTrack, id, track.Source)
+	}
+	file, info, err := s.openLocalFile(track.SourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, ok := contentTypes[strings.ToLower(filepath.Ext(track.SourceID))]
+	if !ok {
+		contentType = "application/octet-stream"
+	}
+	return &TrackFile{File: file, Info: info, ContentType: contentType}, nil
+}
+
+// openLocalFile opens a file in the audio directory. The file is opened through an os.Root on the
+// audio directory, so neither ".." nor a symlink in the SourceID can reach a file outside it.
+func (s *Service) openLocalFile(sourceID string) (*os.File, os.FileInfo, error) {
+	if !filepath.IsLocal(sourceID) {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnsafeSourceID, sourceID)
+	}
+
+	root, err := os.OpenRoot(s.audioPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer root.Close()
+
+	file, err := root.Open(sourceID)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("%w: %v", ErrUnsafeSourceID, err)
+		}
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if !info.Mode().IsRegular() {
+		file.Close()
+		return nil, nil, fmt.Errorf("%w: %q is not a regular file", ErrUnsafeSourceID, sourceID)
+	}
+	return file, info, nil
+}
+
+// ETag identifies the file's current contents by its size and modification time.
+func (f *TrackFile) ETag() string {
+	return fmt.Sprintf(`"%x-%x"`, f.Info.Size(), f.Info.ModTime().UnixNano())
+}
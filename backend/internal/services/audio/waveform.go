@@ -0,0 +1,163 @@
+package audio
+
+import (
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/websocket"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrNoWaveform  = errors.New("track has no waveform")
+	ErrNotAnalyzed = errors.New("track has not been analysed yet")
+)
+
+// Waveform is a track's peak levels, for drawing a scrubbable waveform.
+type Waveform struct {
+	Duration uint      `json:"duration"` // Seconds
+	Loudness *float64  `json:"loudness"` // LUFS
+	Gain     *float64  `json:"gain"`
+	Peaks    []float64 `json:"peaks"` // Peak level of equal slices of the track, 0 to 1
+}
+
+// GetWaveform returns a local track's waveform with at most points peaks, or all of them when points is 0.
+// Decoding a long track takes too long to do within a request, so a track the background analyser has not
+// reached yet is queued for it and ErrNotAnalyzed is returned; tracks_updated is broadcast once it is done.
+func (s *Service) GetWaveform(id uint, points int) (*Waveform, error) {
+	track, err := s.repo.GetTrackByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if track.Source != audio.SourceLocal {
+		return nil, fmt.Errorf("%w: track %d has source %q", ErrNotLocalTrack, id, track.Source)
+	}
+	if !canAnalyze(track.SourceID) {
+		return nil, fmt.Errorf("%w: %s files are not analysed", ErrNoWaveform, filepath.Ext(track.SourceID))
+	}
+	if track.AnalyzedAt == nil {
+		s.queueAnalysis(track.SourceID)
+		return nil, fmt.Errorf("%w: %q", ErrNotAnalyzed, track.SourceID)
+	}
+	if track.Waveform == nil {
+		return nil, fmt.Errorf("%w: %q could not be decoded", ErrNoWaveform, track.SourceID)
+	}
+
+	return &Waveform{
+		Duration: track.Duration,
+		Loudness: track.Loudness,
+		Gain:     track.Gain,
+		Peaks:    resamplePeaks(track.Waveform, points),
+	}, nil
+}
+
+// RunTrackAnalyzer analyses every local track whose file changed since it was last analysed, then keeps
+// analysing files as they are added or written to.
+func (s *Service) RunTrackAnalyzer() {
+	dbTracks, err := s.getDatabaseTracks()
+	if err != nil {
+		s.log.Error("Failed to fetch local tracks from DB", "error", err)
+	}
+	for fileName := range dbTracks {
+		s.queueAnalysis(fileName)
+	}
+	go s.analyzeQueued()
+}
+
+// Helpers
+
+// queueAnalysis marks a file for the background analyser. Files queued before RunTrackAnalyzer wait for it.
+func (s *Service) queueAnalysis(fileName string) {
+	if !canAnalyze(fileName) {
+		return
+	}
+	s.analysisMu.Lock()
+	s.analysisQueue[fileName] = true
+	s.analysisMu.Unlock()
+
+	select {
+	case s.analysisWake <- struct{}{}:
+	default: // Already woken
+	}
+}
+
+func (s *Service) analyzeQueued() {
+	for range s.analysisWake {
+		updated := false
+		for {
+			fileName, ok := s.nextQueued()
+			if !ok {
+				break
+			}
+			track, err := s.repo.GetTrackBySourceID(audio.SourceLocal, fileName)
+			if err != nil {
+				continue // Removed since it was queued
+			}
+			analyzed, err := s.analyzeTrack(track)
+			if err != nil {
+				s.log.Error("Failed to save track analysis", "file", fileName, "error", err)
+			}
+			updated = updated || analyzed
+		}
+		if updated {
+			s.wsManager.Broadcast(websocket.Event{Type: EventTracksUpdated})
+		}
+	}
+}
+
+func (s *Service) nextQueued() (string, bool) {
+	s.analysisMu.Lock()
+	defer s.analysisMu.Unlock()
+	for fileName := range s.analysisQueue {
+		delete(s.analysisQueue, fileName)
+		return fileName, true
+	}
+	return "", false
+}
+
+// analyzeTrack decodes the track's file and saves its duration, loudness and waveform, unless the file has
+// not changed since it was last analysed. A file that cannot be decoded is saved without a waveform, so it
+// is not tried again until it changes. It reports whether the track was updated.
+func (s *Service) analyzeTrack(track *audio.Track) (bool, error) {
+	file, info, err := s.openLocalFile(track.SourceID)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	if track.AnalyzedAt != nil && !info.ModTime().After(*track.AnalyzedAt) {
+		return false, nil
+	}
+
+	start := time.Now()
+	result, err := analyzeAudio(file, track.SourceID)
+	track.AnalyzedAt = &start
+	if err != nil {
+		s.log.Warn("Failed to analyse audio file", "file", track.SourceID, "error", err)
+		track.Loudness, track.Gain, track.Waveform = nil, nil, nil
+	} else {
+		track.Duration = uint(math.Round(result.Duration.Seconds()))
+		track.Loudness = result.Loudness
+		track.Gain = result.Gain()
+		track.Waveform = result.Peaks
+		s.log.Info("Analysed audio file", "file", track.SourceID, "loudness", track.Loudness, "took", time.Since(start))
+	}
+	if err = s.repo.UpdateTrackAnalysis(track); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// resamplePeaks scales the stored peaks to 0-1, keeping the highest peak of each group when fewer points are asked for.
+func resamplePeaks(waveform []byte, points int) []float64 {
+	if points <= 0 || points > len(waveform) {
+		points = len(waveform)
+	}
+	peaks := make([]float64, points)
+	for i, level := range waveform {
+		bucket := i * points / len(waveform)
+		peaks[bucket] = math.Max(peaks[bucket], float64(level)/255)
+	}
+	return peaks
+}
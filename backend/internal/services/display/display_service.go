@@ -0,0 +1,164 @@
+// File: /internal/services/display/display_service.go
+package display
+
+import (
+	"dmd/backend/internal/model/display"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventDisplayUpdated        = "display_updated"
+	MessageDisplayStateRequest = "display_state_request"
+)
+
+var (
+	ErrInvalidLayout = errors.New("invalid layout type")
+	ErrInvalidSlot   = errors.New("invalid slot")
+	ErrImageNotFound = errors.New("image not found")
+)
+
+// Service owns the current player display state. Every change is broadcast
+// to all connected clients so any number of displays stay in sync.
+type Service struct {
+	log       *slog.Logger
+	repo      repos.ImagesRepository
+	wsManager *wsService.Manager
+
+	mu    sync.RWMutex
+	state display.State
+}
+
+func NewService(log *slog.Logger, repo repos.ImagesRepository, wsManager *wsService.Manager) *Service {
+	svc := &Service{
+		log:       log,
+		repo:      repo,
+		wsManager: wsManager,
+		state:     display.State{Slots: []display.Slot{}},
+	}
+
+	// Displays that (re)connect ask for the current state instead of waiting for the next change.
+	wsManager.RegisterHandler(MessageDisplayStateRequest, svc.handleStateRequest)
+	wsManager.RegisterResyncHandler(func(client *wsService.Client) { svc.handleStateRequest(nil, client) })
+
+	return svc
+}
+
+// GetState returns a snapshot of the current display state.
+func (s *Service) GetState() display.State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// SetState validates and replaces the display state, then broadcasts it.
+func (s *Service) SetState(state display.State) (display.State, error) {
+	if err := s.normalizeState(&state); err != nil {
+		return display.State{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state.UpdatedAt = time.Now()
+	s.state = state
+
+	s.log.Info("Display state updated", "layout", state.LayoutType, "slots", len(state.Slots))
+	s.broadcast(state)
+	return state, nil
+}
+
+// ApplyPreset shows a saved preset layout on the player display.
+func (s *Service) ApplyPreset(preset *images.PresetLayout) (display.State, error) {
+	state := display.State{LayoutType: preset.LayoutType, Slots: make([]display.Slot, 0, len(preset.Slots))}
+	for _, slot := range preset.Slots {
+		state.Slots = append(state.Slots, display.Slot{
+			SlotID:    slot.SlotID,
+			ImageID:   slot.ImageID,
+			Zoom:      slot.Zoom,
+			Page:      slot.Page,
+			PositionY: slot.PositionY,
+		})
+	}
+	return s.SetState(state)
+}
+
+// Clear blanks the player display and broadcasts the empty state.
+func (s *Service) Clear() display.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := display.State{Slots: []display.Slot{}, UpdatedAt: time.Now()}
+	s.state = state
+
+	s.log.Info("Display state cleared")
+	s.broadcast(state)
+	return state
+}
+
+// broadcast sends the new state to every client. Callers hold mu, so displays get states in the order they were stored.
+func (s *Service) broadcast(state display.State) {
+	s.wsManager.Broadcast(websocket.Event{Type: EventDisplayUpdated, Payload: state})
+}
+
+func (s *Service) handleStateRequest(_ json.RawMessage, client *wsService.Client) {
+	s.wsManager.SendTo(client, websocket.Event{Type: EventDisplayUpdated, Payload: s.GetState()})
+}
+
+// Helpers
+
+// normalizeState checks the layout and slots, fills in defaults and resolves slot images.
+func (s *Service) normalizeState(state *display.State) error {
+	slotCount := state.LayoutType.SlotCount()
+	if slotCount == 0 {
+		return fmt.Errorf("%w: %q", ErrInvalidLayout, state.LayoutType)
+	}
+	if len(state.Slots) > slotCount {
+		return fmt.Errorf("%w: layout %q has %d slots, got %d", ErrInvalidSlot, state.LayoutType, slotCount, len(state.Slots))
+	}
+
+	if state.Slots == nil {
+		state.Slots = []display.Slot{}
+	}
+
+	seen := make(map[int]bool)
+	for i := range state.Slots {
+		slot := &state.Slots[i]
+		if slot.SlotID < 0 || slot.SlotID >= slotCount || seen[slot.SlotID] {
+			return fmt.Errorf("%w: slot_id %d", ErrInvalidSlot, slot.SlotID)
+		}
+		seen[slot.SlotID] = true
+
+		if slot.Zoom <= 0 {
+			slot.Zoom = 1
+		}
+		if slot.Page < 1 {
+			slot.Page = 1
+		}
+
+		slot.Image = nil
+		if slot.ImageID == 0 {
+			continue
+		}
+		img, err := s.repo.GetImageByID(slot.ImageID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %d", ErrImageNotFound, slot.ImageID)
+			}
+			return err
+		}
+		slot.Image = img
+	}
+
+	sort.Slice(state.Slots, func(i, j int) bool { return state.Slots[i].SlotID < state.Slots[j].SlotID })
+	return nil
+}
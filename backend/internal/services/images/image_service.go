@@ -107,7 +107,8 @@ func (s *Service) getDiskImages(dir string) (map[string]bool, error) {
 		return nil, err
 	}
 	for _, file := range files {
-		if !file.IsDir() {
+		// Hidden files are not images, e.g. .DS_Store or a crop that is still being written.
+		if !file.IsDir() && !strings.HasPrefix(file.Name(), ".") {
 			// Construct the path relative to the 'public' directory, e.g., "images/my-image.png"
 			diskFiles[filepath.Join(filepath.Base(dir), file.Name())] = true
 		}
@@ -115,15 +116,19 @@ func (s *Service) getDiskImages(dir string) (map[string]bool, error) {
 	return diskFiles, nil
 }
 
-// getDatabaseImages fetches all image records and returns a map of file paths to IDs.
+// getDatabaseImages fetches the records of files in the images directory and returns a map of file paths to IDs.
+// Entries stored elsewhere (e.g. PDFs under "pdf/") are owned by their own services and left alone.
 func (s *Service) getDatabaseImages() (map[string]uint, error) {
 	dbImages, err := s.repo.GetAllImages(filters.ImagesFilters{})
 	if err != nil {
 		return nil, err
 	}
+	prefix := filepath.Base(s.imagesPath) + string(filepath.Separator)
 	dbFilePaths := make(map[string]uint)
 	for _, img := range dbImages {
-		dbFilePaths[img.FilePath] = img.ID
+		if strings.HasPrefix(img.FilePath, prefix) {
+			dbFilePaths[img.FilePath] = img.ID
+		}
 	}
 	return dbFilePaths, nil
 }
@@ -0,0 +1,82 @@
+package maps
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/model/websocket"
+	"fmt"
+)
+
+const EventFogUpdated = "fog_updated"
+
+// GetFogMask returns the map's fog operations newer than sinceVersion.
+// Pass 0 to get everything needed to rebuild the mask from scratch.
+func (s *Service) GetFogMask(imageID uint, sinceVersion uint) (*images.FogMask, error) {
+	if _, err := s.getMap(imageID); err != nil {
+		return nil, err
+	}
+	version, err := s.fogRepo.GetFogVersion(imageID)
+	if err != nil {
+		return nil, err
+	}
+	ops, err := s.fogRepo.GetFogOperations(imageID, sinceVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &images.FogMask{ImageID: imageID, Version: version, Operations: ops}, nil
+}
+
+// RevealArea clears the fog inside the given shape.
+func (s *Service) RevealArea(imageID uint, shape string, points []images.Point) (*images.FogOperation, error) {
+	return s.applyFogArea(imageID, images.FogOpReveal, shape, points)
+}
+
+// HideArea covers the given shape with fog again.
+func (s *Service) HideArea(imageID uint, shape string, points []images.Point) (*images.FogOperation, error) {
+	return s.applyFogArea(imageID, images.FogOpHide, shape, points)
+}
+
+// ResetFog covers the whole map with fog.
+func (s *Service) ResetFog(imageID uint) (*images.FogOperation, error) {
+	if _, err := s.getMap(imageID); err != nil {
+		return nil, err
+	}
+	return s.appendFogOperation(&images.FogOperation{ImageID: imageID, Op: images.FogOpReset})
+}
+
+// Helpers
+
+func (s *Service) applyFogArea(imageID uint, op string, shape string, points []images.Point) (*images.FogOperation, error) {
+	if err := validateShape(shape, points); err != nil {
+		return nil, err
+	}
+	if _, err := s.getMap(imageID); err != nil {
+		return nil, err
+	}
+	return s.appendFogOperation(&images.FogOperation{ImageID: imageID, Op: op, Shape: shape, Points: points})
+}
+
+// appendFogOperation stores the operation and broadcasts it as an incremental mask update.
+func (s *Service) appendFogOperation(fogOp *images.FogOperation) (*images.FogOperation, error) {
+	if err := s.fogRepo.AppendFogOperation(fogOp); err != nil {
+		return nil, err
+	}
+	s.wsManager.Broadcast(websocket.Event{Type: EventFogUpdated, Payload: fogOp})
+	s.log.Info("Fog mask updated", "image_id", fogOp.ImageID, "op", fogOp.Op, "version", fogOp.Version)
+	return fogOp, nil
+}
+
+func validateShape(shape string, points []images.Point) error {
+	switch shape {
+	case images.FogShapeRect:
+		if len(points) != 2 {
+			return fmt.Errorf("%w: rect needs 2 points, got %d", ErrInvalidShape, len(points))
+		}
+	case images.FogShapePolygon:
+		if len(points) < 3 {
+			return fmt.Errorf("%w: polygon needs at least 3 points, got %d", ErrInvalidShape, len(points))
+		}
+	default:
+		return fmt.Errorf("%w: unknown shape %q", ErrInvalidShape, shape)
+	}
+	return nil
+}
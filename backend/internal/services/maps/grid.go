@@ -0,0 +1,167 @@
+package maps
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/model/websocket"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+)
+
+const EventGridUpdated = "grid_updated"
+
+// GridUpdate is the payload broadcast when a map's grid calibration changes.
+type GridUpdate struct {
+	ImageID uint           `json:"image_id"`
+	Grid    images.MapGrid `json:"grid"`
+}
+
+// Measurement is the grid distance between two pixel positions on a map.
+type Measurement struct {
+	From  images.GridCell `json:"from"`
+	To    images.GridCell `json:"to"`
+	Cells int             `json:"cells"`
+	Feet  float64         `json:"feet"`
+}
+
+// GetGrid returns the map's grid calibration. A grid calibrated before its extent was stored
+// gets it from the size of the map image.
+func (s *Service) GetGrid(imageID uint) (images.MapGrid, error) {
+	img, err := s.getMap(imageID)
+	if err != nil {
+		return images.MapGrid{}, err
+	}
+	if img.Grid.IsCalibrated() && !img.Grid.HasExtent() {
+		return s.backfillExtent(img), nil
+	}
+	return img.Grid, nil
+}
+
+// CalibrateGrid validates and stores the map's grid, then broadcasts it. Columns and rows
+// left out are worked out from the size of the map image.
+func (s *Service) CalibrateGrid(imageID uint, grid images.MapGrid) (images.MapGrid, error) {
+	if grid.FeetPerCell == 0 {
+		grid.FeetPerCell = images.DefaultFeetPerCell
+	}
+	if !grid.IsCalibrated() || grid.FeetPerCell < 0 {
+		return images.MapGrid{}, fmt.Errorf("%w: type must be %q or %q with a positive cell size",
+			ErrInvalidGrid, images.GridTypeSquare, images.GridTypeHex)
+	}
+	if grid.Columns < 0 || grid.Rows < 0 {
+		return images.MapGrid{}, fmt.Errorf("%w: columns and rows must not be negative", ErrInvalidGrid)
+	}
+	if !grid.HasExtent() {
+		img, err := s.getMap(imageID)
+		if err != nil {
+			return images.MapGrid{}, err
+		}
+		columns, rows, err := s.imageExtent(img, grid)
+		if err != nil {
+			return images.MapGrid{}, fmt.Errorf("%w: columns and rows are required, the map's size could not be read: %v", ErrInvalidGrid, err)
+		}
+		if grid.Columns == 0 {
+			grid.Columns = columns
+		}
+		if grid.Rows == 0 {
+			grid.Rows = rows
+		}
+		if !grid.HasExtent() {
+			return images.MapGrid{}, fmt.Errorf("%w: no whole cell fits on the map", ErrInvalidGrid)
+		}
+	}
+	return s.saveGrid(imageID, grid)
+}
+
+// ClearGrid removes the map's grid calibration.
+func (s *Service) ClearGrid(imageID uint) error {
+	_, err := s.saveGrid(imageID, images.MapGrid{})
+	return err
+}
+
+// CellAt converts a pixel position on the map to a grid cell.
+func (s *Service) CellAt(imageID uint, x, y float64) (images.GridCell, error) {
+	grid, err := s.getCalibratedGrid(imageID)
+	if err != nil {
+		return images.GridCell{}, err
+	}
+	return grid.CellAt(x, y), nil
+}
+
+// Measure returns the grid distance between two pixel positions on the map.
+func (s *Service) Measure(imageID uint, from, to images.Point) (*Measurement, error) {
+	grid, err := s.getCalibratedGrid(imageID)
+	if err != nil {
+		return nil, err
+	}
+	fromCell := grid.CellAt(from.X, from.Y)
+	toCell := grid.CellAt(to.X, to.Y)
+	return &Measurement{
+		From:  fromCell,
+		To:    toCell,
+		Cells: grid.Distance(fromCell, toCell),
+		Feet:  grid.DistanceFeet(fromCell, toCell),
+	}, nil
+}
+
+// Helpers
+
+func (s *Service) saveGrid(imageID uint, grid images.MapGrid) (images.MapGrid, error) {
+	if _, err := s.getMap(imageID); err != nil {
+		return images.MapGrid{}, err
+	}
+	if err := s.imageRepo.UpdateImageGrid(imageID, grid); err != nil {
+		return images.MapGrid{}, err
+	}
+	s.wsManager.Broadcast(websocket.Event{Type: EventGridUpdated, Payload: GridUpdate{ImageID: imageID, Grid: grid}})
+	s.log.Info("Map grid updated", "image_id", imageID, "type", grid.Type, "cell_size", grid.CellSize)
+	return grid, nil
+}
+
+// backfillExtent stores the extent of a grid calibrated before columns and rows were kept.
+// If the image cannot be read the grid is returned as it is, without an extent.
+func (s *Service) backfillExtent(img *images.ImageEntry) images.MapGrid {
+	grid := img.Grid
+	columns, rows, err := s.imageExtent(img, grid)
+	if err != nil {
+		s.log.Warn("Failed to read map size for its grid extent", "image_id", img.ID, "path", img.FilePath, "error", err)
+		return grid
+	}
+	grid.Columns, grid.Rows = columns, rows
+	if err = s.imageRepo.UpdateImageGrid(img.ID, grid); err != nil {
+		s.log.Error("Failed to save map grid extent", "image_id", img.ID, "error", err)
+		return grid
+	}
+	s.log.Info("Map grid extent filled in from the image size", "image_id", img.ID, "columns", columns, "rows", rows)
+	return grid
+}
+
+// imageExtent returns how many columns and rows of the grid fit on the map image.
+func (s *Service) imageExtent(img *images.ImageEntry, grid images.MapGrid) (int, int, error) {
+	file, err := os.Open(filepath.Join(filepath.Dir(s.imagesPath), img.FilePath))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0, err
+	}
+	columns, rows := grid.Extent(config.Width, config.Height)
+	return columns, rows, nil
+}
+
+// getCalibratedGrid returns the map's grid, failing if it has not been calibrated yet.
+func (s *Service) getCalibratedGrid(imageID uint) (images.MapGrid, error) {
+	grid, err := s.GetGrid(imageID)
+	if err != nil {
+		return images.MapGrid{}, err
+	}
+	if !grid.IsCalibrated() {
+		return images.MapGrid{}, ErrNotCalibrated
+	}
+	return grid, nil
+}
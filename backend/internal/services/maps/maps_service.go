@@ -0,0 +1,68 @@
+// File: /internal/services/maps/maps_service.go
+package maps
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/platform/storage/repos"
+	wsService "dmd/backend/internal/services/websocket"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+var (
+	ErrNotAMap       = errors.New("image is not a map")
+	ErrInvalidShape  = errors.New("invalid shape")
+	ErrInvalidGrid   = errors.New("invalid grid")
+	ErrNotCalibrated = errors.New("map grid is not calibrated")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrOutsideGrid   = errors.New("position is outside the map grid")
+)
+
+// Service owns the interactive state layered on top of map images.
+type Service struct {
+	log          *slog.Logger
+	imageRepo    repos.ImagesRepository
+	fogRepo      repos.FogRepository
+	tokenRepo    repos.MapTokenRepository
+	templateRepo repos.CharacterTemplateRepository
+	combatRepo   repos.CombatRepository
+	wsManager    *wsService.Manager
+	imagesPath   string
+}
+
+func NewService(
+	log *slog.Logger,
+	imageRepo repos.ImagesRepository,
+	fogRepo repos.FogRepository,
+	tokenRepo repos.MapTokenRepository,
+	templateRepo repos.CharacterTemplateRepository,
+	combatRepo repos.CombatRepository,
+	wsManager *wsService.Manager,
+	imagesPath string,
+) *Service {
+	return &Service{
+		log:          log,
+		imageRepo:    imageRepo,
+		fogRepo:      fogRepo,
+		tokenRepo:    tokenRepo,
+		templateRepo: templateRepo,
+		combatRepo:   combatRepo,
+		wsManager:    wsManager,
+		imagesPath:   imagesPath,
+	}
+}
+
+// Helpers
+
+// getMap loads an image entry and checks that it is a map.
+func (s *Service) getMap(imageID uint) (*images.ImageEntry, error) {
+	img, err := s.imageRepo.GetImageByID(imageID)
+	if err != nil {
+		return nil, err
+	}
+	if img.Type != images.ImageTypeMap {
+		return nil, fmt.Errorf("%w: %q has type %q", ErrNotAMap, img.Name, img.Type)
+	}
+	return img, nil
+}
@@ -0,0 +1,229 @@
+package maps
+
+import (
+	"dmd/backend/internal/model/combat"
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/model/websocket"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventTokensUpdated = "tokens_updated"
+	EventTokenMoved    = "token_moved"
+)
+
+// TokensUpdate is the payload broadcast when tokens are placed on or removed from a map.
+type TokensUpdate struct {
+	ImageID uint `json:"image_id"`
+}
+
+// TokenMove is the payload broadcast when a token moves. It is also stored in the
+// active combat's history.
+type TokenMove struct {
+	ImageID uint            `json:"image_id"`
+	TokenID uint            `json:"token_id"`
+	From    images.GridCell `json:"from"`
+	To      images.GridCell `json:"to"`
+	Feet    float64         `json:"feet"`
+}
+
+// GetTokens returns the tokens placed on a map.
+func (s *Service) GetTokens(imageID uint) ([]*images.MapToken, error) {
+	if _, err := s.getMap(imageID); err != nil {
+		return nil, err
+	}
+	tokens, err := s.tokenRepo.GetTokensByImageID(imageID)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		setFootprint(token)
+	}
+	return tokens, nil
+}
+
+// GetToken returns a single token.
+func (s *Service) GetToken(tokenID uint) (*images.MapToken, error) {
+	token, err := s.tokenRepo.GetTokenByID(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	setFootprint(token)
+	return token, nil
+}
+
+// PlaceToken puts a new token for a character template or combatant on a calibrated map.
+func (s *Service) PlaceToken(token *images.MapToken) error {
+	if token.CharacterTemplateID == nil && token.CombatantID == nil {
+		return fmt.Errorf("%w: a character template or combatant is required", ErrInvalidToken)
+	}
+	grid, err := s.getCalibratedGrid(token.ImageID)
+	if err != nil {
+		return err
+	}
+	cells, err := s.checkTokenSources(token)
+	if err != nil {
+		return err
+	}
+	if err = checkCell(grid, images.GridCell{Col: token.Col, Row: token.Row}, cells); err != nil {
+		return err
+	}
+
+	token.ID = 0
+	if err = s.tokenRepo.CreateToken(token); err != nil {
+		return err
+	}
+	setFootprint(token)
+
+	s.log.Info("Token placed", "image_id", token.ImageID, "token_id", token.ID)
+	s.wsManager.Broadcast(websocket.Event{Type: EventTokensUpdated, Payload: TokensUpdate{ImageID: token.ImageID}})
+	return nil
+}
+
+// MoveToken moves a token to a cell, records the move if a combat is running and broadcasts it.
+func (s *Service) MoveToken(tokenID uint, to images.GridCell) (*TokenMove, error) {
+	token, err := s.tokenRepo.GetTokenByID(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	grid, err := s.getCalibratedGrid(token.ImageID)
+	if err != nil {
+		return nil, err
+	}
+	setFootprint(token)
+	if err = checkCell(grid, to, token.Cells); err != nil {
+		return nil, err
+	}
+	if err = s.tokenRepo.MoveToken(tokenID, to.Col, to.Row); err != nil {
+		return nil, err
+	}
+
+	from := images.GridCell{Col: token.Col, Row: token.Row}
+	move := &TokenMove{
+		ImageID: token.ImageID,
+		TokenID: tokenID,
+		From:    from,
+		To:      to,
+		Feet:    grid.DistanceFeet(from, to),
+	}
+	s.recordCombatMove(token, move)
+
+	s.wsManager.Broadcast(websocket.Event{Type: EventTokenMoved, Payload: move})
+	return move, nil
+}
+
+// MoveTokenToPoint moves a token to the cell containing a pixel position on its map.
+func (s *Service) MoveTokenToPoint(tokenID uint, at images.Point) (*TokenMove, error) {
+	token, err := s.tokenRepo.GetTokenByID(tokenID)
+	if err != nil {
+		return nil, err
+	}
+	cell, err := s.CellAt(token.ImageID, at.X, at.Y)
+	if err != nil {
+		return nil, err
+	}
+	return s.MoveToken(tokenID, cell)
+}
+
+// RemoveToken takes a token off its map.
+func (s *Service) RemoveToken(tokenID uint) error {
+	token, err := s.tokenRepo.GetTokenByID(tokenID)
+	if err != nil {
+		return err
+	}
+	if err = s.tokenRepo.DeleteToken(tokenID); err != nil {
+		return err
+	}
+	s.log.Info("Token removed", "image_id", token.ImageID, "token_id", tokenID)
+	s.wsManager.Broadcast(websocket.Event{Type: EventTokensUpdated, Payload: TokensUpdate{ImageID: token.ImageID}})
+	return nil
+}
+
+// Helpers
+
+// checkTokenSources makes sure the template and combatant a token refers to exist, and returns
+// how many cells across the token covers.
+func (s *Service) checkTokenSources(token *images.MapToken) (int, error) {
+	size := ""
+	if token.CharacterTemplateID != nil {
+		template, err := s.templateRepo.GetByID(*token.CharacterTemplateID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, fmt.Errorf("%w: character template %d not found", ErrInvalidToken, *token.CharacterTemplateID)
+			}
+			return 0, err
+		}
+		size = template.Size
+	}
+	if token.CombatantID != nil {
+		if _, err := s.combatRepo.GetCombatantByID(*token.CombatantID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return 0, fmt.Errorf("%w: combatant %d not found", ErrInvalidToken, *token.CombatantID)
+			}
+			return 0, err
+		}
+	}
+	return images.CreatureSizeCells(size), nil
+}
+
+// recordCombatMove adds the move to the active combat's history when the token's combatant
+// takes part in it. The move itself has already been saved, so failures are logged rather than returned.
+func (s *Service) recordCombatMove(token *images.MapToken, move *TokenMove) {
+	if token.CombatantID == nil {
+		return
+	}
+	activeCombat, err := s.combatRepo.GetActiveCombat()
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.log.Error("Failed to load active combat", "error", err)
+		}
+		return
+	}
+	combatant, err := s.combatRepo.GetCombatantByID(*token.CombatantID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.log.Error("Failed to load token combatant", "token_id", token.ID, "error", err)
+		}
+		return
+	}
+	if combatant.CombatID != activeCombat.ID {
+		return
+	}
+
+	details, err := json.Marshal(move)
+	if err != nil {
+		s.log.Error("Failed to encode token move", "error", err)
+		return
+	}
+	entry := &combat.HistoryEntry{
+		CombatID:    activeCombat.ID,
+		Round:       activeCombat.Round,
+		Event:       combat.HistoryTokenMoved,
+		CombatantID: token.CombatantID,
+		Details:     details,
+	}
+	if err = s.combatRepo.AddHistoryEntry(entry); err != nil {
+		s.log.Error("Failed to record token move", "combat_id", activeCombat.ID, "token_id", token.ID, "error", err)
+	}
+}
+
+// checkCell makes sure a token anchored at cell, covering cells across, lies wholly on the grid.
+func checkCell(grid images.MapGrid, cell images.GridCell, cells int) error {
+	far := images.GridCell{Col: cell.Col + cells - 1, Row: cell.Row + cells - 1}
+	if !grid.Contains(cell) || !grid.Contains(far) {
+		return fmt.Errorf("%w: a token %d cells across does not fit at col %d, row %d", ErrOutsideGrid, cells, cell.Col, cell.Row)
+	}
+	return nil
+}
+
+func setFootprint(token *images.MapToken) {
+	size := ""
+	if token.CharacterTemplate != nil {
+		size = token.CharacterTemplate.Size
+	}
+	token.Cells = images.CreatureSizeCells(size)
+}
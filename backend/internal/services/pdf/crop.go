@@ -0,0 +1,160 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+var ErrInvalidCrop = errors.New("invalid crop rectangle")
+
+// CropRect is a region of a page given as fractions of the page size, with the origin at the top left.
+type CropRect struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+func (c CropRect) isValid() bool {
+	return c.X >= 0 && c.Y >= 0 && c.Width > 0 && c.Height > 0 && c.X+c.Width <= 1 && c.Y+c.Height <= 1
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// CropPage renders a region of a page (numbered from 1) and saves it as a PNG in destDir.
+// The file name is derived from name, or from the PDF's name when empty, and never overwrites
+// an existing file. It returns the name of the file that was written.
+func (s *Service) CropPage(id uint, page int, rect CropRect, dpi int, name string, destDir string) (string, error) {
+	if dpi < MinDPI || dpi > MaxDPI {
+		return "", fmt.Errorf("%w: %d, must be between %d and %d", ErrInvalidDPI, dpi, MinDPI, MaxDPI)
+	}
+	if !rect.isValid() {
+		return "", fmt.Errorf("%w: must have a positive size and lie within the page", ErrInvalidCrop)
+	}
+	entry, err := s.getPdf(id)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := s.renderCrop(s.fullPath(entry), page, rect, dpi)
+	if err != nil {
+		return "", err
+	}
+
+	if name = sanitizeFileName(name); name == "" {
+		name = sanitizeFileName(fmt.Sprintf("%s_p%d", entry.Name, page))
+	}
+	fileName, err := s.saveUnique(destDir, name, data)
+	if err != nil {
+		return "", err
+	}
+	s.log.Info("Cropped pdf page", "id", id, "page", page, "file", fileName)
+	return fileName, nil
+}
+
+// Helpers
+
+func (s *Service) renderCrop(pdfFile string, page int, rect CropRect, dpi int) ([]byte, error) {
+	s.renderMu.Lock()
+	defer s.renderMu.Unlock()
+
+	doc, err := fitz.New(pdfFile)
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+
+	if page < 1 || page > doc.NumPage() {
+		return nil, fmt.Errorf("%w: %d, document has %d pages", ErrInvalidPage, page, doc.NumPage())
+	}
+	img, err := doc.ImageDPI(page-1, float64(dpi))
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	region := image.Rect(
+		b.Min.X+int(rect.X*float64(b.Dx())),
+		b.Min.Y+int(rect.Y*float64(b.Dy())),
+		b.Min.X+int((rect.X+rect.Width)*float64(b.Dx())),
+		b.Min.Y+int((rect.Y+rect.Height)*float64(b.Dy())),
+	)
+	if region.Empty() {
+		return nil, fmt.Errorf("%w: region is smaller than a pixel at %d dpi", ErrInvalidCrop, dpi)
+	}
+
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img.SubImage(region)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// saveUnique writes the PNG to a hidden temp file in destDir and then hard-links it to a free name, so the
+// images watcher only ever sees a complete file and an existing file is never replaced. The temp file is
+// created next to the target because links and renames cannot cross volumes.
+func (s *Service) saveUnique(destDir, baseName string, data []byte) (string, error) {
+	tmp, err := os.CreateTemp(destDir, ".crop-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err = tmp.Close(); err != nil {
+		return "", err
+	}
+
+	for i := 0; ; i++ {
+		fileName := baseName + ".png"
+		if i > 0 {
+			fileName = fmt.Sprintf("%s_%d.png", baseName, i)
+		}
+		saved, err := linkOrRename(tmp.Name(), filepath.Join(destDir, fileName))
+		if err != nil {
+			return "", err
+		}
+		if saved {
+			return fileName, nil
+		}
+	}
+}
+
+// linkOrRename moves src to dst unless dst exists, and reports whether it did. File systems without hard
+// links, e.g. FAT and exFAT, fall back to a rename, which cannot refuse to replace a file by itself.
+func linkOrRename(src, dst string) (bool, error) {
+	err := os.Link(src, dst)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, fs.ErrExist):
+		return false, nil
+	}
+	if _, err = os.Lstat(dst); err == nil {
+		return false, nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, err
+	}
+	if err = os.Rename(src, dst); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sanitizeFileName keeps a name usable as a file name in the images directory.
+func sanitizeFileName(name string) string {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".png")
+	return strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_")
+}
@@ -0,0 +1,146 @@
+package pdf
+
+import (
+	"dmd/backend/internal/model/images"
+	"dmd/backend/internal/model/websocket"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+const EventBookmarksUpdated = "pdf_bookmarks_updated"
+
+var ErrInvalidBookmark = errors.New("invalid bookmark")
+
+// BookmarksUpdate is the payload broadcast when a PDF's bookmarks change.
+type BookmarksUpdate struct {
+	ImageID uint `json:"image_id"`
+}
+
+// GetOutline returns the PDF's table of contents as a tree. PDFs without one return an empty tree.
+func (s *Service) GetOutline(id uint) ([]*images.OutlineEntry, error) {
+	entry, err := s.getPdf(id)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := fitz.New(s.fullPath(entry))
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+
+	toc, err := doc.ToC()
+	if errors.Is(err, fitz.ErrLoadOutline) {
+		return []*images.OutlineEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buildOutlineTree(toc), nil
+}
+
+// GetBookmarks returns the DM's bookmarks in a PDF.
+func (s *Service) GetBookmarks(id uint) ([]*images.PdfBookmark, error) {
+	if _, err := s.getPdf(id); err != nil {
+		return nil, err
+	}
+	return s.bookmarkRepo.GetBookmarks(id)
+}
+
+// AddBookmark validates and stores a new bookmark.
+func (s *Service) AddBookmark(bookmark *images.PdfBookmark) error {
+	if err := s.validateBookmark(bookmark); err != nil {
+		return err
+	}
+	bookmark.ID = 0
+	if err := s.bookmarkRepo.CreateBookmark(bookmark); err != nil {
+		return err
+	}
+	s.broadcastBookmarks(bookmark.ImageID)
+	return nil
+}
+
+// UpdateBookmark renames or retargets an existing bookmark.
+func (s *Service) UpdateBookmark(bookmark *images.PdfBookmark) error {
+	existing, err := s.bookmarkRepo.GetBookmarkByID(bookmark.ID)
+	if err != nil {
+		return err
+	}
+	bookmark.ImageID = existing.ImageID
+	if err = s.validateBookmark(bookmark); err != nil {
+		return err
+	}
+	if err = s.bookmarkRepo.UpdateBookmark(bookmark); err != nil {
+		return err
+	}
+	updated, err := s.bookmarkRepo.GetBookmarkByID(bookmark.ID)
+	if err != nil {
+		return err
+	}
+	*bookmark = *updated
+	s.broadcastBookmarks(bookmark.ImageID)
+	return nil
+}
+
+func (s *Service) DeleteBookmark(id uint) error {
+	existing, err := s.bookmarkRepo.GetBookmarkByID(id)
+	if err != nil {
+		return err
+	}
+	if err = s.bookmarkRepo.DeleteBookmark(id); err != nil {
+		return err
+	}
+	s.broadcastBookmarks(existing.ImageID)
+	return nil
+}
+
+// Helpers
+
+func (s *Service) validateBookmark(bookmark *images.PdfBookmark) error {
+	bookmark.Name = strings.TrimSpace(bookmark.Name)
+	if bookmark.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidBookmark)
+	}
+	pageCount, err := s.GetPageCount(bookmark.ImageID)
+	if err != nil {
+		return err
+	}
+	if bookmark.Page < 1 || bookmark.Page > pageCount {
+		return fmt.Errorf("%w: page %d, document has %d pages", ErrInvalidBookmark, bookmark.Page, pageCount)
+	}
+	return nil
+}
+
+func (s *Service) broadcastBookmarks(imageID uint) {
+	s.wsManager.Broadcast(websocket.Event{Type: EventBookmarksUpdated, Payload: BookmarksUpdate{ImageID: imageID}})
+}
+
+// buildOutlineTree nests the flat, depth-first outline MuPDF returns by each entry's level.
+func buildOutlineTree(toc []fitz.Outline) []*images.OutlineEntry {
+	roots := []*images.OutlineEntry{}
+	var parents []*images.OutlineEntry // parents[i] is the latest entry at level i+1
+
+	for _, item := range toc {
+		node := &images.OutlineEntry{Title: item.Title, URI: item.URI, Children: []*images.OutlineEntry{}}
+		if item.Page >= 0 {
+			node.Page = item.Page + 1
+			node.URI = ""
+		}
+
+		level := max(item.Level, 1)
+		if level > len(parents)+1 {
+			level = len(parents) + 1 // Tolerate skipped levels
+		}
+		parents = parents[:level-1]
+		if level == 1 {
+			roots = append(roots, node)
+		} else {
+			parent := parents[level-2]
+			parent.Children = append(parent.Children, node)
+		}
+		parents = append(parents, node)
+	}
+	return roots
+}
@@ -0,0 +1,173 @@
+package pdf
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/images"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+const (
+	DefaultDPI   = 150
+	MinDPI       = 24
+	MaxDPI       = 300
+	ThumbnailDPI = 36
+)
+
+var (
+	ErrNotAPdf     = errors.New("image is not a pdf")
+	ErrInvalidPage = errors.New("invalid page")
+	ErrInvalidDPI  = errors.New("invalid dpi")
+)
+
+// GetPageCount returns the number of pages in a PDF, reading it from the file if it was never counted.
+func (s *Service) GetPageCount(id uint) (int, error) {
+	entry, err := s.getPdf(id)
+	if err != nil {
+		return 0, err
+	}
+	if entry.PageCount > 0 {
+		return entry.PageCount, nil
+	}
+	if err = s.updatePageCount(entry); err != nil {
+		return 0, err
+	}
+	return entry.PageCount, nil
+}
+
+// RenderPage returns the path of a PNG of a single page (numbered from 1) at the given DPI.
+// Renders are cached on disk and reused until the PDF file changes.
+func (s *Service) RenderPage(id uint, page int, dpi int) (string, error) {
+	if dpi < MinDPI || dpi > MaxDPI {
+		return "", fmt.Errorf("%w: %d, must be between %d and %d", ErrInvalidDPI, dpi, MinDPI, MaxDPI)
+	}
+	entry, err := s.getPdf(id)
+	if err != nil {
+		return "", err
+	}
+	pdfFile := s.fullPath(entry)
+	pdfInfo, err := os.Stat(pdfFile)
+	if err != nil {
+		return "", err
+	}
+
+	cached := filepath.Join(s.pageCacheDir(id), fmt.Sprintf("p%d_%ddpi.png", page, dpi))
+	if isFresh(cached, pdfInfo) {
+		return cached, nil
+	}
+
+	// MuPDF contexts are not shared between renders; serializing them also keeps memory bounded.
+	s.renderMu.Lock()
+	defer s.renderMu.Unlock()
+	if isFresh(cached, pdfInfo) {
+		return cached, nil
+	}
+
+	doc, err := fitz.New(pdfFile)
+	if err != nil {
+		return "", err
+	}
+	defer doc.Close()
+
+	if page < 1 || page > doc.NumPage() {
+		return "", fmt.Errorf("%w: %d, document has %d pages", ErrInvalidPage, page, doc.NumPage())
+	}
+	png, err := doc.ImagePNG(page-1, float64(dpi))
+	if err != nil {
+		return "", err
+	}
+
+	if err = writeFileAtomic(cached, png); err != nil {
+		return "", err
+	}
+	s.log.Info("Rendered pdf page", "id", id, "page", page, "dpi", dpi)
+	return cached, nil
+}
+
+// Helpers
+
+// getPdf loads an image entry and checks that it is a PDF.
+func (s *Service) getPdf(id uint) (*images.ImageEntry, error) {
+	entry, err := s.repo.GetImageByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type != images.ImageTypePDF {
+		return nil, fmt.Errorf("%w: %q has type %q", ErrNotAPdf, entry.Name, entry.Type)
+	}
+	return entry, nil
+}
+
+func (s *Service) fullPath(entry *images.ImageEntry) string {
+	return filepath.Join(filepath.Dir(s.pdfPath), entry.FilePath)
+}
+
+func (s *Service) pageCacheDir(id uint) string {
+	return filepath.Join(s.cachePath, fmt.Sprint(id))
+}
+
+// updatePageCount reads the page count from the PDF file and stores it on the entry.
+func (s *Service) updatePageCount(entry *images.ImageEntry) error {
+	count, err := countPages(s.fullPath(entry))
+	if err != nil {
+		return err
+	}
+	entry.PageCount = count
+	return s.repo.UpdateImageEntry(entry)
+}
+
+// fillMissingPageCounts counts the pages of PDFs indexed before page counts were stored.
+func (s *Service) fillMissingPageCounts() {
+	entries, err := s.repo.GetAllImages(filters.ImagesFilters{Type: images.ImageTypePDF})
+	if err != nil {
+		s.log.Error("Failed to fetch pdf entries from DB", "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if entry.PageCount > 0 {
+			continue
+		}
+		if err = s.updatePageCount(entry); err != nil {
+			s.log.Warn("Failed to count pdf pages", "path", entry.FilePath, "error", err)
+		}
+	}
+}
+
+func countPages(path string) (int, error) {
+	doc, err := fitz.New(path)
+	if err != nil {
+		return 0, err
+	}
+	defer doc.Close()
+	return doc.NumPage(), nil
+}
+
+// isFresh reports whether a cached render exists and is newer than the PDF it came from.
+func isFresh(cached string, pdfInfo os.FileInfo) bool {
+	info, err := os.Stat(cached)
+	return err == nil && !info.ModTime().Before(pdfInfo.ModTime())
+}
+
+// writeFileAtomic writes through a temporary file so readers never see a partial PNG.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".render-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
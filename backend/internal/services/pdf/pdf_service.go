@@ -11,27 +11,53 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/fsnotify/fsnotify"
 )
 
 type Service struct {
-	log        *slog.Logger
-	repo       repos.ImagesRepository
-	wsManager  *wsService.Manager
-	dirWatcher *watcher.Service
-	pdfPath    string
+	log          *slog.Logger
+	repo         repos.ImagesRepository
+	textRepo     repos.PdfTextRepository
+	bookmarkRepo repos.PdfBookmarkRepository
+	wsManager    *wsService.Manager
+	dirWatcher   *watcher.Service
+	pdfPath      string
+	cachePath    string // Rendered pages, one directory per PDF entry
+
+	renderMu sync.Mutex
+
+	indexMu    sync.Mutex
+	indexQueue map[uint]bool // PDF entries waiting for the background text indexer
+	indexWake  chan struct{}
 }
 
-func NewService(log *slog.Logger, repo repos.ImagesRepository, wsManager *wsService.Manager, pdfPath string) *Service {
+func NewService(
+	log *slog.Logger,
+	repo repos.ImagesRepository,
+	textRepo repos.PdfTextRepository,
+	bookmarkRepo repos.PdfBookmarkRepository,
+	wsManager *wsService.Manager,
+	pdfPath string,
+) *Service {
 	svc := &Service{
-		log:       log,
-		repo:      repo,
-		wsManager: wsManager,
-		pdfPath:   pdfPath,
+		log:          log,
+		repo:         repo,
+		textRepo:     textRepo,
+		bookmarkRepo: bookmarkRepo,
+		wsManager:    wsManager,
+		pdfPath:      pdfPath,
+		cachePath:    filepath.Join(filepath.Dir(pdfPath), "cache", "pdf"),
+
+		indexQueue: make(map[uint]bool),
+		indexWake:  make(chan struct{}, 1),
 	}
 	svc.dirWatcher = watcher.NewService(log, pdfPath, svc.pdfDirEventHandler)
 
+	if !textRepo.HasFullTextIndex() {
+		log.Warn("SQLite has no FTS5, so PDF search falls back to slower LIKE matching without ranking; build with -tags sqlite_fts5 to enable it")
+	}
 	svc.SyncPdfEntriesWithDatabase()
 
 	return svc
@@ -66,6 +92,7 @@ func (s *Service) DeletePdfFile(id uint) error {
 }
 
 // SyncPdfEntriesWithDatabase performs a two-way sync between the pdf directory and the database.
+// Extracting text takes a while for a large book, so PDFs are only queued for the background text indexer.
 func (s *Service) SyncPdfEntriesWithDatabase() {
 	diskPdfs, err := s.getDiskPdfs(s.pdfPath)
 	if err != nil {
@@ -81,6 +108,8 @@ func (s *Service) SyncPdfEntriesWithDatabase() {
 
 	s.removeOrphanedPdfs(diskPdfs, dbPdfs)
 	s.addNewPdfs(diskPdfs, dbPdfs)
+	s.fillMissingPageCounts()
+	s.queueAllText()
 }
 
 func (s *Service) pdfDirEventHandler(event fsnotify.Event) error {
@@ -128,6 +157,12 @@ func (s *Service) removeOrphanedPdfs(diskFiles map[string]bool, dbPdfs map[strin
 			} else {
 				s.log.Info("Removed orphan pdf record from database", "path", path)
 			}
+			if err := s.textRepo.DeletePdfPages(id); err != nil {
+				s.log.Warn("Failed to remove pdf text index", "path", path, "error", err)
+			}
+			if err := os.RemoveAll(s.pageCacheDir(id)); err != nil {
+				s.log.Warn("Failed to remove rendered pages", "path", path, "error", err)
+			}
 		}
 	}
 }
@@ -145,6 +180,11 @@ func (s *Service) addNewPdfs(diskFiles map[string]bool, dbPdfs map[string]uint)
 				Type:     images.ImageTypePDF,
 				FilePath: path,
 			}
+			if count, err := countPages(s.fullPath(entry)); err == nil {
+				entry.PageCount = count
+			} else {
+				s.log.Warn("Failed to count pdf pages", "file", fileName, "error", err)
+			}
 			if err := s.repo.CreateImageEntry(entry); err != nil {
 				s.log.Error("Failed to create pdf record", "file", fileName, "error", err)
 			} else {
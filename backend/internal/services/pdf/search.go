@@ -0,0 +1,144 @@
+package pdf
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/images"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// Search returns the PDF pages containing every word of query, with snippets.
+func (s *Service) Search(query string, limit int) ([]*images.PdfSearchResult, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, ErrEmptyQuery
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+	return s.textRepo.SearchPdfPages(query, limit)
+}
+
+// IndexPdfText extracts the text of every page of a PDF and stores it for search.
+func (s *Service) IndexPdfText(id uint) error {
+	entry, err := s.getPdf(id)
+	if err != nil {
+		return err
+	}
+	return s.indexText(entry)
+}
+
+// Helpers
+
+func (s *Service) indexText(entry *images.ImageEntry) error {
+	started := time.Now()
+	info, err := os.Stat(s.fullPath(entry))
+	if err != nil {
+		return err
+	}
+	doc, err := fitz.New(s.fullPath(entry))
+	if err != nil {
+		return err
+	}
+	defer doc.Close()
+
+	pages := make([]string, doc.NumPage())
+	for i := range pages {
+		// A page that fails to extract is stored empty so the rest of the book stays searchable.
+		if pages[i], err = doc.Text(i); err != nil {
+			s.log.Warn("Failed to extract pdf page text", "path", entry.FilePath, "page", i+1, "error", err)
+		}
+	}
+	if err = s.textRepo.ReplacePdfPages(entry.ID, pages, info.ModTime()); err != nil {
+		return err
+	}
+	// A replaced file may have a different number of pages.
+	if entry.PageCount != len(pages) {
+		entry.PageCount = len(pages)
+		if err = s.repo.UpdateImageEntry(entry); err != nil {
+			return err
+		}
+	}
+	s.log.Info("Indexed pdf text", "path", entry.FilePath, "pages", len(pages), "took", time.Since(started))
+	return nil
+}
+
+// RunTextIndexer extracts the text of every PDF that has not been indexed yet, or whose file changed since,
+// then keeps indexing PDFs as they are added or replaced.
+func (s *Service) RunTextIndexer() {
+	go s.indexQueued()
+}
+
+// queueAllText marks every PDF for the background text indexer, which skips those that are up to date.
+// PDFs queued before RunTextIndexer wait for it.
+func (s *Service) queueAllText() {
+	entries, err := s.repo.GetAllImages(filters.ImagesFilters{Type: images.ImageTypePDF})
+	if err != nil {
+		s.log.Error("Failed to fetch pdf entries from DB", "error", err)
+		return
+	}
+	s.indexMu.Lock()
+	for _, entry := range entries {
+		s.indexQueue[entry.ID] = true
+	}
+	s.indexMu.Unlock()
+
+	select {
+	case s.indexWake <- struct{}{}:
+	default: // Already woken
+	}
+}
+
+func (s *Service) indexQueued() {
+	for range s.indexWake {
+		for {
+			id, ok := s.nextQueued()
+			if !ok {
+				break
+			}
+			entry, err := s.getPdf(id)
+			if err != nil {
+				continue // Removed since it was queued
+			}
+			s.indexStaleText(entry)
+		}
+	}
+}
+
+func (s *Service) nextQueued() (uint, bool) {
+	s.indexMu.Lock()
+	defer s.indexMu.Unlock()
+	for id := range s.indexQueue {
+		delete(s.indexQueue, id)
+		return id, true
+	}
+	return 0, false
+}
+
+// indexStaleText extracts the text of a PDF that has not been indexed yet, or whose file changed since.
+func (s *Service) indexStaleText(entry *images.ImageEntry) {
+	indexed, err := s.textRepo.GetPdfPagesModTime(entry.ID)
+	if err != nil {
+		s.log.Error("Failed to check pdf text index", "path", entry.FilePath, "error", err)
+		return
+	}
+	if indexed != nil {
+		if info, err := os.Stat(s.fullPath(entry)); err != nil || info.ModTime().Equal(*indexed) {
+			return
+		}
+	}
+	if err = s.indexText(entry); err != nil {
+		s.log.Warn("Failed to index pdf text", "path", entry.FilePath, "error", err)
+	}
+}
@@ -0,0 +1,370 @@
+package playback
+
+import (
+	"dmd/backend/internal/model/audio"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+const (
+	ActionPlay      = "play"      // Load track_id or playlist_id and play it, or resume what is loaded
+	ActionPause     = "pause"     // Hold the current position
+	ActionStop      = "stop"      // Stop and rewind, keeping the track or playlist loaded
+	ActionSeek      = "seek"      // Jump to position
+	ActionNext      = "next"      // Skip to the next track in the queue
+	ActionPrevious  = "previous"  // Restart the track, or go back one if it only just started
+	ActionEnded     = "ended"     // A client finished the track at queue_index
+	ActionSet       = "set"       // Change volume, loop and/or shuffle
+	ActionCrossfade = "crossfade" // Fade from what is playing to track_id or playlist_id over duration, after delay
+	ActionDuck      = "duck"      // Lower the channel to volume for duration, ramping over fade, after delay
+)
+
+const (
+	restartThreshold = 3.0 // How far into a track "previous" restarts it instead of going back
+	defaultDuckFade  = 0.5
+	maxFade          = 10.0
+	maxCrossfade     = 60.0
+	maxDelay         = 3600.0
+)
+
+// Command changes the state of one channel. Only the fields used by the action need to be set.
+type Command struct {
+	Channel    audio.ChannelName `json:"channel"`
+	Action     string            `json:"action"`
+	TrackID    *uint             `json:"track_id,omitempty"`
+	PlaylistID *uint             `json:"playlist_id,omitempty"`
+	QueueIndex *int              `json:"queue_index,omitempty"` // play: where to start in the playlist; ended: the track that finished
+	Position   *float64          `json:"position,omitempty"`    // Seconds into the track
+	Volume     *float64          `json:"volume,omitempty"`
+	Loop       *bool             `json:"loop,omitempty"`
+	Shuffle    *bool             `json:"shuffle,omitempty"`
+	Duration   *float64          `json:"duration,omitempty"` // crossfade: how long the fade takes; duck: how long to hold
+	Fade       *float64          `json:"fade,omitempty"`     // duck: how long each ramp takes
+	Delay      *float64          `json:"delay,omitempty"`    // crossfade, duck: seconds from now until it starts
+}
+
+// apply changes the channel according to the command and reports whether anything changed.
+func (s *Service) apply(ch *audio.ChannelState, cmd Command, now time.Time) (bool, error) {
+	if cmd.Position != nil && *cmd.Position < 0 {
+		return false, invalidCommand("position must not be negative")
+	}
+	if interrupts(cmd.Action) {
+		ch.Crossfade = nil
+	}
+	// Re-base the position on now, so an action that does not move playback keeps it where it is.
+	// A scheduled crossfade keeps its future start until something interrupts it.
+	if ch.Crossfade == nil || now.After(ch.UpdatedAt) {
+		ch.Position = ch.PositionAt(now)
+		ch.UpdatedAt = now
+	}
+
+	switch cmd.Action {
+	case ActionPlay:
+		if err := s.play(ch, cmd); err != nil {
+			return false, err
+		}
+	case ActionPause:
+		if ch.Status != audio.StatusPlaying {
+			return false, nil
+		}
+		ch.Status = audio.StatusPaused
+	case ActionStop:
+		ch.Status = audio.StatusStopped
+		ch.Position = 0
+	case ActionSeek:
+		if ch.TrackID == nil {
+			return false, invalidCommand("nothing is loaded")
+		}
+		if cmd.Position == nil {
+			return false, invalidCommand("seek needs a position")
+		}
+		ch.Position = *cmd.Position
+	case ActionNext:
+		if err := s.advance(ch, true); err != nil {
+			return false, err
+		}
+	case ActionPrevious:
+		if err := s.previous(ch); err != nil {
+			return false, err
+		}
+	case ActionEnded:
+		// Every display reports the end of a track; only the first report for the current track counts.
+		// During a crossfade the report may be for the track fading out, so it is ignored.
+		if ch.Status != audio.StatusPlaying || ch.Crossfade != nil || cmd.QueueIndex == nil || *cmd.QueueIndex != ch.QueueIndex {
+			return false, nil
+		}
+		if err := s.advance(ch, false); err != nil {
+			return false, err
+		}
+	case ActionSet:
+		if err := s.set(ch, cmd); err != nil {
+			return false, err
+		}
+	case ActionCrossfade:
+		if err := s.crossfade(ch, cmd, now); err != nil {
+			return false, err
+		}
+	case ActionDuck:
+		if err := s.duck(ch, cmd, now); err != nil {
+			return false, err
+		}
+	default:
+		return false, invalidCommand(fmt.Sprintf("unknown action %q", cmd.Action))
+	}
+	return true, nil
+}
+
+// play loads a track or playlist, or resumes the loaded one.
+func (s *Service) play(ch *audio.ChannelState, cmd Command) error {
+	switch {
+	case cmd.TrackID != nil:
+		ch.PlaylistID = nil
+		ch.Queue = []uint{*cmd.TrackID}
+		ch.QueueIndex = 0
+	case cmd.PlaylistID != nil:
+		trackIDs, err := s.playlistTrackIDs(*cmd.PlaylistID)
+		if err != nil {
+			return err
+		}
+		start := 0
+		if cmd.QueueIndex != nil {
+			start = *cmd.QueueIndex
+		}
+		if start < 0 || start >= len(trackIDs) {
+			return invalidCommand(fmt.Sprintf("queue_index %d is outside the playlist's %d tracks", start, len(trackIDs)))
+		}
+		ch.PlaylistID = cmd.PlaylistID
+		ch.Queue, ch.QueueIndex = trackIDs, start
+		if ch.Shuffle {
+			ch.Queue, ch.QueueIndex = shuffleFrom(trackIDs, start), 0
+		}
+	default:
+		if ch.TrackID == nil {
+			return invalidCommand("nothing is loaded")
+		}
+		if ch.Status == audio.StatusStopped {
+			ch.Position = 0
+		}
+		if cmd.Position != nil {
+			ch.Position = *cmd.Position
+		}
+		ch.Status = audio.StatusPlaying
+		return nil
+	}
+
+	position := 0.0
+	if cmd.Position != nil {
+		position = *cmd.Position
+	}
+	return s.load(ch, position)
+}
+
+// advance moves to the next track in the queue. At the end of the queue the channel wraps around when
+// looping; otherwise a skip stays on the last track and a finished track stops the channel.
+func (s *Service) advance(ch *audio.ChannelState, skip bool) error {
+	if ch.TrackID == nil {
+		return invalidCommand("nothing is loaded")
+	}
+	if !skip && ch.Loop && len(ch.Queue) == 1 {
+		ch.Position = 0
+		return nil
+	}
+
+	next := ch.QueueIndex + 1
+	if next >= len(ch.Queue) {
+		if !ch.Loop {
+			if !skip {
+				ch.Status = audio.StatusStopped
+				ch.Position = 0
+			}
+			return nil
+		}
+		next = 0
+	}
+	ch.QueueIndex = next
+	return s.load(ch, 0)
+}
+
+func (s *Service) previous(ch *audio.ChannelState) error {
+	if ch.TrackID == nil {
+		return invalidCommand("nothing is loaded")
+	}
+	prev := ch.QueueIndex
+	if ch.Position < restartThreshold {
+		prev--
+	}
+	if prev < 0 {
+		prev = 0
+		if ch.Loop {
+			prev = len(ch.Queue) - 1
+		}
+	}
+	ch.QueueIndex = prev
+	return s.load(ch, 0)
+}
+
+func (s *Service) set(ch *audio.ChannelState, cmd Command) error {
+	if cmd.Volume == nil && cmd.Loop == nil && cmd.Shuffle == nil {
+		return invalidCommand("set needs volume, loop or shuffle")
+	}
+	if cmd.Volume != nil {
+		if *cmd.Volume < 0 || *cmd.Volume > 1 {
+			return invalidCommand("volume must be between 0 and 1")
+		}
+		ch.Volume = *cmd.Volume
+	}
+	if cmd.Loop != nil {
+		ch.Loop = *cmd.Loop
+	}
+	if cmd.Shuffle != nil && *cmd.Shuffle != ch.Shuffle {
+		ch.Shuffle = *cmd.Shuffle
+		if err := s.reorderQueue(ch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// crossfade loads the new track or playlist and keeps what was playing as the track to fade out.
+func (s *Service) crossfade(ch *audio.ChannelState, cmd Command, now time.Time) error {
+	if cmd.TrackID == nil && cmd.PlaylistID == nil {
+		return invalidCommand("crossfade needs a track_id or playlist_id")
+	}
+	if cmd.Duration == nil || *cmd.Duration <= 0 || *cmd.Duration > maxCrossfade {
+		return invalidCommand(fmt.Sprintf("crossfade needs a duration of up to %g seconds", maxCrossfade))
+	}
+	startsAt, err := delayed(now, cmd.Delay)
+	if err != nil {
+		return err
+	}
+
+	var from *audio.ChannelState
+	if ch.Status == audio.StatusPlaying {
+		previous := *ch
+		previous.Duck = nil
+		from = &previous
+	}
+	if err = s.play(ch, cmd); err != nil {
+		return err
+	}
+	ch.UpdatedAt = startsAt
+	ch.Crossfade = &audio.Crossfade{
+		ID:       s.newTransitionID(),
+		StartsAt: startsAt,
+		EndsAt:   startsAt.Add(seconds(*cmd.Duration)),
+		From:     from,
+	}
+	return nil
+}
+
+func (s *Service) duck(ch *audio.ChannelState, cmd Command, now time.Time) error {
+	if cmd.Volume == nil || *cmd.Volume < 0 || *cmd.Volume > 1 {
+		return invalidCommand("duck needs a volume between 0 and 1")
+	}
+	if cmd.Duration == nil || *cmd.Duration <= 0 {
+		return invalidCommand("duck needs a positive duration")
+	}
+	fade := defaultDuckFade
+	if cmd.Fade != nil {
+		fade = *cmd.Fade
+	}
+	if fade < 0 || fade > maxFade {
+		return invalidCommand(fmt.Sprintf("fade must be between 0 and %g seconds", maxFade))
+	}
+	startsAt, err := delayed(now, cmd.Delay)
+	if err != nil {
+		return err
+	}
+
+	ch.Duck = &audio.Duck{
+		ID:       s.newTransitionID(),
+		Level:    *cmd.Volume,
+		Fade:     fade,
+		StartsAt: startsAt,
+		EndsAt:   startsAt.Add(seconds(*cmd.Duration)),
+	}
+	return nil
+}
+
+// reorderQueue shuffles the playlist behind the current track, or restores the playlist's order around it.
+func (s *Service) reorderQueue(ch *audio.ChannelState) error {
+	if ch.PlaylistID == nil || ch.TrackID == nil {
+		return nil
+	}
+	if ch.Shuffle {
+		ch.Queue, ch.QueueIndex = shuffleFrom(ch.Queue, ch.QueueIndex), 0
+		return nil
+	}
+	trackIDs, err := s.playlistTrackIDs(*ch.PlaylistID)
+	if err != nil {
+		return err
+	}
+	ch.Queue = trackIDs
+	ch.QueueIndex = max(slices.Index(trackIDs, *ch.TrackID), 0)
+	return nil
+}
+
+// load makes the track at QueueIndex current and starts playing it from position.
+func (s *Service) load(ch *audio.ChannelState, position float64) error {
+	trackID := ch.Queue[ch.QueueIndex]
+	track, err := s.trackRepo.GetTrackByID(trackID)
+	if err != nil {
+		return err
+	}
+	ch.TrackID = &trackID
+	ch.Track = track
+	ch.Position = position
+	ch.Status = audio.StatusPlaying
+	return nil
+}
+
+func (s *Service) playlistTrackIDs(playlistID uint) ([]uint, error) {
+	playlist, err := s.playlistRepo.GetPlaylistByID(playlistID)
+	if err != nil {
+		return nil, err
+	}
+	if len(playlist.Tracks) == 0 {
+		return nil, invalidCommand(fmt.Sprintf("playlist %q has no tracks", playlist.Name))
+	}
+	trackIDs := make([]uint, len(playlist.Tracks))
+	for i, track := range playlist.Tracks {
+		trackIDs[i] = track.ID
+	}
+	return trackIDs, nil
+}
+
+// shuffleFrom returns the track IDs in random order, starting with the one at index start.
+func shuffleFrom(trackIDs []uint, start int) []uint {
+	rest := slices.Delete(slices.Clone(trackIDs), start, start+1)
+	rand.Shuffle(len(rest), func(i, j int) { rest[i], rest[j] = rest[j], rest[i] })
+	return append([]uint{trackIDs[start]}, rest...)
+}
+
+// interrupts reports whether the action cuts short a crossfade on its channel.
+func interrupts(action string) bool {
+	return action != ActionSet && action != ActionDuck && action != ActionEnded
+}
+
+func delayed(now time.Time, delay *float64) (time.Time, error) {
+	if delay == nil {
+		return now, nil
+	}
+	if *delay < 0 || *delay > maxDelay {
+		return time.Time{}, invalidCommand(fmt.Sprintf("delay must be between 0 and %g seconds", maxDelay))
+	}
+	return now.Add(seconds(*delay)), nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func invalidCommand(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidCommand, reason)
+}
+
+func unknownChannel(name audio.ChannelName) error {
+	return fmt.Errorf("%w: %q, expected one of %v", ErrUnknownChannel, name, audio.Channels)
+}
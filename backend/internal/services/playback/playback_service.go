@@ -0,0 +1,202 @@
+// File: /internal/services/playback/playback_service.go
+package playback
+
+import (
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	EventPlaybackUpdated        = "playback_updated"
+	EventPlaybackError          = "playback_error"
+	EventPlaybackTransition     = "playback_transition"
+	MessagePlaybackCommand      = "playback_command"
+	MessagePlaybackStateRequest = "playback_state_request"
+)
+
+var (
+	ErrUnknownChannel = errors.New("unknown channel")
+	ErrInvalidCommand = errors.New("invalid playback command")
+)
+
+// CommandError is sent back to a WebSocket client whose command was rejected.
+type CommandError struct {
+	Command Command `json:"command"`
+	Error   string  `json:"error"`
+}
+
+// TransitionEvent is broadcast instead of playback_updated when a command starts a crossfade or a duck.
+// Kind is the command's action; the timings are on the channel in State, so every display runs the
+// transition against the same clock.
+type TransitionEvent struct {
+	Channel audio.ChannelName   `json:"channel"`
+	Kind    string              `json:"kind"`
+	State   audio.PlaybackState `json:"state"`
+}
+
+// Service owns the playback state of every audio channel. Commands arrive over REST or WebSocket,
+// and each change is broadcast so every player-side browser plays the same audio in sync.
+type Service struct {
+	log          *slog.Logger
+	trackRepo    repos.TrackRepository
+	playlistRepo repos.PlaylistRepository
+	wsManager    *wsService.Manager
+
+	mu           sync.Mutex
+	channels     map[audio.ChannelName]audio.ChannelState
+	transitionID uint64 // Last ID handed out to a crossfade or duck
+}
+
+func NewService(log *slog.Logger, trackRepo repos.TrackRepository, playlistRepo repos.PlaylistRepository, wsManager *wsService.Manager) *Service {
+	svc := &Service{
+		log:          log,
+		trackRepo:    trackRepo,
+		playlistRepo: playlistRepo,
+		wsManager:    wsManager,
+		channels:     make(map[audio.ChannelName]audio.ChannelState),
+	}
+	now := time.Now()
+	for _, name := range audio.Channels {
+		svc.channels[name] = audio.ChannelState{
+			Name:      name,
+			Status:    audio.StatusStopped,
+			Queue:     []uint{},
+			Volume:    1,
+			Loop:      name == audio.ChannelAmbience, // Ambience beds are meant to run until replaced
+			UpdatedAt: now,
+		}
+	}
+
+	wsManager.RegisterHandler(MessagePlaybackCommand, svc.handleCommand)
+	wsManager.RegisterHandler(MessagePlaybackStateRequest, svc.handleStateRequest)
+	wsManager.RegisterResyncHandler(func(client *wsService.Client) { svc.handleStateRequest(nil, client) })
+
+	return svc
+}
+
+// GetState returns a snapshot of every channel.
+func (s *Service) GetState() audio.PlaybackState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshot()
+}
+
+// Execute applies a command to its channel and broadcasts the new state.
+// A stale "ended" report (e.g. from a second display) leaves the state unchanged and is not broadcast.
+func (s *Service) Execute(cmd Command) (audio.PlaybackState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.channels[cmd.Channel]
+	if !ok {
+		return audio.PlaybackState{}, unknownChannel(cmd.Channel)
+	}
+
+	ch := current
+	ch.Queue = slices.Clone(current.Queue)
+	changed, err := s.apply(&ch, cmd, time.Now())
+	if err != nil {
+		return audio.PlaybackState{}, err
+	}
+	if !changed {
+		return s.snapshot(), nil
+	}
+
+	s.channels[cmd.Channel] = ch
+	state := s.snapshot()
+	s.log.Info("Playback updated", "channel", ch.Name, "action", cmd.Action, "status", ch.Status, "track_id", ch.TrackID)
+
+	switch cmd.Action {
+	case ActionCrossfade:
+		id := ch.Crossfade.ID
+		s.expireAt(cmd.Channel, ch.Crossfade.EndsAt, func(c *audio.ChannelState) bool {
+			if c.Crossfade == nil || c.Crossfade.ID != id {
+				return false
+			}
+			c.Crossfade = nil
+			return true
+		})
+	case ActionDuck:
+		id := ch.Duck.ID
+		s.expireAt(cmd.Channel, ch.Duck.EndsAt.Add(seconds(ch.Duck.Fade)), func(c *audio.ChannelState) bool {
+			if c.Duck == nil || c.Duck.ID != id {
+				return false
+			}
+			c.Duck = nil
+			return true
+		})
+	default:
+		s.wsManager.Broadcast(websocket.Event{Type: EventPlaybackUpdated, Payload: state})
+		return state, nil
+	}
+	event := TransitionEvent{Channel: cmd.Channel, Kind: cmd.Action, State: state}
+	// A display that reconnects after the transition started gets the state instead, and joins it part way.
+	s.wsManager.BroadcastWithReplay(
+		websocket.Event{Type: EventPlaybackTransition, Payload: event},
+		websocket.Event{Type: EventPlaybackUpdated, Payload: state},
+	)
+	return state, nil
+}
+
+// Duck lowers a channel to level times its volume for the given number of seconds.
+func (s *Service) Duck(channel audio.ChannelName, level, seconds float64) (audio.PlaybackState, error) {
+	return s.Execute(Command{Channel: channel, Action: ActionDuck, Volume: &level, Duration: &seconds})
+}
+
+func (s *Service) handleCommand(payload json.RawMessage, client *wsService.Client) {
+	var cmd Command
+	if err := json.Unmarshal(payload, &cmd); err != nil {
+		s.wsManager.SendTo(client, websocket.Event{Type: EventPlaybackError, Payload: CommandError{Error: err.Error()}})
+		return
+	}
+	if err := client.RequireRole(wsService.RoleDM); err != nil {
+		s.wsManager.SendTo(client, websocket.Event{Type: EventPlaybackError, Payload: CommandError{Command: cmd, Error: err.Error()}})
+		return
+	}
+	if _, err := s.Execute(cmd); err != nil {
+		s.wsManager.SendTo(client, websocket.Event{Type: EventPlaybackError, Payload: CommandError{Command: cmd, Error: err.Error()}})
+	}
+}
+
+func (s *Service) handleStateRequest(_ json.RawMessage, client *wsService.Client) {
+	s.wsManager.SendTo(client, websocket.Event{Type: EventPlaybackUpdated, Payload: s.GetState()})
+}
+
+// Helpers
+
+func (s *Service) newTransitionID() uint64 {
+	s.transitionID++
+	return s.transitionID
+}
+
+// expireAt runs clear on the channel at the given time, to drop a finished crossfade or duck from the state
+// unless a newer one has replaced it. Displays have already run it to the end by the clock, so nothing is broadcast.
+func (s *Service) expireAt(channel audio.ChannelName, at time.Time, clear func(*audio.ChannelState) bool) {
+	time.AfterFunc(time.Until(at), func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		ch := s.channels[channel]
+		if clear(&ch) {
+			s.channels[channel] = ch
+		}
+	})
+}
+
+// snapshot copies the channels in display order. Callers must hold mu.
+func (s *Service) snapshot() audio.PlaybackState {
+	state := audio.PlaybackState{Channels: make([]audio.ChannelState, 0, len(audio.Channels))}
+	for _, name := range audio.Channels {
+		ch := s.channels[name]
+		ch.Queue = slices.Clone(ch.Queue)
+		state.Channels = append(state.Channels, ch)
+	}
+	return state
+}
@@ -0,0 +1,95 @@
+// File: /internal/services/scenes/scene_service.go
+package scenes
+
+import (
+	"dmd/backend/internal/model/display"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	displaySvc "dmd/backend/internal/services/display"
+	wsService "dmd/backend/internal/services/websocket"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+const EventSceneCue = "scene_cue"
+
+var (
+	ErrEndOfScene    = errors.New("scene has no next cue")
+	ErrStartOfScene  = errors.New("scene has no previous cue")
+	ErrPresetMissing = errors.New("cue preset no longer exists")
+)
+
+// CueEvent is the payload broadcast when a scene cue is applied.
+// Clients use the cue's track or playlist to start the attached audio.
+type CueEvent struct {
+	SceneID uint             `json:"scene_id"`
+	Cue     display.SceneCue `json:"cue"`
+	Display display.State    `json:"display"`
+}
+
+// Service steps through scenes, applying each cue's preset to the player display.
+type Service struct {
+	log            *slog.Logger
+	repo           repos.SceneRepository
+	displayService *displaySvc.Service
+	wsManager      *wsService.Manager
+
+	mu sync.Mutex // Serialises steps, so two quick presses move two cues and broadcast them in order
+}
+
+func NewService(log *slog.Logger, repo repos.SceneRepository, displayService *displaySvc.Service, wsManager *wsService.Manager) *Service {
+	return &Service{
+		log:            log,
+		repo:           repo,
+		displayService: displayService,
+		wsManager:      wsManager,
+	}
+}
+
+// Next applies the cue after the scene's active cue. A scene that has not started begins at its first cue.
+func (s *Service) Next(sceneID uint) (*CueEvent, error) {
+	return s.step(sceneID, 1)
+}
+
+// Previous applies the cue before the scene's active cue.
+func (s *Service) Previous(sceneID uint) (*CueEvent, error) {
+	return s.step(sceneID, -1)
+}
+
+func (s *Service) step(sceneID uint, delta int) (*CueEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	scene, err := s.repo.GetSceneByID(sceneID)
+	if err != nil {
+		return nil, err
+	}
+
+	target := int(scene.ActiveCue) + delta
+	if target > len(scene.Cues) {
+		return nil, ErrEndOfScene
+	}
+	if target < 1 {
+		return nil, ErrStartOfScene
+	}
+
+	cue := scene.Cues[target-1]
+	if cue.PresetLayout.ID == 0 {
+		return nil, ErrPresetMissing
+	}
+
+	state, err := s.displayService.ApplyPreset(&cue.PresetLayout)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetActiveCue(sceneID, cue.CueOrder); err != nil {
+		return nil, err
+	}
+
+	event := &CueEvent{SceneID: sceneID, Cue: cue, Display: state}
+	s.wsManager.Broadcast(websocket.Event{Type: EventSceneCue, Payload: event})
+
+	s.log.Info("Scene cue applied", "scene", scene.Name, "cue", cue.CueOrder, "of", len(scene.Cues))
+	return event, nil
+}
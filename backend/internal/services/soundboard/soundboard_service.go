@@ -0,0 +1,226 @@
+// File: /internal/services/soundboard/soundboard_service.go
+package soundboard
+
+import (
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	playbackSvc "dmd/backend/internal/services/playback"
+	wsService "dmd/backend/internal/services/websocket"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventSoundboardTrigger   = "soundboard_trigger"
+	EventSoundboardError     = "soundboard_error"
+	MessageSoundboardTrigger = "soundboard_trigger"
+)
+
+const (
+	defaultGridSize = 4
+	maxGridSize     = 12
+	duckLevel       = 0.3 // Music level under a pad with DuckMusic set
+	defaultDuckTime = 3.0 // Seconds to duck for when the track's duration is unknown
+)
+
+var ErrInvalidSoundboard = errors.New("invalid soundboard")
+
+// TriggerEvent is the one-shot play event broadcast when a pad is triggered. Displays play the track
+// on the SFX channel, scaled by that channel's volume, without touching the music or ambience.
+type TriggerEvent struct {
+	PadID        uint          `json:"pad_id"`
+	SoundboardID uint          `json:"soundboard_id"`
+	TrackID      uint          `json:"track_id"`
+	Track        audio.Track   `json:"track"`
+	Volume       float64       `json:"volume"`
+	Mode         audio.PadMode `json:"mode"`
+	TriggeredAt  time.Time     `json:"triggered_at"`
+}
+
+// TriggerRequest is the payload of a soundboard_trigger message from the DM screen.
+type TriggerRequest struct {
+	PadID uint `json:"pad_id"`
+}
+
+// TriggerError is sent back to a WebSocket client whose trigger was rejected.
+type TriggerError struct {
+	PadID uint   `json:"pad_id"`
+	Error string `json:"error"`
+}
+
+// Service manages soundboards and fires their pads.
+type Service struct {
+	log             *slog.Logger
+	repo            repos.SoundboardRepository
+	trackRepo       repos.TrackRepository
+	playbackService *playbackSvc.Service
+	wsManager       *wsService.Manager
+}
+
+func NewService(log *slog.Logger, repo repos.SoundboardRepository, trackRepo repos.TrackRepository, playbackService *playbackSvc.Service, wsManager *wsService.Manager) *Service {
+	svc := &Service{
+		log:             log,
+		repo:            repo,
+		trackRepo:       trackRepo,
+		playbackService: playbackService,
+		wsManager:       wsManager,
+	}
+	wsManager.RegisterHandler(MessageSoundboardTrigger, svc.handleTrigger)
+	return svc
+}
+
+func (s *Service) GetSoundboard(id uint) (*audio.Soundboard, error) {
+	return s.repo.GetSoundboardByID(id)
+}
+
+func (s *Service) GetSoundboards(filters filters.SoundboardFilters) ([]*audio.Soundboard, error) {
+	return s.repo.GetAllSoundboards(filters)
+}
+
+// CreateSoundboard validates and stores a new soundboard with its pads.
+func (s *Service) CreateSoundboard(board *audio.Soundboard) error {
+	if err := s.validateSoundboard(board); err != nil {
+		return err
+	}
+	board.ID = 0
+	return s.repo.CreateSoundboard(board)
+}
+
+// UpdateSoundboard replaces an existing soundboard's details and pads.
+func (s *Service) UpdateSoundboard(board *audio.Soundboard) error {
+	if _, err := s.repo.GetSoundboardByID(board.ID); err != nil {
+		return err
+	}
+	if err := s.validateSoundboard(board); err != nil {
+		return err
+	}
+	return s.repo.UpdateSoundboard(board)
+}
+
+func (s *Service) DeleteSoundboard(id uint) error {
+	return s.repo.DeleteSoundboard(id)
+}
+
+// Trigger broadcasts a one-shot play event for the pad. What the channels play is left alone;
+// a pad with DuckMusic set only ducks the music for the length of its track.
+func (s *Service) Trigger(padID uint) (*TriggerEvent, error) {
+	pad, err := s.repo.GetPadByID(padID)
+	if err != nil {
+		return nil, err
+	}
+	if pad.DuckMusic {
+		seconds := float64(pad.Track.Duration)
+		if seconds == 0 {
+			seconds = defaultDuckTime
+		}
+		if _, err = s.playbackService.Duck(audio.ChannelMusic, duckLevel, seconds); err != nil {
+			s.log.Warn("Failed to duck music for pad", "pad", pad.ID, "error", err)
+		}
+	}
+
+	event := &TriggerEvent{
+		PadID:        pad.ID,
+		SoundboardID: pad.SoundboardID,
+		TrackID:      pad.TrackID,
+		Track:        pad.Track,
+		Volume:       *pad.Volume,
+		Mode:         pad.Mode,
+		TriggeredAt:  time.Now(),
+	}
+	// A sound that went off while a display was reconnecting is not played late.
+	s.wsManager.BroadcastTransient(websocket.Event{Type: EventSoundboardTrigger, Payload: event})
+
+	s.log.Info("Soundboard pad triggered", "pad", pad.ID, "label", pad.Label, "track_id", pad.TrackID)
+	return event, nil
+}
+
+func (s *Service) handleTrigger(payload json.RawMessage, client *wsService.Client) {
+	var req TriggerRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		s.wsManager.SendTo(client, websocket.Event{Type: EventSoundboardError, Payload: TriggerError{Error: err.Error()}})
+		return
+	}
+	if err := client.RequireRole(wsService.RoleDM); err != nil {
+		s.wsManager.SendTo(client, websocket.Event{Type: EventSoundboardError, Payload: TriggerError{PadID: req.PadID, Error: err.Error()}})
+		return
+	}
+	if _, err := s.Trigger(req.PadID); err != nil {
+		s.wsManager.SendTo(client, websocket.Event{Type: EventSoundboardError, Payload: TriggerError{PadID: req.PadID, Error: err.Error()}})
+	}
+}
+
+// Helpers
+
+// validateSoundboard checks the grid and its pads, filling in defaults for anything left out.
+func (s *Service) validateSoundboard(board *audio.Soundboard) error {
+	board.Name = strings.TrimSpace(board.Name)
+	if board.Name == "" {
+		return invalidSoundboard("name is required")
+	}
+	if board.Rows == 0 {
+		board.Rows = defaultGridSize
+	}
+	if board.Columns == 0 {
+		board.Columns = defaultGridSize
+	}
+	if board.Rows > maxGridSize || board.Columns > maxGridSize {
+		return invalidSoundboard(fmt.Sprintf("grid must be at most %dx%d", maxGridSize, maxGridSize))
+	}
+
+	cells := make(map[[2]uint]bool)
+	keys := make(map[string]bool)
+	for i := range board.Pads {
+		pad := &board.Pads[i]
+		if pad.Row >= board.Rows || pad.Column >= board.Columns {
+			return invalidSoundboard(fmt.Sprintf("pad at row %d, column %d is outside the %dx%d grid", pad.Row, pad.Column, board.Rows, board.Columns))
+		}
+		cell := [2]uint{pad.Row, pad.Column}
+		if cells[cell] {
+			return invalidSoundboard(fmt.Sprintf("more than one pad at row %d, column %d", pad.Row, pad.Column))
+		}
+		cells[cell] = true
+
+		pad.KeyBinding = strings.ToLower(strings.TrimSpace(pad.KeyBinding))
+		if pad.KeyBinding != "" {
+			if keys[pad.KeyBinding] {
+				return invalidSoundboard(fmt.Sprintf("key %q is bound to more than one pad", pad.KeyBinding))
+			}
+			keys[pad.KeyBinding] = true
+		}
+
+		if pad.Volume == nil {
+			fullVolume := 1.0
+			pad.Volume = &fullVolume
+		}
+		if *pad.Volume < 0 || *pad.Volume > 1 {
+			return invalidSoundboard("pad volume must be between 0 and 1")
+		}
+		switch pad.Mode {
+		case "":
+			pad.Mode = audio.PadModeRestart
+		case audio.PadModeRestart, audio.PadModeOverlap:
+		default:
+			return invalidSoundboard(fmt.Sprintf("unknown pad mode %q", pad.Mode))
+		}
+
+		if _, err := s.trackRepo.GetTrackByID(pad.TrackID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return invalidSoundboard(fmt.Sprintf("track %d does not exist", pad.TrackID))
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func invalidSoundboard(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidSoundboard, reason)
+}
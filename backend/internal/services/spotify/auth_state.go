@@ -0,0 +1,58 @@
+package spotify
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultLoginTimeout = 10 * time.Minute
+
+// pendingLogin is a login that was sent to Spotify and has not come back through the callback yet.
+type pendingLogin struct {
+	verifier  string // PKCE code verifier; Spotify only sees its challenge
+	expiresAt time.Time
+}
+
+// loginStore keeps pending logins by their OAuth state. Each state can be used once, and only until it expires.
+type loginStore struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	logins  map[string]pendingLogin
+}
+
+func newLoginStore(timeout time.Duration) *loginStore {
+	return &loginStore{
+		timeout: timeout,
+		logins:  make(map[string]pendingLogin),
+	}
+}
+
+// add records a login, dropping logins that were abandoned.
+func (l *loginStore) add(state, verifier string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	for s, login := range l.logins {
+		if now.After(login.expiresAt) {
+			delete(l.logins, s)
+		}
+	}
+	l.logins[state] = pendingLogin{verifier: verifier, expiresAt: now.Add(l.timeout)}
+}
+
+// take removes the login and returns its verifier, if the state is known and has not expired.
+func (l *loginStore) take(state string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	login, ok := l.logins[state]
+	if !ok {
+		return "", false
+	}
+	delete(l.logins, state)
+	if time.Now().After(login.expiresAt) {
+		return "", false
+	}
+	return login.verifier, true
+}
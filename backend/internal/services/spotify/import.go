@@ -0,0 +1,232 @@
+package spotify
+
+import (
+	"context"
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/audio"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zmb3/spotify/v2"
+	"gorm.io/gorm"
+)
+
+const pageLimit = 50
+
+var ErrUnknownPlaylist = errors.New("playlist is not in the Spotify library")
+
+// RemotePlaylist is a playlist in the user's Spotify library.
+type RemotePlaylist struct {
+	ID          string `json:"id"`
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+	TrackCount  int    `json:"track_count"`
+	ImageURL    string `json:"image_url"`
+}
+
+// ImportedPlaylist reports what an import did with one Spotify playlist.
+type ImportedPlaylist struct {
+	SpotifyID string          `json:"spotify_id"`
+	Created   bool            `json:"created"` // False when an earlier import was synced
+	Skipped   int             `json:"skipped"` // Local files, podcast episodes and unavailable tracks
+	Playlist  *audio.Playlist `json:"playlist"`
+}
+
+// GetPlaylists lists the playlists the user owns or follows on Spotify.
+func (s *Service) GetPlaylists(ctx context.Context) ([]RemotePlaylist, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.getPlaylists(ctx, client)
+}
+
+// ImportPlaylists copies the given Spotify playlists, or the whole library when no IDs are given, into
+// local playlists of spotify tracks. Importing a playlist again syncs its name, description and tracks.
+func (s *Service) ImportPlaylists(ctx context.Context, spotifyIDs []string) ([]ImportedPlaylist, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	library, err := s.getPlaylists(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := library
+	if len(spotifyIDs) > 0 {
+		byID := make(map[string]RemotePlaylist, len(library))
+		for _, remote := range library {
+			byID[remote.ID] = remote
+		}
+		selected = nil
+		for _, id := range spotifyIDs {
+			remote, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownPlaylist, id)
+			}
+			selected = append(selected, remote)
+		}
+	}
+
+	imported := make([]ImportedPlaylist, 0, len(selected))
+	for _, remote := range selected {
+		result, err := s.importPlaylist(ctx, client, remote)
+		if err != nil {
+			return nil, fmt.Errorf("importing %q: %w", remote.Name, err)
+		}
+		imported = append(imported, *result)
+	}
+	return imported, nil
+}
+
+// Helpers
+
+func (s *Service) getPlaylists(ctx context.Context, client *spotify.Client) ([]RemotePlaylist, error) {
+	page, err := client.CurrentUsersPlaylists(ctx, spotify.Limit(pageLimit))
+	if err != nil {
+		return nil, err
+	}
+	playlists := []RemotePlaylist{}
+	for {
+		for _, p := range page.Playlists {
+			remote := RemotePlaylist{
+				ID:          p.ID.String(),
+				URI:         string(p.URI),
+				Name:        p.Name,
+				Description: p.Description,
+				Owner:       p.Owner.DisplayName,
+				TrackCount:  int(p.Tracks.Total),
+			}
+			if len(p.Images) > 0 {
+				remote.ImageURL = p.Images[0].URL
+			}
+			playlists = append(playlists, remote)
+		}
+		if err = client.NextPage(ctx, page); errors.Is(err, spotify.ErrNoMorePages) {
+			return playlists, nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *Service) importPlaylist(ctx context.Context, client *spotify.Client, remote RemotePlaylist) (*ImportedPlaylist, error) {
+	tracks, skipped, err := s.getPlaylistTracks(ctx, client, spotify.ID(remote.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err = s.trackRepo.BulkCreateTracks(tracks); err != nil {
+		return nil, err
+	}
+	trackIDs := make([]uint, len(tracks))
+	for i, track := range tracks {
+		trackIDs[i] = track.ID
+	}
+
+	result := &ImportedPlaylist{SpotifyID: remote.ID, Skipped: skipped}
+	existing, err := s.playlistRepo.GetPlaylistBySourceID(audio.SourceSpotify, remote.URI)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		name, err := s.availableName(remote.Name, 0)
+		if err != nil {
+			return nil, err
+		}
+		playlist := &audio.Playlist{Name: name, Description: remote.Description, Source: audio.SourceSpotify, SourceID: remote.URI}
+		if result.Playlist, err = s.playlistRepo.CreatePlaylist(playlist, trackIDs); err != nil {
+			return nil, err
+		}
+		result.Created = true
+		s.log.Info("Imported Spotify playlist", "name", name, "tracks", len(result.Playlist.Tracks), "skipped", skipped)
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.Name, err = s.availableName(remote.Name, existing.ID); err != nil {
+		return nil, err
+	}
+	existing.Description = remote.Description
+	if _, err = s.playlistRepo.UpdatePlaylist(existing); err != nil {
+		return nil, err
+	}
+	if err = s.playlistRepo.ReplacePlaylistTracks(existing.ID, trackIDs); err != nil {
+		return nil, err
+	}
+	if result.Playlist, err = s.playlistRepo.GetPlaylistByID(existing.ID); err != nil {
+		return nil, err
+	}
+	s.log.Info("Synced Spotify playlist", "name", existing.Name, "tracks", len(result.Playlist.Tracks), "skipped", skipped)
+	return result, nil
+}
+
+// getPlaylistTracks returns the playlist's tracks in order, as unsaved spotify tracks.
+func (s *Service) getPlaylistTracks(ctx context.Context, client *spotify.Client, id spotify.ID) ([]*audio.Track, int, error) {
+	page, err := client.GetPlaylistItems(ctx, id, spotify.Limit(pageLimit))
+	if err != nil {
+		return nil, 0, err
+	}
+	var tracks []*audio.Track
+	skipped := 0
+	for {
+		for _, item := range page.Items {
+			track := item.Track.Track
+			if item.IsLocal || track == nil || track.URI == "" {
+				skipped++
+				continue
+			}
+			tracks = append(tracks, newSpotifyTrack(track))
+		}
+		if err = client.NextPage(ctx, page); errors.Is(err, spotify.ErrNoMorePages) {
+			return tracks, skipped, nil
+		} else if err != nil {
+			return nil, 0, err
+		}
+	}
+}
+
+// availableName returns name, or name with a " (Spotify)" suffix when another playlist already uses it.
+// The playlist with ID self is allowed to keep its own name.
+func (s *Service) availableName(name string, self uint) (string, error) {
+	existing, err := s.playlistRepo.GetAllPlaylists(filters.PlaylistFilters{Name: name})
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, playlist := range existing {
+		if playlist.ID != self {
+			taken[playlist.Name] = true
+		}
+	}
+
+	candidate := name
+	for i := 1; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (Spotify)", name)
+		if i > 1 {
+			candidate = fmt.Sprintf("%s (Spotify %d)", name, i)
+		}
+	}
+	return candidate, nil
+}
+
+func newSpotifyTrack(track *spotify.FullTrack) *audio.Track {
+	artists := make([]string, len(track.Artists))
+	for i, artist := range track.Artists {
+		artists[i] = artist.Name
+	}
+	result := &audio.Track{
+		Title:    track.Name,
+		Artist:   strings.Join(artists, ", "),
+		Duration: uint((track.Duration + 500) / 1000),
+		Source:   audio.SourceSpotify,
+		SourceID: string(track.URI),
+	}
+	// Spotify lists album images largest first; the smallest makes the thumbnail.
+	if images := track.Album.Images; len(images) > 0 {
+		result.ThumbnailURL = images[len(images)-1].URL
+	}
+	return result
+}
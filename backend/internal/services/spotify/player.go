@@ -0,0 +1,170 @@
+package spotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/zmb3/spotify/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	ActionPlay     = "play"     // Start or resume; context_uri or uris pick what to play
+	ActionPause    = "pause"    // Pause playback
+	ActionNext     = "next"     // Skip to the next track
+	ActionPrevious = "previous" // Go back to the previous track
+	ActionSeek     = "seek"     // Jump to position_ms
+	ActionVolume   = "volume"   // Set volume_percent
+	ActionShuffle  = "shuffle"  // Turn shuffle on or off
+	ActionTransfer = "transfer" // Move playback to device_id
+)
+
+var (
+	ErrNotAuthenticated     = errors.New("spotify is not connected")
+	ErrInvalidPlayerCommand = errors.New("invalid spotify player command")
+)
+
+// PlayerCommand controls the user's Spotify player. Only the fields used by the action need to be set.
+type PlayerCommand struct {
+	Action        string   `json:"action"`
+	DeviceID      string   `json:"device_id,omitempty"`   // The device to control; the active one when empty
+	ContextURI    string   `json:"context_uri,omitempty"` // play: a playlist or album URI
+	URIs          []string `json:"uris,omitempty"`        // play: track URIs
+	Offset        *int     `json:"offset,omitempty"`      // play: index into context_uri to start at
+	PositionMs    *int     `json:"position_ms,omitempty"` // play, seek
+	VolumePercent *int     `json:"volume_percent,omitempty"`
+	Shuffle       *bool    `json:"shuffle,omitempty"`
+	Play          *bool    `json:"play,omitempty"` // transfer: keep playing on the new device
+}
+
+// GetPlayerState returns what Spotify is playing. With no active device the state is empty.
+func (s *Service) GetPlayerState(ctx context.Context) (*spotify.PlayerState, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.PlayerState(ctx)
+}
+
+// GetDevices lists the devices Spotify can play on.
+func (s *Service) GetDevices(ctx context.Context) ([]spotify.PlayerDevice, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return client.PlayerDevices(ctx)
+}
+
+// ExecutePlayerCommand validates the command and sends it to Spotify.
+func (s *Service) ExecutePlayerCommand(ctx context.Context, cmd PlayerCommand) error {
+	if err := validatePlayerCommand(cmd); err != nil {
+		return err
+	}
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	opt := &spotify.PlayOptions{}
+	if cmd.DeviceID != "" {
+		deviceID := spotify.ID(cmd.DeviceID)
+		opt.DeviceID = &deviceID
+	}
+
+	switch cmd.Action {
+	case ActionPlay:
+		if cmd.ContextURI != "" {
+			uri := spotify.URI(cmd.ContextURI)
+			opt.PlaybackContext = &uri
+		}
+		for _, uri := range cmd.URIs {
+			opt.URIs = append(opt.URIs, spotify.URI(uri))
+		}
+		if cmd.Offset != nil {
+			opt.PlaybackOffset = &spotify.PlaybackOffset{Position: cmd.Offset}
+		}
+		if cmd.PositionMs != nil {
+			opt.PositionMs = spotify.Numeric(*cmd.PositionMs)
+		}
+		err = client.PlayOpt(ctx, opt)
+	case ActionPause:
+		err = client.PauseOpt(ctx, opt)
+	case ActionNext:
+		err = client.NextOpt(ctx, opt)
+	case ActionPrevious:
+		err = client.PreviousOpt(ctx, opt)
+	case ActionSeek:
+		err = client.SeekOpt(ctx, *cmd.PositionMs, opt)
+	case ActionVolume:
+		err = client.VolumeOpt(ctx, *cmd.VolumePercent, opt)
+	case ActionShuffle:
+		err = client.ShuffleOpt(ctx, *cmd.Shuffle, opt)
+	case ActionTransfer:
+		err = client.TransferPlayback(ctx, spotify.ID(cmd.DeviceID), cmd.Play != nil && *cmd.Play)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.log.Info("Spotify player command sent", "action", cmd.Action, "device_id", cmd.DeviceID)
+	return nil
+}
+
+// Helpers
+
+// client returns a Web API client authorised with the stored token.
+func (s *Service) client(ctx context.Context) (*spotify.Client, error) {
+	token, err := s.GetValidToken(ctx)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotAuthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []spotify.ClientOption
+	if s.apiURL != "" {
+		opts = append(opts, spotify.WithBaseURL(s.apiURL))
+	}
+	return spotify.New(s.auth.Client(s.withHTTPClient(ctx), token), opts...), nil
+}
+
+func validatePlayerCommand(cmd PlayerCommand) error {
+	switch cmd.Action {
+	case ActionPlay:
+		if cmd.ContextURI != "" && len(cmd.URIs) > 0 {
+			return invalidPlayerCommand("play takes either context_uri or uris, not both")
+		}
+		if cmd.Offset != nil && cmd.ContextURI == "" {
+			return invalidPlayerCommand("offset needs a context_uri")
+		}
+		if cmd.PositionMs != nil && *cmd.PositionMs < 0 {
+			return invalidPlayerCommand("position_ms must not be negative")
+		}
+	case ActionPause, ActionNext, ActionPrevious:
+	case ActionSeek:
+		if cmd.PositionMs == nil || *cmd.PositionMs < 0 {
+			return invalidPlayerCommand("seek needs a position_ms of 0 or more")
+		}
+	case ActionVolume:
+		if cmd.VolumePercent == nil || *cmd.VolumePercent < 0 || *cmd.VolumePercent > 100 {
+			return invalidPlayerCommand("volume needs a volume_percent between 0 and 100")
+		}
+	case ActionShuffle:
+		if cmd.Shuffle == nil {
+			return invalidPlayerCommand("shuffle needs a shuffle value")
+		}
+	case ActionTransfer:
+		if cmd.DeviceID == "" {
+			return invalidPlayerCommand("transfer needs a device_id")
+		}
+	default:
+		return invalidPlayerCommand(fmt.Sprintf("unknown action %q", cmd.Action))
+	}
+	return nil
+}
+
+func invalidPlayerCommand(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPlayerCommand, reason)
+}
@@ -4,8 +4,14 @@ import (
 	"context"
 	"crypto/rand"
 	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	wsService "dmd/backend/internal/services/websocket"
 	"encoding/base64"
+	"errors"
 	"log/slog"
+	"net/http"
+	"sync"
 	"time"
 
 	spotifyauth "github.com/zmb3/spotify/v2/auth"
@@ -15,16 +21,61 @@ import (
 
 const (
 	tokenID = 1 // Singleton ID for single-user app
+
+	EventAuthStatus = "spotify_auth_status"
+)
+
+// Why the auth status changed
+const (
+	AuthReasonConnected    = "connected"    // A login completed
+	AuthReasonDisconnected = "disconnected" // The user logged out
+	AuthReasonRevoked      = "revoked"      // Spotify rejected the refresh token, e.g. access was removed in the account settings
 )
 
+var ErrInvalidState = errors.New("unknown or expired login state")
+
+// AuthStatus is broadcast as EventAuthStatus whenever Spotify is connected or disconnected.
+type AuthStatus struct {
+	Authenticated bool   `json:"authenticated"`
+	Reason        string `json:"reason"`
+}
+
 type Service struct {
-	db          *gorm.DB
-	auth        *spotifyauth.Authenticator
-	log         *slog.Logger
-	redirectURI string
+	db           *gorm.DB
+	trackRepo    repos.TrackRepository
+	playlistRepo repos.PlaylistRepository
+	wsManager    *wsService.Manager
+	auth         *spotifyauth.Authenticator
+	log          *slog.Logger
+	redirectURI  string
+	tokenKey     TokenKey
+	logins       *loginStore
+	tokenMu      sync.Mutex   // Serialises reading, refreshing and writing the stored token
+	httpClient   *http.Client // Used for every call to Spotify when set
+	apiURL       string       // Overrides the Web API base URL when set
+}
+
+// Option customises how the service reaches Spotify.
+type Option func(*Service)
+
+// WithHTTPClient makes the service send its requests, token refreshes included, through client.
+func WithHTTPClient(client *http.Client) Option {
+	return func(s *Service) { s.httpClient = client }
+}
+
+// WithAPIBaseURL points the Web API client at another server, e.g. a fake one in tests.
+// The URL must end with a slash, like "http://127.0.0.1:4000/v1/".
+func WithAPIBaseURL(url string) Option {
+	return func(s *Service) { s.apiURL = url }
 }
 
-func NewService(log *slog.Logger, db *gorm.DB, clientID, clientSecret, redirectURI string) *Service {
+// WithLoginTimeout sets how long a started login can take to come back through the callback.
+func WithLoginTimeout(timeout time.Duration) Option {
+	return func(s *Service) { s.logins = newLoginStore(timeout) }
+}
+
+func NewService(log *slog.Logger, db *gorm.DB, trackRepo repos.TrackRepository, playlistRepo repos.PlaylistRepository,
+	wsManager *wsService.Manager, tokenKey TokenKey, clientID, clientSecret, redirectURI string, opts ...Option) *Service {
 	auth := spotifyauth.New(
 		spotifyauth.WithRedirectURL(redirectURI),
 		spotifyauth.WithScopes(
@@ -40,44 +91,85 @@ func NewService(log *slog.Logger, db *gorm.DB, clientID, clientSecret, redirectU
 		spotifyauth.WithClientSecret(clientSecret),
 	)
 
-	return &Service{
-		db:          db,
-		auth:        auth,
-		log:         log,
-		redirectURI: redirectURI,
+	svc := &Service{
+		db:           db,
+		trackRepo:    trackRepo,
+		playlistRepo: playlistRepo,
+		wsManager:    wsManager,
+		auth:         auth,
+		log:          log,
+		redirectURI:  redirectURI,
+		tokenKey:     tokenKey,
+		logins:       newLoginStore(defaultLoginTimeout),
+	}
+	for _, opt := range opts {
+		opt(svc)
 	}
+	return svc
 }
 
-// GenerateAuthURL creates a new auth URL with a secure state string
+// GenerateAuthURL starts a login: it returns the Spotify auth URL and the state the callback must bring back.
+// The URL carries a PKCE challenge, so the code Spotify returns is only usable by this server.
 func (s *Service) GenerateAuthURL() (string, string, error) {
 	state, err := generateState()
 	if err != nil {
 		return "", "", err
 	}
-	url := s.auth.AuthURL(state, spotifyauth.ShowDialog)
+	verifier := oauth2.GenerateVerifier()
+	s.logins.add(state, verifier)
+
+	url := s.auth.AuthURL(state, spotifyauth.ShowDialog, oauth2.S256ChallengeOption(verifier))
 	return url, state, nil
 }
 
-// ExchangeToken exchanges auth code for token and saves to DB
-func (s *Service) ExchangeToken(ctx context.Context, code string) error {
-	token, err := s.auth.Exchange(ctx, code)
+// CancelLogin forgets a started login, e.g. when the user declined access.
+func (s *Service) CancelLogin(state string) {
+	s.logins.take(state)
+}
+
+// ExchangeToken completes the login started with state, exchanging the auth code for a token and saving it
+func (s *Service) ExchangeToken(ctx context.Context, state, code string) error {
+	verifier, ok := s.logins.take(state)
+	if !ok {
+		return ErrInvalidState
+	}
+	token, err := s.auth.Exchange(s.withHTTPClient(ctx), code, oauth2.VerifierOption(verifier))
 	if err != nil {
 		return err
 	}
 
-	return s.saveToken(token)
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	if err := s.saveToken(token); err != nil {
+		return err
+	}
+	s.log.Info("Spotify connected")
+	s.broadcastAuthStatus(true, AuthReasonConnected)
+	return nil
 }
 
-// GetValidToken retrieves token from DB, refreshing if expired
+// GetValidToken retrieves token from DB, refreshing if expired. Spotify rotates the refresh token on every
+// refresh, so only one caller refreshes at a time; the others wait and read the token it saved.
 func (s *Service) GetValidToken(ctx context.Context) (*oauth2.Token, error) {
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+
 	var spotifyToken audio.SpotifyToken
 	if err := s.db.First(&spotifyToken, tokenID).Error; err != nil {
 		return nil, err
 	}
 
+	accessToken, encrypted, err := s.decryptToken("access_token", spotifyToken.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, _, err := s.decryptToken("refresh_token", spotifyToken.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
 	token := &oauth2.Token{
-		AccessToken:  spotifyToken.AccessToken,
-		RefreshToken: spotifyToken.RefreshToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 		TokenType:    spotifyToken.TokenType,
 		Expiry:       spotifyToken.Expiry,
 	}
@@ -85,7 +177,17 @@ func (s *Service) GetValidToken(ctx context.Context) (*oauth2.Token, error) {
 	// Check if token is expired or will expire soon (30 second buffer)
 	if time.Until(token.Expiry) < 30*time.Second {
 		s.log.Info("Token expired, refreshing...")
-		newToken, err := s.auth.RefreshToken(ctx, token)
+		newToken, err := s.auth.RefreshToken(s.withHTTPClient(ctx), token)
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+			// The refresh token is dead for good; keeping it would only fail again on every request.
+			s.log.Warn("Spotify refused the refresh token, disconnecting", "error", err)
+			if err := s.db.Unscoped().Delete(&audio.SpotifyToken{}, tokenID).Error; err != nil {
+				return nil, err
+			}
+			s.broadcastAuthStatus(false, AuthReasonRevoked)
+			return nil, gorm.ErrRecordNotFound
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -95,6 +197,12 @@ func (s *Service) GetValidToken(ctx context.Context) (*oauth2.Token, error) {
 		return newToken, nil
 	}
 
+	// Tokens saved before encryption was added are encrypted on first use.
+	if !encrypted {
+		if err := s.saveToken(token); err != nil {
+			return nil, err
+		}
+	}
 	return token, nil
 }
 
@@ -107,17 +215,39 @@ func (s *Service) IsAuthenticated() bool {
 
 // DeleteToken removes the stored token from the DB (logout)
 func (s *Service) DeleteToken() error {
-	return s.db.Unscoped().Delete(&audio.SpotifyToken{}, tokenID).Error
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+	if err := s.db.Unscoped().Delete(&audio.SpotifyToken{}, tokenID).Error; err != nil {
+		return err
+	}
+	s.broadcastAuthStatus(false, AuthReasonDisconnected)
+	return nil
 }
 
-// saveToken saves or updates the token in the database (singleton pattern)
+// withHTTPClient makes the oauth2 package use the injected HTTP client, if any.
+func (s *Service) withHTTPClient(ctx context.Context) context.Context {
+	if s.httpClient == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, oauth2.HTTPClient, s.httpClient)
+}
+
+// saveToken encrypts the token and saves or updates it in the database (singleton pattern)
 func (s *Service) saveToken(token *oauth2.Token) error {
+	accessToken, err := s.encryptToken("access_token", token.AccessToken)
+	if err != nil {
+		return err
+	}
+	refreshToken, err := s.encryptToken("refresh_token", token.RefreshToken)
+	if err != nil {
+		return err
+	}
 	spotifyToken := audio.SpotifyToken{
 		Model: gorm.Model{
 			ID: tokenID,
 		},
-		AccessToken:  token.AccessToken,
-		RefreshToken: token.RefreshToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 		TokenType:    token.TokenType,
 		Expiry:       token.Expiry,
 	}
@@ -126,6 +256,10 @@ func (s *Service) saveToken(token *oauth2.Token) error {
 	return s.db.Save(&spotifyToken).Error
 }
 
+func (s *Service) broadcastAuthStatus(authenticated bool, reason string) {
+	s.wsManager.Broadcast(websocket.Event{Type: EventAuthStatus, Payload: AuthStatus{Authenticated: authenticated, Reason: reason}})
+}
+
 // generateState creates a secure random state string
 func generateState() (string, error) {
 	b := make([]byte, 32)
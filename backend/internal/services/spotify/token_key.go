@@ -0,0 +1,106 @@
+package spotify
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// encryptedPrefix marks stored token values that are encrypted. Values without it were saved in plaintext
+// before encryption was added; they are still read, and encrypted the next time the token is saved.
+const encryptedPrefix = "enc:"
+
+// TokenKey is the AES-256 key stored tokens are encrypted with.
+type TokenKey [32]byte
+
+// LoadOrCreateTokenKey reads the key file at path, generating a new random key there on first use.
+// Losing the file makes the stored token unreadable; logging out and connecting again fixes that.
+func LoadOrCreateTokenKey(path string) (TokenKey, error) {
+	var key TokenKey
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, err = rand.Read(key[:]); err != nil {
+			return key, err
+		}
+		if dir := filepath.Dir(path); dir != "." {
+			if err = os.MkdirAll(dir, 0o700); err != nil {
+				return key, err
+			}
+		}
+		// O_EXCL so a key written in the meantime is never replaced.
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if err != nil {
+			return key, err
+		}
+		defer file.Close()
+		_, err = file.WriteString(base64.StdEncoding.EncodeToString(key[:]))
+		return key, err
+	}
+	if err != nil {
+		return key, err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(decoded) != len(key) {
+		return key, fmt.Errorf("%s is not a valid token key", path)
+	}
+	copy(key[:], decoded)
+	return key, nil
+}
+
+// Helpers
+
+// encryptToken seals value with the key. The column name is bound in as additional data, so an access
+// token cannot be passed off as a refresh token.
+func (s *Service) encryptToken(column, value string) (string, error) {
+	gcm, err := s.tokenCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(column))
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptToken opens a value sealed by encryptToken. The second result is false for plaintext values.
+func (s *Service) decryptToken(column, value string) (string, bool, error) {
+	encoded, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, false, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", true, err
+	}
+	gcm, err := s.tokenCipher()
+	if err != nil {
+		return "", true, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", true, errors.New("stored token is truncated")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, []byte(column))
+	if err != nil {
+		return "", true, fmt.Errorf("stored token cannot be decrypted with the key file: %w", err)
+	}
+	return string(plain), true, nil
+}
+
+func (s *Service) tokenCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.tokenKey[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -4,40 +4,231 @@ package websocket
 import (
 	websocket2 "dmd/backend/internal/model/websocket"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
+// Role is what a client is used for, chosen when it connects. It decides which targeted events reach it.
+type Role string
+
+const (
+	RoleDM      Role = "dm"      // The DM's screen, which sees everything
+	RoleDisplay Role = "display" // A shared screen the players look at, e.g. the TV or table
+	RolePlayer  Role = "player"  // A player's own device
+)
+
+// maxRoomsPerClient and maxRoomNameLength keep a client from growing the room table without bounds.
+const (
+	maxRoomsPerClient = 16
+	maxRoomNameLength = 64
+)
+
+var (
+	ErrInvalidRole  = errors.New("invalid websocket role")
+	ErrInvalidRoom  = errors.New("invalid websocket room")
+	ErrNotPermitted = errors.New("message not permitted for this websocket role")
+)
+
+// ParseRole reads a role from a connection request. Clients that do not say get the least privileged role.
+func ParseRole(s string) (Role, error) {
+	switch role := Role(s); role {
+	case "":
+		return RolePlayer, nil
+	case RoleDM, RoleDisplay, RolePlayer:
+		return role, nil
+	default:
+		return "", fmt.Errorf("%w: %q, must be %s, %s or %s", ErrInvalidRole, s, RoleDM, RoleDisplay, RolePlayer)
+	}
+}
+
 // Client represents a single WebSocket connection.
 type Client struct {
-	conn    *websocket.Conn
-	manager *Manager
-	send    chan []byte
+	conn        *websocket.Conn
+	manager     *Manager
+	send        chan []byte
+	id          string
+	role        Role
+	connectedAt time.Time
+	lastSeen    atomic.Int64 // Unix nanoseconds of the last message or pong from the client
+	lastSeq     *uint64      // Last event the client saw before reconnecting, if it asked to resume
+
+	roomsMu sync.Mutex
+	rooms   map[string]bool
+}
+
+// ValidateRooms checks the rooms a client asks to start in.
+func ValidateRooms(rooms []string) error {
+	for _, room := range rooms {
+		if err := validateRoom(room); err != nil {
+			return err
+		}
+	}
+	if len(rooms) > maxRoomsPerClient {
+		return fmt.Errorf("%w: a client can be in at most %d rooms", ErrInvalidRoom, maxRoomsPerClient)
+	}
+	return nil
 }
 
-func NewClient(conn *websocket.Conn, manager *Manager) *Client {
+// NewClient creates a client in the given rooms, which must have passed ValidateRooms.
+func NewClient(conn *websocket.Conn, manager *Manager, role Role, rooms ...string) *Client {
 	client := &Client{
 		conn:    conn,
 		manager: manager,
-		send:    make(chan []byte, 256),
+		send:    make(chan []byte, manager.sendBufferSize()),
+		id:      uuid.NewString(),
+		role:    role,
+		rooms:   make(map[string]bool),
+
+		connectedAt: time.Now(),
+	}
+	client.lastSeen.Store(client.connectedAt.UnixNano())
+	for _, room := range rooms {
+		client.rooms[room] = true
 	}
 
 	return client
 }
 
-// ReadPump pumps messages from the WebSocket connection to the manager.
+// ResumeFrom asks for the events after lastSeq to be replayed when the client registers.
+func (c *Client) ResumeFrom(lastSeq uint64) {
+	c.lastSeq = &lastSeq
+}
+
+// ID identifies the connection; a client that reconnects gets a new one.
+func (c *Client) ID() string {
+	return c.id
+}
+
+func (c *Client) Role() Role {
+	return c.role
+}
+
+// RequireRole fails unless the client connected with the given role. Message handlers that change
+// shared state call it, so that only the DM's screen can drive them.
+func (c *Client) RequireRole(role Role) error {
+	if c.role != role {
+		return fmt.Errorf("%w: needs %s, client is %s", ErrNotPermitted, role, c.role)
+	}
+	return nil
+}
+
+// JoinRoom adds the client to a named room. Rooms are created by joining them.
+func (c *Client) JoinRoom(room string) error {
+	if err := validateRoom(room); err != nil {
+		return err
+	}
+	c.roomsMu.Lock()
+	defer c.roomsMu.Unlock()
+	if !c.rooms[room] && len(c.rooms) >= maxRoomsPerClient {
+		return fmt.Errorf("%w: a client can be in at most %d rooms", ErrInvalidRoom, maxRoomsPerClient)
+	}
+	c.rooms[room] = true
+	return nil
+}
+
+func (c *Client) LeaveRoom(room string) {
+	c.roomsMu.Lock()
+	defer c.roomsMu.Unlock()
+	delete(c.rooms, room)
+}
+
+func (c *Client) InRoom(room string) bool {
+	c.roomsMu.Lock()
+	defer c.roomsMu.Unlock()
+	return c.rooms[room]
+}
+
+// Rooms returns the rooms the client is in, sorted.
+func (c *Client) Rooms() []string {
+	c.roomsMu.Lock()
+	defer c.roomsMu.Unlock()
+	rooms := make([]string, 0, len(c.rooms))
+	for room := range c.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+func validateRoom(room string) error {
+	if room == "" || len(room) > maxRoomNameLength {
+		return fmt.Errorf("%w: room names must be 1 to %d characters", ErrInvalidRoom, maxRoomNameLength)
+	}
+	return nil
+}
+
+func (c *Client) Info() ClientInfo {
+	return ClientInfo{ID: c.id, Role: c.role, Rooms: c.Rooms()}
+}
+
+// LastSeen is when the client last sent a message or answered a ping.
+func (c *Client) LastSeen() time.Time {
+	return time.Unix(0, c.lastSeen.Load())
+}
+
+func (c *Client) stats() ClientStats {
+	stats := ClientStats{
+		ClientInfo:  c.Info(),
+		ConnectedAt: c.connectedAt,
+		LastSeen:    c.LastSeen(),
+		Queued:      len(c.send),
+	}
+	if c.conn != nil {
+		stats.RemoteAddr = c.conn.RemoteAddr().String()
+	}
+	return stats
+}
+
+func (c *Client) touch() {
+	c.lastSeen.Store(time.Now().UnixNano())
+}
+
+// extendReadDeadline gives the client another pong timeout to show it is still there.
+func (c *Client) extendReadDeadline() {
+	c.conn.SetReadDeadline(time.Now().Add(c.manager.heartbeat.PongTimeout))
+}
+
+// ReadPump pumps messages from the WebSocket connection to the manager. A client that sends nothing,
+// not even a pong, for the heartbeat's pong timeout is dropped, as is one that sends an oversized message.
 func (c *Client) ReadPump() {
 	defer func() {
 		c.manager.unregister <- c
 		c.conn.Close()
 	}()
 
+	c.conn.SetReadLimit(c.manager.heartbeat.MaxMessageSize)
+	c.extendReadDeadline()
+	c.conn.SetPongHandler(func(string) error {
+		c.touch()
+		c.extendReadDeadline()
+		return nil
+	})
+
 	for {
 		_, messageBytes, err := c.conn.ReadMessage()
 		if err != nil {
-			c.manager.log.Error("ReadPump error", "error", err, "msg", string(messageBytes))
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				c.manager.log.Info("Client stopped responding", "id", c.id, "role", c.role, "last_seen", c.LastSeen())
+			case errors.Is(err, websocket.ErrReadLimit):
+				c.manager.log.Warn("Client sent an oversized message", "id", c.id, "limit", c.manager.heartbeat.MaxMessageSize)
+			case websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway):
+			default:
+				c.manager.log.Error("ReadPump error", "error", err, "msg", string(messageBytes))
+			}
 			break
 		}
+		c.touch()
+		c.extendReadDeadline()
 
 		var msg websocket2.Message
 		if err := json.Unmarshal(messageBytes, &msg); err != nil {
@@ -54,13 +245,34 @@ func (c *Client) ReadPump() {
 	}
 }
 
-// WritePump pumps messages from the manager to the WebSocket connection.
+// WritePump pumps messages from the manager to the WebSocket connection and pings the client.
+// A write that takes longer than the heartbeat's write timeout closes the connection.
 func (c *Client) WritePump() {
-	defer c.conn.Close()
-	for message := range c.send {
-		if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			c.manager.log.Warn("WritePump error", "error", err)
-			break
+	ticker := time.NewTicker(c.manager.heartbeat.PingInterval)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.manager.heartbeat.WriteTimeout))
+			if !ok {
+				// The manager dropped the client.
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				c.manager.log.Warn("WritePump error", "error", err)
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.manager.heartbeat.WriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.manager.log.Warn("Failed to ping client", "id", c.id, "error", err)
+				return
+			}
 		}
 	}
 }
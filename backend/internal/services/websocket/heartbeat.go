@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"sort"
+	"time"
+)
+
+// Heartbeat controls how dead connections are found. Every PingInterval the server pings each client; a client
+// that sends nothing, not even the pong, for PongTimeout is dropped, as is one whose writes stall for WriteTimeout.
+// That clears out half-open connections, e.g. from a laptop that went to sleep.
+type Heartbeat struct {
+	PingInterval   time.Duration
+	PongTimeout    time.Duration
+	WriteTimeout   time.Duration
+	MaxMessageSize int64 // Bytes; larger incoming messages close the connection
+}
+
+func DefaultHeartbeat() Heartbeat {
+	return Heartbeat{
+		PingInterval:   30 * time.Second,
+		PongTimeout:    60 * time.Second,
+		WriteTimeout:   10 * time.Second,
+		MaxMessageSize: 1 << 20,
+	}
+}
+
+// withDefaults fills in unset values, and stretches a pong timeout that would expire before the next ping.
+func (h Heartbeat) withDefaults() Heartbeat {
+	defaults := DefaultHeartbeat()
+	if h.PingInterval <= 0 {
+		h.PingInterval = defaults.PingInterval
+	}
+	if h.PongTimeout <= 0 {
+		h.PongTimeout = defaults.PongTimeout
+	}
+	if h.PongTimeout <= h.PingInterval {
+		h.PongTimeout = 2 * h.PingInterval
+	}
+	if h.WriteTimeout <= 0 {
+		h.WriteTimeout = defaults.WriteTimeout
+	}
+	if h.MaxMessageSize <= 0 {
+		h.MaxMessageSize = defaults.MaxMessageSize
+	}
+	return h
+}
+
+type Option func(*Manager)
+
+// WithHeartbeat replaces DefaultHeartbeat. Zero values keep their default.
+func WithHeartbeat(heartbeat Heartbeat) Option {
+	return func(m *Manager) {
+		m.heartbeat = heartbeat.withDefaults()
+	}
+}
+
+// ClientStats describes a connected client.
+type ClientStats struct {
+	ClientInfo
+	RemoteAddr  string    `json:"remote_addr"`
+	ConnectedAt time.Time `json:"connected_at"`
+	LastSeen    time.Time `json:"last_seen"`
+	Queued      int       `json:"queued"` // Events waiting to be written to the client
+}
+
+// Clients lists the connected clients, longest connected first.
+func (m *Manager) Clients() []ClientStats {
+	reply := make(chan []ClientStats)
+	m.statsRequests <- reply
+	stats := <-reply
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ConnectedAt.Before(stats[j].ConnectedAt) })
+	return stats
+}
+
+// Heartbeat returns the settings the manager's clients use.
+func (m *Manager) Heartbeat() Heartbeat {
+	return m.heartbeat
+}
@@ -7,26 +7,82 @@ import (
 )
 
 type MessageHandler func(payload json.RawMessage, client *Client)
+
+const (
+	EventClientInfo          = "client_info"
+	EventRoomError           = "room_error"
+	MessageJoinRoom          = "join_room"
+	MessageLeaveRoom         = "leave_room"
+	MessageClientInfoRequest = "client_info_request"
+)
+
+// RoomRequest is the payload of join_room and leave_room messages.
+type RoomRequest struct {
+	Room string `json:"room"`
+}
+
+// ClientInfo tells a client how the server sees it. It is sent in reply to room and client_info_request messages.
+type ClientInfo struct {
+	ID    string   `json:"id"`
+	Role  Role     `json:"role"`
+	Rooms []string `json:"rooms"`
+}
+
+// RoomError is sent back to a client that could not join a room.
+type RoomError struct {
+	Room  string `json:"room"`
+	Error string `json:"error"`
+}
+
+// targetedEvent is an event for the clients that match; a nil match means every client.
+// Transient events, i.e. replies to a single client and one-shot cues, are neither numbered nor kept for replay,
+// unless they come with a replay event to keep in their place.
+type targetedEvent struct {
+	match     func(*Client) bool
+	event     websocket.Event
+	transient bool
+	replay    *websocket.Event
+}
+
 type Manager struct {
 	clients    map[*Client]bool
-	broadcast  chan websocket.Event
+	outgoing   chan targetedEvent
 	register   chan *Client
 	unregister chan *Client
 	log        *slog.Logger
 	handlers   map[string]MessageHandler
+	heartbeat  Heartbeat
+
+	statsRequests chan chan []ClientStats
+
+	seq            uint64 // Sequence number of the last numbered event
+	history        *eventHistory
+	resyncHandlers []ResyncHandler
 }
 
-func NewManager(log *slog.Logger) *Manager {
+func NewManager(log *slog.Logger, opts ...Option) *Manager {
 	m := &Manager{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan websocket.Event),
+		outgoing:   make(chan targetedEvent),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		log:        log,
 		handlers:   make(map[string]MessageHandler),
+		heartbeat:  DefaultHeartbeat(),
+
+		statsRequests: make(chan chan []ClientStats),
+
+		seq:     firstSeq(),
+		history: newEventHistory(DefaultReplayBuffer),
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
 
 	m.handlers["send_message"] = m.handleChatMessage
+	m.handlers[MessageJoinRoom] = m.handleJoinRoom
+	m.handlers[MessageLeaveRoom] = m.handleLeaveRoom
+	m.handlers[MessageClientInfoRequest] = m.handleClientInfoRequest
 
 	return m
 }
@@ -37,36 +93,108 @@ func (m *Manager) Run() {
 		select {
 		case client := <-m.register:
 			m.clients[client] = true
-			m.log.Info("Client registered", "remote_addr", client.conn.RemoteAddr())
+			m.log.Info("Client registered", "remote_addr", client.conn.RemoteAddr(), "id", client.id, "role", client.role)
+			if client.lastSeq != nil && m.resume(client) {
+				// Resync handlers send through the manager, so they cannot run on this loop.
+				go m.resync(client)
+			}
 		case client := <-m.unregister:
 			if _, ok := m.clients[client]; ok {
 				delete(m.clients, client)
 				close(client.send)
 				m.log.Info("Client unregistered", "remote_addr", client.conn.RemoteAddr())
 			}
-		case event := <-m.broadcast:
+		case te := <-m.outgoing:
+			if !te.transient || te.replay != nil {
+				m.seq++
+				te.event.Seq = m.seq
+			}
 			// Marshal the event to JSON
-			messageBytes, err := json.Marshal(event)
+			messageBytes, err := json.Marshal(te.event)
 			if err != nil {
 				m.log.Error("Failed to marshal event", "error", err)
 				continue
 			}
-			// Send the marshaled message to all clients
+			if !te.transient {
+				m.history.add(sentEvent{seq: te.event.Seq, match: te.match, bytes: messageBytes})
+			} else if te.replay != nil {
+				m.keepForReplay(te)
+			}
+			// Send the marshaled message to every matching client
 			for client := range m.clients {
-				select {
-				case client.send <- messageBytes:
-				default:
-					close(client.send)
-					delete(m.clients, client)
+				if te.match == nil || te.match(client) {
+					m.sendToClient(client, messageBytes)
 				}
 			}
+		case reply := <-m.statsRequests:
+			stats := make([]ClientStats, 0, len(m.clients))
+			for client := range m.clients {
+				stats = append(stats, client.stats())
+			}
+			reply <- stats
 		}
 	}
 }
 
-// Broadcast sends an event to the broadcast channel.
+// sendToClient queues a message on the client's send channel, dropping the
+// client if its buffer is full. It must only be called from the Run loop.
+func (m *Manager) sendToClient(client *Client, messageBytes []byte) bool {
+	select {
+	case client.send <- messageBytes:
+		return true
+	default:
+		close(client.send)
+		delete(m.clients, client)
+		return false
+	}
+}
+
+// sendBufferSize leaves room for a full replay on top of the usual backlog.
+func (m *Manager) sendBufferSize() int {
+	return max(256, len(m.history.events)+64)
+}
+
+// Broadcast sends an event to every client.
 func (m *Manager) Broadcast(event websocket.Event) {
-	m.broadcast <- event
+	m.outgoing <- targetedEvent{event: event}
+}
+
+// SendTo sends an event to a single client, if it is still connected.
+func (m *Manager) SendTo(client *Client, event websocket.Event) {
+	m.outgoing <- targetedEvent{match: func(c *Client) bool { return c == client }, event: event, transient: true}
+}
+
+// BroadcastTransient sends an event to every client that is connected now. It is not replayed to clients
+// that reconnect, so it suits one-shot cues like a sound effect, which would be wrong to play late.
+func (m *Manager) BroadcastTransient(event websocket.Event) {
+	m.outgoing <- targetedEvent{event: event, transient: true}
+}
+
+// BroadcastWithReplay sends an event to every client that is connected now, and keeps replay in its place
+// for clients that reconnect, e.g. the state a fade leads to instead of the fade itself.
+func (m *Manager) BroadcastWithReplay(event, replay websocket.Event) {
+	m.outgoing <- targetedEvent{event: event, transient: true, replay: &replay}
+}
+
+// SendToRole sends an event to the clients that connected with the role, e.g. DM-only data to RoleDM.
+func (m *Manager) SendToRole(role Role, event websocket.Event) {
+	m.SendWhere(func(c *Client) bool { return c.role == role }, event)
+}
+
+// SendToRoom sends an event to the clients in a room.
+func (m *Manager) SendToRoom(room string, event websocket.Event) {
+	m.SendWhere(func(c *Client) bool { return c.InRoom(room) }, event)
+}
+
+// BroadcastExcept sends an event to every client but the sender, which already knows what it did.
+func (m *Manager) BroadcastExcept(sender *Client, event websocket.Event) {
+	m.SendWhere(func(c *Client) bool { return c != sender }, event)
+}
+
+// SendWhere sends an event to the clients match accepts. match runs on the manager's event loop,
+// so it must not block or call back into the manager.
+func (m *Manager) SendWhere(match func(client *Client) bool, event websocket.Event) {
+	m.outgoing <- targetedEvent{match: match, event: event}
 }
 
 func (m *Manager) RegisterClient(client *Client) {
@@ -74,6 +202,12 @@ func (m *Manager) RegisterClient(client *Client) {
 
 }
 
+// RegisterHandler binds an incoming message type to a handler.
+// It must be called before Run is started.
+func (m *Manager) RegisterHandler(msgType string, handler MessageHandler) {
+	m.handlers[msgType] = handler
+}
+
 func (m *Manager) handleChatMessage(payload json.RawMessage, client *Client) {
 	var chatMessage websocket.ChatMessage
 	if err := json.Unmarshal(payload, &chatMessage); err != nil {
@@ -88,5 +222,32 @@ func (m *Manager) handleChatMessage(payload json.RawMessage, client *Client) {
 		Type:    "new_chat_message",
 		Payload: chatMessage,
 	}
-	m.broadcast <- event
+	m.Broadcast(event)
+}
+
+func (m *Manager) handleJoinRoom(payload json.RawMessage, client *Client) {
+	var req RoomRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		m.log.Warn("Failed to unmarshal room request", "error", err)
+		return
+	}
+	if err := client.JoinRoom(req.Room); err != nil {
+		m.SendTo(client, websocket.Event{Type: EventRoomError, Payload: RoomError{Room: req.Room, Error: err.Error()}})
+		return
+	}
+	m.SendTo(client, websocket.Event{Type: EventClientInfo, Payload: client.Info()})
+}
+
+func (m *Manager) handleLeaveRoom(payload json.RawMessage, client *Client) {
+	var req RoomRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		m.log.Warn("Failed to unmarshal room request", "error", err)
+		return
+	}
+	client.LeaveRoom(req.Room)
+	m.SendTo(client, websocket.Event{Type: EventClientInfo, Payload: client.Info()})
+}
+
+func (m *Manager) handleClientInfoRequest(_ json.RawMessage, client *Client) {
+	m.SendTo(client, websocket.Event{Type: EventClientInfo, Payload: client.Info()})
 }
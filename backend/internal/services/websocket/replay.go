@@ -0,0 +1,146 @@
+package websocket
+
+import (
+	"dmd/backend/internal/model/websocket"
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventResumed = "resumed"
+	EventResync  = "resync"
+)
+
+// DefaultReplayBuffer is how many recent events are kept for clients that reconnect.
+const DefaultReplayBuffer = 256
+
+// ResumeStatus answers a client that reconnected with ?last_seq=. Seq is the newest sequence number sent.
+type ResumeStatus struct {
+	Seq      uint64 `json:"seq"`
+	Replayed int    `json:"replayed,omitempty"` // Missed events sent ahead of this one
+	Reason   string `json:"reason,omitempty"`   // Why the client has to resync
+}
+
+// ResyncHandler sends a client that missed too much the current state it needs, e.g. the display state.
+type ResyncHandler func(client *Client)
+
+// WithReplayBuffer keeps the given number of recent events instead of DefaultReplayBuffer.
+func WithReplayBuffer(size int) Option {
+	return func(m *Manager) {
+		if size > 0 {
+			m.history = newEventHistory(size)
+		}
+	}
+}
+
+// RegisterResyncHandler adds a handler that runs when a client has to resync.
+// It must be called before Run is started.
+func (m *Manager) RegisterResyncHandler(handler ResyncHandler) {
+	m.resyncHandlers = append(m.resyncHandlers, handler)
+}
+
+// firstSeq starts sequence numbers from the time the server started, so they keep growing across restarts
+// and a number a client remembers from before a restart is always too old to resume from. It stays well
+// within the integers JavaScript can represent.
+func firstSeq() uint64 {
+	return uint64(time.Now().UnixMilli()) * 1000
+}
+
+// sentEvent is an event as it was sent, kept for replay.
+type sentEvent struct {
+	seq   uint64
+	match func(*Client) bool
+	bytes []byte
+}
+
+// eventHistory is a ring buffer of the most recent sequenced events. It is only used from the Run loop.
+type eventHistory struct {
+	events []sentEvent
+	next   int // Where the next event goes
+	full   bool
+}
+
+func newEventHistory(size int) *eventHistory {
+	return &eventHistory{events: make([]sentEvent, size)}
+}
+
+func (h *eventHistory) add(event sentEvent) {
+	h.events[h.next] = event
+	h.next = (h.next + 1) % len(h.events)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// since returns the events after lastSeq, oldest first. ok is false when some of them are no longer kept.
+func (h *eventHistory) since(lastSeq, currentSeq uint64) (missed []sentEvent, ok bool) {
+	if lastSeq > currentSeq {
+		return nil, false
+	}
+	start, count := 0, h.next
+	if h.full {
+		start, count = h.next, len(h.events)
+	}
+	oldest := currentSeq + 1 // Nothing kept yet, so only an up-to-date client can resume
+	if count > 0 {
+		oldest = h.events[start].seq
+	}
+	if lastSeq+1 < oldest {
+		return nil, false
+	}
+	for i := 0; i < count; i++ {
+		if event := h.events[(start+i)%len(h.events)]; event.seq > lastSeq {
+			missed = append(missed, event)
+		}
+	}
+	return missed, true
+}
+
+// resume sends a newly registered client the events it missed while it was away, before any new ones.
+// It reports whether the client fell too far behind and has to resync instead.
+func (m *Manager) resume(client *Client) bool {
+	missed, ok := m.history.since(*client.lastSeq, m.seq)
+	if !ok {
+		m.sendStatus(client, EventResync, ResumeStatus{Seq: m.seq, Reason: "missed events are no longer available"})
+		return true
+	}
+	replayed := 0
+	for _, event := range missed {
+		if event.match != nil && !event.match(client) {
+			continue
+		}
+		if !m.sendToClient(client, event.bytes) {
+			return false
+		}
+		replayed++
+	}
+	m.sendStatus(client, EventResumed, ResumeStatus{Seq: m.seq, Replayed: replayed})
+	return false
+}
+
+// keepForReplay numbers a transient event's replay event like the event itself and adds it to the history.
+func (m *Manager) keepForReplay(te targetedEvent) {
+	te.replay.Seq = te.event.Seq
+	messageBytes, err := json.Marshal(te.replay)
+	if err != nil {
+		m.log.Error("Failed to marshal event", "error", err)
+		return
+	}
+	m.history.add(sentEvent{seq: te.replay.Seq, match: te.match, bytes: messageBytes})
+}
+
+func (m *Manager) sendStatus(client *Client, eventType string, status ResumeStatus) {
+	messageBytes, err := json.Marshal(websocket.Event{Type: eventType, Payload: status})
+	if err != nil {
+		m.log.Error("Failed to marshal event", "error", err)
+		return
+	}
+	m.sendToClient(client, messageBytes)
+}
+
+// resync sends the client the resync event's follow-up: the current state from every resync handler.
+func (m *Manager) resync(client *Client) {
+	for _, handler := range m.resyncHandlers {
+		handler(client)
+	}
+}
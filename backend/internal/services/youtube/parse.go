@@ -0,0 +1,202 @@
+package youtube
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Entry is a video found in an export, with whatever metadata the export itself carried.
+type Entry struct {
+	VideoID string
+	Title   string
+	Artist  string
+}
+
+var (
+	videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+	// urlPattern finds YouTube links in free text, with or without a scheme.
+	urlPattern = regexp.MustCompile(`(?i)(?:https?://)?(?:[a-z0-9-]+\.)*(?:youtube\.com|youtu\.be|youtube-nocookie\.com)/[^\s"'<>,]*`)
+	// bareIDPattern finds a video ID that starts a line on its own, like the rows of a Takeout playlist CSV.
+	bareIDPattern = regexp.MustCompile(`(?m)^\s*([A-Za-z0-9_-]{11})\s*(?:,|$)`)
+)
+
+// Keys a JSON export may keep the video ID, title and channel under. Google Takeout, the Data API and
+// most export tools use one of these.
+var (
+	idKeys     = []string{"videoId", "video_id", "videoID"}
+	titleKeys  = []string{"title", "name"}
+	artistKeys = []string{"videoOwnerChannelTitle", "channelTitle", "channel", "author", "artist", "uploader"}
+)
+
+// VideoID returns the video a YouTube link points at: watch, youtu.be, shorts, embed, live and music links
+// are understood. A bare 11-character ID is returned as is.
+func VideoID(link string) (string, bool) {
+	link = strings.TrimSpace(link)
+	if videoIDPattern.MatchString(link) {
+		return link, true
+	}
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	var id string
+	switch {
+	case host == "youtu.be":
+		id = segments[0]
+	case host == "youtube.com", host == "m.youtube.com", host == "music.youtube.com", host == "youtube-nocookie.com":
+		switch segments[0] {
+		case "watch":
+			id = u.Query().Get("v")
+		case "shorts", "embed", "live", "v":
+			if len(segments) > 1 {
+				id = segments[1]
+			}
+		}
+	}
+	if !videoIDPattern.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// ParseExport finds the videos in pasted text or an exported file. JSON is searched for links and video ID
+// fields, taking titles and channels from the same objects; anything else is searched as text for links and
+// for lines that start with a bare video ID. Each video is listed once, in the order first seen.
+// links returns the YouTube links that do not point at a single video, e.g. playlist or channel links.
+func ParseExport(data []byte) (entries []Entry, links []string) {
+	p := &exportParser{seen: make(map[string]int)}
+	var doc any
+	if json.Unmarshal(data, &doc) == nil {
+		p.walk(doc)
+	} else {
+		p.scanText(string(data))
+	}
+	return p.entries, p.unmatched
+}
+
+// Helpers
+
+type exportParser struct {
+	entries   []Entry
+	seen      map[string]int // Video ID to index in entries
+	unmatched []string
+}
+
+func (p *exportParser) add(id string) {
+	if _, ok := p.seen[id]; !ok {
+		p.seen[id] = len(p.entries)
+		p.entries = append(p.entries, Entry{VideoID: id})
+	}
+}
+
+func (p *exportParser) scanText(text string) {
+	p.scanLinks(text)
+	for _, match := range bareIDPattern.FindAllStringSubmatch(text, -1) {
+		p.add(match[1])
+	}
+}
+
+// scanLinks records the videos linked in text and returns their IDs.
+func (p *exportParser) scanLinks(text string) []string {
+	var ids []string
+	for _, link := range urlPattern.FindAllString(text, -1) {
+		if id, ok := VideoID(link); ok {
+			p.add(id)
+			ids = append(ids, id)
+		} else if !contains(p.unmatched, link) {
+			p.unmatched = append(p.unmatched, link)
+		}
+	}
+	return ids
+}
+
+// walk records the videos in a JSON value and returns their IDs. An object that holds exactly one video
+// lends it its title and channel, unless an object nested deeper already did. Strings outside lists are only
+// searched for links, as any 11-letter word would pass for a bare video ID.
+func (p *exportParser) walk(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return p.scanLinks(v)
+	case []any:
+		var ids []string
+		for _, item := range v {
+			// A list of plain IDs is a common export, so a bare ID counts when it is a list item.
+			if s, ok := item.(string); ok && videoIDPattern.MatchString(strings.TrimSpace(s)) {
+				p.add(strings.TrimSpace(s))
+				ids = append(ids, strings.TrimSpace(s))
+				continue
+			}
+			ids = append(ids, p.walk(item)...)
+		}
+		return ids
+	case map[string]any:
+		var ids []string
+		for _, key := range idKeys {
+			if id, ok := v[key].(string); ok && videoIDPattern.MatchString(id) {
+				p.add(id)
+				ids = append(ids, id)
+			}
+		}
+		// Sorted, so videos are listed in the same order every time.
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			if !contains(titleKeys, key) && !contains(artistKeys, key) && !contains(idKeys, key) {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			ids = append(ids, p.walk(v[key])...)
+		}
+		if single, ok := singleID(ids); ok {
+			entry := &p.entries[p.seen[single]]
+			if entry.Title == "" {
+				entry.Title = firstString(v, titleKeys)
+			}
+			if entry.Artist == "" {
+				entry.Artist = firstString(v, artistKeys)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+func singleID(ids []string) (string, bool) {
+	if len(ids) == 0 {
+		return "", false
+	}
+	for _, id := range ids[1:] {
+		if id != ids[0] {
+			return "", false
+		}
+	}
+	return ids[0], true
+}
+
+func firstString(object map[string]any, keys []string) string {
+	for _, key := range keys {
+		if s, ok := object[key].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrNoMetadata is returned by resolvers that know nothing about a video.
+var ErrNoMetadata = errors.New("no metadata for video")
+
+// Metadata is what a resolver learned about a video. Empty fields are unknown.
+type Metadata struct {
+	Title    string
+	Artist   string
+	Duration uint // Seconds
+}
+
+// Resolver looks up a video's metadata. Imports work without one: videos keep what their export said
+// about them, or a placeholder title.
+type Resolver interface {
+	Resolve(ctx context.Context, videoID string) (Metadata, error)
+}
+
+// OfflineResolver never looks anything up.
+type OfflineResolver struct{}
+
+func (OfflineResolver) Resolve(context.Context, string) (Metadata, error) {
+	return Metadata{}, ErrNoMetadata
+}
+
+// OEmbedResolver reads the title and channel from YouTube's oEmbed endpoint, which needs no API key.
+// oEmbed does not report durations.
+type OEmbedResolver struct {
+	Client   *http.Client
+	Endpoint string // Defaults to https://www.youtube.com/oembed
+}
+
+func NewOEmbedResolver() *OEmbedResolver {
+	return &OEmbedResolver{
+		Client:   &http.Client{Timeout: 5 * time.Second},
+		Endpoint: "https://www.youtube.com/oembed",
+	}
+}
+
+func (r *OEmbedResolver) Resolve(ctx context.Context, videoID string) (Metadata, error) {
+	query := url.Values{"format": {"json"}, "url": {WatchURL(videoID)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.Endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	// Private, removed and unknown videos
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusBadRequest:
+		return Metadata{}, ErrNoMetadata
+	case resp.StatusCode != http.StatusOK:
+		return Metadata{}, fmt.Errorf("oembed lookup for %s: %s", videoID, resp.Status)
+	}
+
+	var body struct {
+		Title      string `json:"title"`
+		AuthorName string `json:"author_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Metadata{}, err
+	}
+	return Metadata{Title: body.Title, Artist: body.AuthorName}, nil
+}
+
+// WatchURL is the page a video plays on.
+func WatchURL(videoID string) string {
+	return "https://www.youtube.com/watch?v=" + videoID
+}
+
+// ThumbnailURL is the video's 480x360 thumbnail, which every video has.
+func ThumbnailURL(videoID string) string {
+	return "https://i.ytimg.com/vi/" + videoID + "/hqdefault.jpg"
+}
@@ -0,0 +1,259 @@
+package youtube
+
+import (
+	"context"
+	"dmd/backend/internal/api/common/filters"
+	"dmd/backend/internal/model/audio"
+	"dmd/backend/internal/model/websocket"
+	"dmd/backend/internal/platform/storage/repos"
+	audioSvc "dmd/backend/internal/services/audio"
+	wsService "dmd/backend/internal/services/websocket"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// maxVideos caps a single import, so a huge export cannot flood the metadata lookups.
+const maxVideos = 500
+
+var ErrInvalidImport = errors.New("invalid youtube import")
+
+// ImportResult lists the tracks for every video in the export, in export order.
+type ImportResult struct {
+	Tracks    []*audio.Track `json:"tracks"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`   // Placeholder tracks that got a title from this export
+	Existing  int            `json:"existing"`  // Tracks that were already in the library
+	Resolving int            `json:"resolving"` // Tracks queued for a metadata lookup in the background
+	Ignored   []string       `json:"ignored"`   // YouTube links that do not point at a single video
+}
+
+type Service struct {
+	log       *slog.Logger
+	repo      repos.TrackRepository
+	resolver  Resolver
+	wsManager *wsService.Manager
+
+	resolveMu      sync.Mutex
+	resolveQueue   []string // Video IDs in import order
+	resolvePending map[string]bool
+	resolveWake    chan struct{}
+}
+
+// NewService creates the service. Without a resolver, metadata only comes from the exports themselves.
+func NewService(log *slog.Logger, repo repos.TrackRepository, resolver Resolver, wsManager *wsService.Manager) *Service {
+	if resolver == nil {
+		resolver = OfflineResolver{}
+	}
+	return &Service{
+		log:       log,
+		repo:      repo,
+		resolver:  resolver,
+		wsManager: wsManager,
+
+		resolvePending: make(map[string]bool),
+		resolveWake:    make(chan struct{}, 1),
+	}
+}
+
+// Import creates a youtube track for every video in data, which is pasted text or an exported file
+// (see ParseExport). Videos already in the library are reused. New videos and ones that are still missing a
+// title are saved with what the export knows, and the rest is looked up in the background (see
+// RunMetadataResolver), so the import never waits on the network.
+func (s *Service) Import(ctx context.Context, data []byte) (*ImportResult, error) {
+	entries, ignored := ParseExport(data)
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no YouTube videos found", ErrInvalidImport)
+	}
+	if len(entries) > maxVideos {
+		return nil, fmt.Errorf("%w: found %d videos, at most %d can be imported at once", ErrInvalidImport, len(entries), maxVideos)
+	}
+
+	result := &ImportResult{Tracks: make([]*audio.Track, len(entries)), Ignored: ignored}
+	if result.Ignored == nil {
+		result.Ignored = []string{}
+	}
+	var toSave []*audio.Track
+	var toResolve []string
+	for i, entry := range entries {
+		existing, err := s.repo.GetTrackBySourceID(audio.SourceYouTube, entry.VideoID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil && (existing.Title != placeholderTitle(entry.VideoID) || entry.Title == "") {
+			if existing.Title == placeholderTitle(entry.VideoID) {
+				toResolve = append(toResolve, entry.VideoID)
+			}
+			result.Tracks[i] = existing
+			result.Existing++
+			continue
+		}
+
+		track := &audio.Track{
+			Title:        entry.Title,
+			Artist:       entry.Artist,
+			ThumbnailURL: ThumbnailURL(entry.VideoID),
+			Source:       audio.SourceYouTube,
+			SourceID:     entry.VideoID,
+		}
+		if track.Title == "" {
+			track.Title = placeholderTitle(entry.VideoID)
+		}
+		if existing == nil {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+		if track.Title == placeholderTitle(entry.VideoID) || track.Artist == "" {
+			toResolve = append(toResolve, entry.VideoID)
+		}
+		result.Tracks[i] = track
+		toSave = append(toSave, track)
+	}
+
+	if len(toSave) > 0 {
+		// Upserts on (source, source_id), so a video removed earlier comes back as the same track.
+		if err := s.repo.BulkCreateTracks(toSave); err != nil {
+			return nil, err
+		}
+	}
+	result.Resolving = s.queueLookups(toResolve)
+	if result.Created > 0 || result.Updated > 0 {
+		s.wsManager.Broadcast(websocket.Event{Type: audioSvc.EventTracksUpdated})
+	}
+	s.log.Info("Imported YouTube videos", "created", result.Created, "updated", result.Updated, "existing", result.Existing,
+		"resolving", result.Resolving, "ignored", len(ignored))
+	return result, nil
+}
+
+// RunMetadataResolver looks up the titles of youtube tracks that still have a placeholder, then keeps looking up
+// the videos that imports queue. It does nothing while the resolver is offline.
+func (s *Service) RunMetadataResolver() {
+	if s.isOffline() {
+		return
+	}
+	tracks, err := s.repo.GetAllTracks(filters.TrackFilters{Source: audio.SourceYouTube})
+	if err != nil {
+		s.log.Error("Failed to fetch youtube tracks from DB", "error", err)
+	}
+	var videoIDs []string
+	for _, track := range tracks {
+		if track.Title == placeholderTitle(track.SourceID) {
+			videoIDs = append(videoIDs, track.SourceID)
+		}
+	}
+	s.queueLookups(videoIDs)
+	go s.resolveQueued()
+}
+
+// Helpers
+
+func (s *Service) isOffline() bool {
+	_, offline := s.resolver.(OfflineResolver)
+	return offline
+}
+
+// queueLookups marks videos for the background resolver and reports how many are waiting on it. Videos queued
+// before RunMetadataResolver wait for it.
+func (s *Service) queueLookups(videoIDs []string) int {
+	if s.isOffline() || len(videoIDs) == 0 {
+		return 0
+	}
+	s.resolveMu.Lock()
+	for _, videoID := range videoIDs {
+		if !s.resolvePending[videoID] {
+			s.resolvePending[videoID] = true
+			s.resolveQueue = append(s.resolveQueue, videoID)
+		}
+	}
+	s.resolveMu.Unlock()
+
+	select {
+	case s.resolveWake <- struct{}{}:
+	default: // Already woken
+	}
+	return len(videoIDs)
+}
+
+func (s *Service) resolveQueued() {
+	for range s.resolveWake {
+		updated := false
+		for {
+			videoID, ok := s.nextQueued()
+			if !ok {
+				break
+			}
+			track, err := s.repo.GetTrackBySourceID(audio.SourceYouTube, videoID)
+			if err != nil {
+				continue // Removed since it was queued
+			}
+			resolved, err := s.resolve(track)
+			if err != nil {
+				// Most likely there is no network, so the rest would fail the same way. Later imports try again.
+				s.log.Warn("YouTube metadata lookup failed, skipping the queued videos", "video_id", videoID, "error", err)
+				s.clearQueue()
+				break
+			}
+			updated = updated || resolved
+		}
+		if updated {
+			s.wsManager.Broadcast(websocket.Event{Type: audioSvc.EventTracksUpdated})
+		}
+	}
+}
+
+func (s *Service) nextQueued() (string, bool) {
+	s.resolveMu.Lock()
+	defer s.resolveMu.Unlock()
+	if len(s.resolveQueue) == 0 {
+		return "", false
+	}
+	videoID := s.resolveQueue[0]
+	s.resolveQueue = s.resolveQueue[1:]
+	delete(s.resolvePending, videoID)
+	return videoID, true
+}
+
+func (s *Service) clearQueue() {
+	s.resolveMu.Lock()
+	defer s.resolveMu.Unlock()
+	s.resolveQueue = nil
+	clear(s.resolvePending)
+}
+
+// resolve fills in what the export left out and saves the track. It reports whether the track changed; an
+// unknown video is not an error, it just keeps its placeholder.
+func (s *Service) resolve(track *audio.Track) (bool, error) {
+	meta, err := s.resolver.Resolve(context.Background(), track.SourceID)
+	if errors.Is(err, ErrNoMetadata) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	if track.Title == placeholderTitle(track.SourceID) && meta.Title != "" {
+		track.Title, changed = meta.Title, true
+	}
+	if track.Artist == "" && meta.Artist != "" {
+		track.Artist, changed = meta.Artist, true
+	}
+	if track.Duration == 0 && meta.Duration != 0 {
+		track.Duration, changed = meta.Duration, true
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := s.repo.UpdateTrack(track); err != nil {
+		s.log.Error("Failed to save YouTube metadata", "video_id", track.SourceID, "error", err)
+		return false, nil
+	}
+	return true, nil
+}
+
+func placeholderTitle(videoID string) string {
+	return "YouTube video " + videoID
+}
@@ -34,7 +34,7 @@ export default function DmLayout() {
     }, [dispatch]); // Add dispatch as a dependency
 
     // Now, the onMessage function is stable between re-renders
-    useWebSocket(`${API_BASE_URL}/ws`, handleWebSocketMessage);
+    useWebSocket(`${API_BASE_URL}/ws?role=dm`, handleWebSocketMessage);
 
     // Keep the Spotify player alive across page navigations
     useSpotifyPlayer();